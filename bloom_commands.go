@@ -0,0 +1,168 @@
+package main
+
+import (
+	"math"
+	"time"
+)
+
+// BloomFilters holds the Bloom filters of the cache.
+type BloomFilters interface {
+	Reserve(key string, capacity int, errorRate float64, expiresAt time.Time) Error
+	Add(key string, item string, expiresAt time.Time) (bool, Error)
+	Exists(key string, item string) (bool, Error)
+}
+
+type bloomFilters struct {
+	cache Cache[string, Value]
+}
+
+func NewBloomFilters(cache Cache[string, Value]) BloomFilters {
+	return &bloomFilters{cache: cache}
+}
+
+// Reserve creates an empty filter sized for capacity items at the error rate,
+// which expires at expiresAt. It fails when the key already exists.
+func (b *bloomFilters) Reserve(key string, capacity int, errorRate float64, expiresAt time.Time) Error {
+	_, err := computeAs(b.cache, key, BloomFilterData, func(current **BloomFilter) (*BloomFilter, ComputeAction, Error) {
+		if current != nil {
+			return nil, KeepValue, &CommandError{message: "Key already exists in BF.RESERVE"}
+		}
+		return NewBloomFilter(capacity, errorRate), StoreValue, nil
+	}, expiresAt)
+	return err
+}
+
+// Add adds the item to the filter, which is created with the default capacity
+// and error rate when needed and then expires at expiresAt. It returns whether
+// the item was not in the filter yet, or an error once the filter is full.
+func (b *bloomFilters) Add(key string, item string, expiresAt time.Time) (bool, Error) {
+	added := false
+	_, err := computeAs(b.cache, key, BloomFilterData, func(current **BloomFilter) (*BloomFilter, ComputeAction, Error) {
+		var filter *BloomFilter
+		if current != nil {
+			filter = *current
+		} else {
+			filter = NewBloomFilter(DefaultBloomCapacity, DefaultBloomErrorRate)
+		}
+		var ok bool
+		if added, ok = filter.Add(item); !ok {
+			return nil, KeepValue, &CommandError{message: "Bloom filter is full in BF.ADD"}
+		}
+		if !added && current != nil {
+			return filter, KeepValue, nil
+		}
+		return filter, StoreValue, nil
+	}, expiresAt)
+	return added, err
+}
+
+// Exists returns whether the item may have been added to the filter, false
+// when the key does not exist.
+func (b *bloomFilters) Exists(key string, item string) (bool, Error) {
+	exists := false
+	_, err := computeAs(b.cache, key, BloomFilterData, func(current **BloomFilter) (*BloomFilter, ComputeAction, Error) {
+		if current != nil {
+			exists = (*current).Contains(item)
+		}
+		return nil, KeepValue, nil
+	}, time.Time{})
+	return exists, err
+}
+
+type bfReserveCommand struct {
+	Command
+	filters BloomFilters
+}
+
+func NewBFReserveCommand(filters BloomFilters) ExecutableCommand[string, Value] {
+	return &bfReserveCommand{
+		Command: NewCommand("BF.RESERVE").
+			WithArgument(KeyCommandArgument).
+			WithArgument(ErrorRateArgument).
+			WithArgument(CapacityArgument).
+			WithOption(ExpirationOption).
+			WithOption(ExpirationMsOption),
+		filters: filters,
+	}
+}
+
+func (c *bfReserveCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	errorRate := input.GetArgument(*ErrorRateArgument).(float64)
+	capacity := input.GetArgument(*CapacityArgument).(int)
+	if math.IsNaN(errorRate) || errorRate <= 0 || errorRate >= 1 {
+		return nil, &CommandError{message: "Invalid error rate in " + c.GetName()}
+	}
+	if capacity <= 0 || capacity > maxBloomLayerCapacity {
+		return nil, &CommandError{message: "Invalid capacity in " + c.GetName()}
+	}
+	if !validBloomFilter(capacity, errorRate) {
+		return nil, &CommandError{message: "Filter too large for the error rate and capacity in " + c.GetName()}
+	}
+	expiresAt, err := expirationFromInput(c.GetName(), input)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.filters.Reserve(key, capacity, errorRate, expiresAt); err != nil {
+		return nil, err
+	}
+	return &okResult[Value]{}, nil
+}
+
+type bfAddCommand struct {
+	Command
+	filters BloomFilters
+}
+
+func NewBFAddCommand(filters BloomFilters) ExecutableCommand[string, Value] {
+	return &bfAddCommand{
+		Command: NewCommand("BF.ADD").
+			WithArgument(KeyCommandArgument).
+			WithArgument(ItemArgument).
+			WithOption(ExpirationOption).
+			WithOption(ExpirationMsOption),
+		filters: filters,
+	}
+}
+
+// Run replies 1 when the item was added and 0 when it may already have been.
+// The TTL given as option applies to the filter when it is created.
+func (c *bfAddCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	item := input.GetArgument(*ItemArgument).(string)
+	expiresAt, err := expirationFromInput(c.GetName(), input)
+	if err != nil {
+		return nil, err
+	}
+	added, err := c.filters.Add(key, item, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return booleanResult[Value](added), nil
+}
+
+type bfExistsCommand struct {
+	Command
+	filters BloomFilters
+}
+
+func NewBFExistsCommand(filters BloomFilters) ExecutableCommand[string, Value] {
+	return &bfExistsCommand{
+		Command: NewCommand("BF.EXISTS").
+			WithArgument(KeyCommandArgument).
+			WithArgument(ItemArgument),
+		filters: filters,
+	}
+}
+
+// Run replies 1 when the item may have been added and 0 when it surely was
+// not.
+func (c *bfExistsCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	item := input.GetArgument(*ItemArgument).(string)
+	exists, err := c.filters.Exists(key, item)
+	if err != nil {
+		return nil, err
+	}
+	return booleanResult[Value](exists), nil
+}
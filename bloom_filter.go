@@ -0,0 +1,141 @@
+package main
+
+import "math"
+
+const (
+	DefaultBloomCapacity  = 100
+	DefaultBloomErrorRate = 0.01
+	// bloomExpansion is the factor by which the capacity of each new layer
+	// grows
+	bloomExpansion = 2
+	// bloomTightening is the factor by which the error rate of each new layer
+	// shrinks, so that the error rates of all the layers add up to at most the
+	// one of the filter
+	bloomTightening = 0.5
+	// maxBloomLayerCapacity bounds the growth of the layers
+	maxBloomLayerCapacity = 1 << 27
+	// maxBloomFilterBits bounds the memory of a filter, all its layers
+	// included, to 128MB
+	maxBloomFilterBits = 1 << 30
+)
+
+// bloomLayer is a fixed size Bloom filter holding up to capacity items with
+// the error rate it was sized for.
+type bloomLayer struct {
+	bits     []uint64
+	size     uint64
+	hashes   int
+	capacity int
+	count    int
+}
+
+// bloomLayerSize returns the number of bits of a layer holding capacity items
+// at the error rate, as a float since it may not fit in an integer.
+func bloomLayerSize(capacity int, errorRate float64) float64 {
+	return max(math.Ceil(-float64(capacity)*math.Log(errorRate)/(math.Ln2*math.Ln2)), 64)
+}
+
+// validBloomFilter returns whether a filter for capacity items at the error
+// rate fits in maxBloomFilterBits.
+func validBloomFilter(capacity int, errorRate float64) bool {
+	return bloomLayerSize(capacity, errorRate*(1-bloomTightening)) <= maxBloomFilterBits
+}
+
+func newBloomLayer(capacity int, errorRate float64) *bloomLayer {
+	size := uint64(bloomLayerSize(capacity, errorRate))
+	hashes := max(int(math.Ceil(math.Ln2*float64(size)/float64(capacity))), 1)
+	return &bloomLayer{
+		bits:     make([]uint64, (size+63)/64),
+		size:     size,
+		hashes:   hashes,
+		capacity: capacity,
+	}
+}
+
+// positions derives the bits of an item from its hash by double hashing.
+func (l *bloomLayer) positions(hash uint64, yield func(bit uint64) bool) {
+	step := mixHash(hash) | 1
+	for i := 0; i < l.hashes; i++ {
+		if !yield((hash + uint64(i)*step) % l.size) {
+			return
+		}
+	}
+}
+
+func (l *bloomLayer) add(hash uint64) {
+	l.positions(hash, func(bit uint64) bool {
+		l.bits[bit/64] |= 1 << (bit % 64)
+		return true
+	})
+	l.count++
+}
+
+func (l *bloomLayer) contains(hash uint64) bool {
+	contains := true
+	l.positions(hash, func(bit uint64) bool {
+		contains = l.bits[bit/64]&(1<<(bit%64)) != 0
+		return contains
+	})
+	return contains
+}
+
+// BloomFilter is a scalable Bloom filter: it tells whether an item may have
+// been added, with no false negatives and false positives at most at the
+// error rate. Once the current layer is full a larger one is added, so the
+// filter keeps its error rate whatever the number of items, until its layers
+// would take more than maxBloomFilterBits.
+type BloomFilter struct {
+	errorRate float64
+	layers    []*bloomLayer
+	// size is the number of bits of all the layers
+	size uint64
+}
+
+// NewBloomFilter creates a filter for capacity items at the error rate, which
+// must be valid, see validBloomFilter.
+func NewBloomFilter(capacity int, errorRate float64) *BloomFilter {
+	layer := newBloomLayer(capacity, errorRate*(1-bloomTightening))
+	return &BloomFilter{
+		errorRate: errorRate,
+		layers:    []*bloomLayer{layer},
+		size:      layer.size,
+	}
+}
+
+// Add adds the item and returns whether it was not in the filter yet, a false
+// positive making it look like it was. The second result is false when the
+// item could not be added because the filter is full: its last layer is at
+// capacity and the next one would go over maxBloomFilterBits.
+func (f *BloomFilter) Add(item string) (bool, bool) {
+	hash := hashString(item)
+	if f.contains(hash) {
+		return false, true
+	}
+	last := f.layers[len(f.layers)-1]
+	if last.count >= last.capacity {
+		capacity := min(last.capacity*bloomExpansion, maxBloomLayerCapacity)
+		errorRate := f.errorRate * (1 - bloomTightening) * math.Pow(bloomTightening, float64(len(f.layers)))
+		if float64(f.size)+bloomLayerSize(capacity, errorRate) > maxBloomFilterBits {
+			return false, false
+		}
+		last = newBloomLayer(capacity, errorRate)
+		f.layers = append(f.layers, last)
+		f.size += last.size
+	}
+	last.add(hash)
+	return true, true
+}
+
+// Contains returns whether the item may have been added.
+func (f *BloomFilter) Contains(item string) bool {
+	return f.contains(hashString(item))
+}
+
+func (f *BloomFilter) contains(hash uint64) bool {
+	for _, layer := range f.layers {
+		if layer.contains(hash) {
+			return true
+		}
+	}
+	return false
+}
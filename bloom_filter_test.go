@@ -0,0 +1,25 @@
+package main
+
+import "testing"
+
+func TestBloomFilterSizeBounded(t *testing.T) {
+	if !validBloomFilter(maxBloomLayerCapacity, 0.1) {
+		t.Errorf("filter of the largest capacity rejected at a 10%% error rate")
+	}
+	if validBloomFilter(maxBloomLayerCapacity, 1e-300) {
+		t.Errorf("filter of %.0f bits accepted", bloomLayerSize(maxBloomLayerCapacity, 1e-300))
+	}
+
+	// A full filter whose next layer would go over the bound takes no more
+	// items
+	filter := NewBloomFilter(DefaultBloomCapacity, DefaultBloomErrorRate)
+	filter.size = maxBloomFilterBits - 64
+	last := filter.layers[len(filter.layers)-1]
+	last.count = last.capacity
+	if added, ok := filter.Add("item"); added || ok {
+		t.Errorf("item added to a full filter")
+	}
+	if len(filter.layers) != 1 {
+		t.Errorf("filter grew to %d layers over the bound", len(filter.layers))
+	}
+}
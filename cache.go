@@ -2,18 +2,48 @@ package main
 
 import (
 	"fmt"
+	"iter"
+	"slices"
 	"sync"
 	"time"
 )
 
+// CacheConfig holds the settings of a cache.
+type CacheConfig struct {
+	// Precision is the precision at which expiration times are recorded.
+	Precision time.Duration
+	// Records selects how expiration times are recorded.
+	Records RecordsKind
+	// IdlePolicy is applied to the keys set without an expiration time.
+	IdlePolicy IdlePolicy
+}
+
+// IdlePolicy makes keys expire once they have not been read for IdleTimeout,
+// while never living longer than MaxLifetime when it is set. A zero
+// IdleTimeout disables it.
+type IdlePolicy struct {
+	IdleTimeout time.Duration
+	MaxLifetime time.Duration
+}
+
 type CacheValue[V any] interface {
 	Value() V
 	ExpiresAt() time.Time
+	IdleTimeout() time.Duration
+	Deadline() time.Time
+	Version() uint64
 }
 
 type cacheValue[V any] struct {
 	value     *V
 	expiresAt time.Time
+	// Keys with an idle timeout see their expiration time pushed back on
+	// every read, up to their deadline when they have one.
+	idleTimeout time.Duration
+	deadline    time.Time
+	// version changes every time the value or its expiration is written, it is
+	// used as a compare-and-swap token.
+	version uint64
 }
 
 func (c *cacheValue[V]) Value() V {
@@ -24,33 +54,178 @@ func (c *cacheValue[V]) ExpiresAt() time.Time {
 	return c.expiresAt
 }
 
+func (c *cacheValue[V]) IdleTimeout() time.Duration {
+	return c.idleTimeout
+}
+
+func (c *cacheValue[V]) Deadline() time.Time {
+	return c.deadline
+}
+
+func (c *cacheValue[V]) Version() uint64 {
+	return c.version
+}
+
+// KeyValue is a key along with its value, as written by SetMany.
+type KeyValue[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
+// WriteCondition decides from the current value of a key, nil when the key
+// does not exist, whether a conditional write takes place.
+type WriteCondition[V any] func(current CacheValue[V]) bool
+
+// IfAbsent allows writing keys that do not exist.
+func IfAbsent[V any](current CacheValue[V]) bool {
+	return current == nil
+}
+
+// IfExists allows writing keys that exist.
+func IfExists[V any](current CacheValue[V]) bool {
+	return current != nil
+}
+
+// IfVersion allows writing a key whose value still has the given version.
+func IfVersion[V any](version uint64) WriteCondition[V] {
+	return func(current CacheValue[V]) bool {
+		return current != nil && current.Version() == version
+	}
+}
+
+// newIdleValue creates a value expiring after idleTimeout without being read,
+// and at the latest at deadline when it is not zero.
+func newIdleValue[V any](value V, idleTimeout time.Duration, deadline time.Time, now time.Time) *cacheValue[V] {
+	expiresAt := now.Add(idleTimeout)
+	if !deadline.IsZero() && deadline.Before(expiresAt) {
+		expiresAt = deadline
+	}
+	return &cacheValue[V]{value: &value, expiresAt: expiresAt, idleTimeout: idleTimeout, deadline: deadline}
+}
+
+// newValue creates the value stored by Set: the idle policy of the cache is
+// applied when no expiration time is given.
+func newValue[V any](value V, expiresAt time.Time, policy IdlePolicy, now time.Time) *cacheValue[V] {
+	if expiresAt.IsZero() && policy.IdleTimeout > 0 {
+		var deadline time.Time
+		if policy.MaxLifetime > 0 {
+			deadline = now.Add(policy.MaxLifetime)
+		}
+		return newIdleValue(value, policy.IdleTimeout, deadline, now)
+	}
+	return &cacheValue[V]{value: &value, expiresAt: expiresAt}
+}
+
+// withValue returns a value replacing the one of current while keeping its
+// expiration.
+func withValue[V any](current CacheValue[V], value V) *cacheValue[V] {
+	return &cacheValue[V]{value: &value, expiresAt: current.ExpiresAt(), idleTimeout: current.IdleTimeout(), deadline: current.Deadline(), version: current.Version()}
+}
+
+// withExpiration returns a value replacing the expiration time of current by
+// expiresAt, a zero expiresAt meaning none. Values with an idle timeout keep
+// expiring once they are not read: expiresAt then becomes their deadline when
+// it comes before the one they have.
+func withExpiration[V any](current CacheValue[V], expiresAt time.Time) *cacheValue[V] {
+	value := current.Value()
+	idleTimeout := current.IdleTimeout()
+	if idleTimeout <= 0 {
+		return &cacheValue[V]{value: &value, expiresAt: expiresAt}
+	}
+	deadline := current.Deadline()
+	if !expiresAt.IsZero() && (deadline.IsZero() || expiresAt.Before(deadline)) {
+		deadline = expiresAt
+	}
+	idleExpiresAt := current.ExpiresAt()
+	if !deadline.IsZero() && deadline.Before(idleExpiresAt) {
+		idleExpiresAt = deadline
+	}
+	return &cacheValue[V]{value: &value, expiresAt: idleExpiresAt, idleTimeout: idleTimeout, deadline: deadline}
+}
+
+// touched returns the value with its expiration time pushed back by its idle
+// timeout, or false when the value has no idle timeout or cannot be extended.
+func touched[V any](value CacheValue[V], now time.Time) (CacheValue[V], bool) {
+	idleTimeout := value.IdleTimeout()
+	if idleTimeout <= 0 {
+		return nil, false
+	}
+	refreshed := newIdleValue(value.Value(), idleTimeout, value.Deadline(), now)
+	if !refreshed.expiresAt.After(value.ExpiresAt()) {
+		return nil, false
+	}
+	refreshed.version = value.Version()
+	return refreshed, true
+}
+
+// isExpired tells whether the value has an expiration time which is reached.
+func isExpired[V any](value CacheValue[V], now time.Time) bool {
+	expiresAt := value.ExpiresAt()
+	return !expiresAt.IsZero() && !expiresAt.After(now)
+}
+
+// ComputeAction tells Compute what to do with the value computed.
+type ComputeAction int
+
+const (
+	// StoreValue stores the value computed.
+	StoreValue ComputeAction = iota
+	// KeepValue leaves the key unchanged, for computations only reading it.
+	KeepValue
+	// DeleteValue deletes the key.
+	DeleteValue
+)
+
 type Cache[K comparable, V any] interface {
 	get(K) (CacheValue[V], bool)
 	Get(K) (*V, bool)
-	set(K, CacheValue[V])
+	setIf(K, *cacheValue[V], WriteCondition[V]) (CacheValue[V], bool)
 	Set(K, V, time.Time)
-	Delete(K)
+	SetIf(K, V, time.Time, WriteCondition[V]) (CacheValue[V], bool)
+	SetIdle(K, V, time.Duration, time.Time)
+	SetIdleIf(K, V, time.Duration, time.Time, WriteCondition[V]) (CacheValue[V], bool)
+	Update(K, func(*V) (V, Error), time.Time) (V, Error)
+	Compute(K, func(*V) (V, ComputeAction, Error), time.Time) (V, Error)
+	Expire(K, time.Time) bool
+	Persist(K) bool
+	Delete(K) bool
+	GetMany([]K) []*V
+	SetMany([]KeyValue[K, V], time.Time)
+	DeleteMany([]K) int
+	All() iter.Seq2[K, V]
+	Keys() iter.Seq[K]
+	Scan(int, int) ([]K, int)
+	ExpireSample(int) (int, int)
 	ClearExpired() int
 	Clear()
+	Events() KeyspaceEvents[K]
 	String() string
 }
 
 type cache[K comparable, V any] struct {
-	data    map[K]CacheValue[V]
-	records Records[K]
+	data       map[K]CacheValue[V]
+	records    Records[K]
+	scan       *scanIndex[K]
+	idlePolicy IdlePolicy
+	// version is the last version given to a value, written under the lock
+	version uint64
 	locker  sync.RWMutex
+	events  KeyspaceEvents[K]
 	logger  Logger
 }
 
-func NewCache[K comparable, V any](precision time.Duration, logger Logger) (*cache[K, V], Error) {
-	records, err := NewRecords[K](precision, logger)
+func NewCache[K comparable, V any](config CacheConfig, logger Logger) (*cache[K, V], Error) {
+	records, err := NewRecordsOfKind[K](config.Records, config.Precision, logger)
 	if err != nil {
 		return nil, err
 	}
 	return &cache[K, V]{
-		data:    make(map[K]CacheValue[V]),
-		records: records,
-		logger:  logger,
+		data:       make(map[K]CacheValue[V]),
+		records:    records,
+		scan:       newScanIndex[K](),
+		idlePolicy: config.IdlePolicy,
+		events:     NewKeyspaceEvents[K](logger),
+		logger:     logger,
 	}, nil
 }
 
@@ -64,13 +239,19 @@ func (c *cache[K, V]) get(key K) (CacheValue[V], bool) {
 	return cacheValue, true
 }
 
+// Get returns the value of the key, expired values are deleted on read so
+// that they are never returned even before the janitor clears them.
 func (c *cache[K, V]) Get(key K) (*V, bool) {
 	cacheValue, ok := c.get(key)
 	if ok {
-		if cacheValue.ExpiresAt().After(time.Now()) {
-			c.logger.Warning(fmt.Sprintf("[MAIN_CACHE_EVENT] value with key %v exists after expired!", key))
+		if isExpired(cacheValue, time.Now()) {
+			c.deleteIfExpired(key)
+			c.logger.Info(fmt.Sprintf("[MAIN_CACHE_EVENT] Key expired: %v", key))
 			return nil, false
 		}
+		if cacheValue.IdleTimeout() > 0 {
+			c.touch(key)
+		}
 		value := cacheValue.Value()
 		return &value, true
 	}
@@ -78,80 +259,482 @@ func (c *cache[K, V]) Get(key K) (*V, bool) {
 	return nil, false
 }
 
-func (c *cache[K, V]) set(key K, value CacheValue[V]) {
+// setIf stores the value with a new version when the condition, if any, holds
+// for the current value of the key. It returns the value replaced or kept and
+// whether the write took place.
+func (c *cache[K, V]) setIf(key K, value *cacheValue[V], condition WriteCondition[V]) (CacheValue[V], bool) {
 	c.locker.Lock()
 	defer c.locker.Unlock()
-	if oldVal, exists := c.get(key); exists {
-		c.records.Delete(key, oldVal.ExpiresAt())
+	return c.store(key, value, condition)
+}
+
+// store is setIf without locking, it must be called with the lock held.
+func (c *cache[K, V]) store(key K, value *cacheValue[V], condition WriteCondition[V]) (CacheValue[V], bool) {
+	var current CacheValue[V]
+	if oldValue, exists := c.data[key]; exists {
+		if isExpired(oldValue, time.Now()) {
+			delete(c.data, key)
+			c.scan.Delete(key)
+			c.records.Delete(key, oldValue.ExpiresAt())
+			c.events.Emit(ExpiredEvent, key)
+		} else {
+			current = oldValue
+		}
+	}
+	if condition != nil && !condition(current) {
+		return current, false
 	}
+	if current != nil {
+		c.records.Delete(key, current.ExpiresAt())
+	}
+	c.version++
+	value.version = c.version
 	c.data[key] = value
 	c.records.Add(key, value.ExpiresAt())
+	c.scan.Add(key)
+	c.events.Emit(writeEvent(value.Value()), key)
+	return current, true
+}
+
+// touch pushes back the expiration time of a key read before its idle timeout
+// elapsed.
+func (c *cache[K, V]) touch(key K) {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	c.refresh(key, time.Now())
 }
 
+// refresh is touch without locking, it must be called with the lock held.
+func (c *cache[K, V]) refresh(key K, now time.Time) {
+	cacheValue, ok := c.data[key]
+	if !ok || isExpired(cacheValue, now) {
+		return
+	}
+	refreshed, ok := touched(cacheValue, now)
+	if !ok {
+		return
+	}
+	c.data[key] = refreshed
+	c.records.Move(key, cacheValue.ExpiresAt(), refreshed.ExpiresAt())
+}
+
+// Set stores the value until expiresAt, a zero expiresAt means the key never
+// expires unless the cache has an idle policy.
 func (c *cache[K, V]) Set(key K, value V, expiresAt time.Time) {
-	if expiresAt.Before(time.Now()) {
+	c.SetIf(key, value, expiresAt, nil)
+}
+
+// SetIf stores the value like Set when the condition holds for the current
+// value of the key, atomically. It returns the value replaced or kept and
+// whether the write took place.
+func (c *cache[K, V]) SetIf(key K, value V, expiresAt time.Time, condition WriteCondition[V]) (CacheValue[V], bool) {
+	now := time.Now()
+	if !expiresAt.IsZero() && expiresAt.Before(now) {
 		c.logger.Warning(fmt.Sprintf("[MAIN_CACHE_EVENT] Attempted to set key %v with past expiration time", key))
-		return
+		return nil, false
 	}
-	c.set(key, &cacheValue[V]{value: &value, expiresAt: expiresAt})
+	return c.setIf(key, newValue(value, expiresAt, c.idlePolicy, now), condition)
 }
 
-func (c *cache[K, V]) Delete(key K) {
+// SetIdle stores the value until it has not been read for idleTimeout, and at
+// the latest until deadline when it is not zero.
+func (c *cache[K, V]) SetIdle(key K, value V, idleTimeout time.Duration, deadline time.Time) {
+	c.SetIdleIf(key, value, idleTimeout, deadline, nil)
+}
+
+// SetIdleIf stores the value like SetIdle when the condition holds for the
+// current value of the key, atomically.
+func (c *cache[K, V]) SetIdleIf(key K, value V, idleTimeout time.Duration, deadline time.Time, condition WriteCondition[V]) (CacheValue[V], bool) {
+	now := time.Now()
+	if !deadline.IsZero() && deadline.Before(now) {
+		c.logger.Warning(fmt.Sprintf("[MAIN_CACHE_EVENT] Attempted to set key %v with past expiration time", key))
+		return nil, false
+	}
+	return c.setIf(key, newIdleValue(value, idleTimeout, deadline, now), condition)
+}
+
+// Update atomically replaces the value of a key by the one computed from its
+// current value, which is nil when the key does not exist. An existing key
+// keeps its expiration while a created one expires at expiresAt. Nothing is
+// stored when update returns an error.
+func (c *cache[K, V]) Update(key K, update func(*V) (V, Error), expiresAt time.Time) (V, Error) {
+	return c.Compute(key, func(current *V) (V, ComputeAction, Error) {
+		value, err := update(current)
+		return value, StoreValue, err
+	}, expiresAt)
+}
+
+// Compute is Update where compute also decides whether the value returned is
+// stored, the key left unchanged or deleted. Values holding pointers may be
+// changed in place by compute since it runs with the lock held, as long as
+// they are only read through Compute as well.
+func (c *cache[K, V]) Compute(key K, compute func(*V) (V, ComputeAction, Error), expiresAt time.Time) (V, Error) {
+	now := time.Now()
 	c.locker.Lock()
 	defer c.locker.Unlock()
-	cacheValue, ok := c.get(key)
+	var current *V
+	oldValue, ok := c.data[key]
+	if ok && isExpired(oldValue, now) {
+		delete(c.data, key)
+		c.scan.Delete(key)
+		c.records.Delete(key, oldValue.ExpiresAt())
+		c.events.Emit(ExpiredEvent, key)
+		ok = false
+	}
+	if ok {
+		value := oldValue.Value()
+		current = &value
+	}
+	value, action, err := compute(current)
+	if err != nil || action == KeepValue {
+		return value, err
+	}
+	if action == DeleteValue {
+		if ok {
+			delete(c.data, key)
+			c.scan.Delete(key)
+			c.records.Delete(key, oldValue.ExpiresAt())
+			c.events.Emit(DelEvent, key)
+		}
+		return value, nil
+	}
+	c.version++
+	if ok {
+		updated := withValue(oldValue, value)
+		updated.version = c.version
+		c.data[key] = updated
+		c.events.Emit(writeEvent(value), key)
+		return value, nil
+	}
+	newValue := newValue(value, expiresAt, c.idlePolicy, now)
+	newValue.version = c.version
+	c.data[key] = newValue
+	c.records.Add(key, newValue.ExpiresAt())
+	c.scan.Add(key)
+	c.events.Emit(writeEvent(value), key)
+	return value, nil
+}
+
+// Expire changes the expiration time of an existing key, moving it to its new
+// record. A zero expiresAt makes the key persistent and a past one deletes it.
+// Keys with an idle timeout keep expiring on idle, at expiresAt at the latest.
+func (c *cache[K, V]) Expire(key K, expiresAt time.Time) bool {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	oldValue, ok := c.data[key]
+	if !ok || isExpired(oldValue, time.Now()) {
+		return false
+	}
+	if !expiresAt.IsZero() && !expiresAt.After(time.Now()) {
+		delete(c.data, key)
+		c.scan.Delete(key)
+		c.records.Delete(key, oldValue.ExpiresAt())
+		c.events.Emit(DelEvent, key)
+		return true
+	}
+	newValue := withExpiration(oldValue, expiresAt)
+	c.version++
+	newValue.version = c.version
+	c.data[key] = newValue
+	c.records.Move(key, oldValue.ExpiresAt(), newValue.ExpiresAt())
+	if newValue.ExpiresAt().IsZero() {
+		c.events.Emit(PersistEvent, key)
+	} else {
+		c.events.Emit(ExpireEvent, key)
+	}
+	return true
+}
+
+// Persist removes the expiration time of a key, it returns false when the key
+// does not exist, has no expiration time or expires on idle.
+func (c *cache[K, V]) Persist(key K) bool {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	oldValue, ok := c.data[key]
+	if !ok || oldValue.ExpiresAt().IsZero() || oldValue.IdleTimeout() > 0 || isExpired(oldValue, time.Now()) {
+		return false
+	}
+	newValue := withExpiration(oldValue, time.Time{})
+	c.version++
+	newValue.version = c.version
+	c.data[key] = newValue
+	c.records.Delete(key, oldValue.ExpiresAt())
+	c.events.Emit(PersistEvent, key)
+	return true
+}
+
+func (c *cache[K, V]) Delete(key K) bool {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	cacheValue, ok := c.data[key]
 	if !ok {
 		c.logger.Info(fmt.Sprintf("[MAIN_CACHE_EVENT] tried to delete inexistant key: %v", key))
-		return
+		return false
 	}
 	expiresAt := cacheValue.ExpiresAt()
 	delete(c.data, key)
+	c.scan.Delete(key)
 	c.records.Delete(key, expiresAt)
+	c.events.Emit(DelEvent, key)
+	return true
+}
+
+func (c *cache[K, V]) deleteIfExpired(key K) {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	c.removeIfExpired(key, time.Now())
 }
 
-func (c *cache[K, V]) DeleteMany(keys []K) {
+// removeIfExpired is deleteIfExpired without locking, it must be called with
+// the lock held.
+func (c *cache[K, V]) removeIfExpired(key K, now time.Time) {
+	cacheValue, ok := c.data[key]
+	if ok && isExpired(cacheValue, now) {
+		delete(c.data, key)
+		c.scan.Delete(key)
+		c.records.Delete(key, cacheValue.ExpiresAt())
+		c.events.Emit(ExpiredEvent, key)
+	}
+}
+
+// GetMany returns the values of the keys in order, with nil for the missing
+// ones. The values are read under a single lock, which is only taken again
+// once to delete the expired keys and push back idle expirations.
+func (c *cache[K, V]) GetMany(keys []K) []*V {
+	now := time.Now()
+	values := make([]*V, len(keys))
+	var expired, idle []K
+	c.locker.RLock()
+	for i, key := range keys {
+		cacheValue, ok := c.data[key]
+		if !ok {
+			continue
+		}
+		if isExpired(cacheValue, now) {
+			expired = append(expired, key)
+			continue
+		}
+		if cacheValue.IdleTimeout() > 0 {
+			idle = append(idle, key)
+		}
+		value := cacheValue.Value()
+		values[i] = &value
+	}
+	c.locker.RUnlock()
+	if len(expired) > 0 || len(idle) > 0 {
+		c.locker.Lock()
+		defer c.locker.Unlock()
+		for _, key := range expired {
+			c.removeIfExpired(key, now)
+		}
+		for _, key := range idle {
+			c.refresh(key, now)
+		}
+	}
+	return values
+}
+
+// SetMany stores the values under a single lock, like Set would one by one.
+func (c *cache[K, V]) SetMany(entries []KeyValue[K, V], expiresAt time.Time) {
+	now := time.Now()
+	if !expiresAt.IsZero() && expiresAt.Before(now) {
+		c.logger.Warning(fmt.Sprintf("[MAIN_CACHE_EVENT] Attempted to set %d keys with past expiration time", len(entries)))
+		return
+	}
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	for _, entry := range entries {
+		c.store(entry.Key, newValue(entry.Value, expiresAt, c.idlePolicy, now), nil)
+	}
+}
+
+// DeleteMany deletes the keys under a single lock and returns the number of
+// keys deleted.
+func (c *cache[K, V]) DeleteMany(keys []K) int {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	deleted := 0
 	for _, key := range keys {
-		c.Delete(key)
+		cacheValue, ok := c.data[key]
+		if !ok {
+			continue
+		}
+		delete(c.data, key)
+		c.scan.Delete(key)
+		c.records.Delete(key, cacheValue.ExpiresAt())
+		c.events.Emit(DelEvent, key)
+		deleted++
 	}
+	return deleted
 }
 
+// ExpireSample looks at up to n keys having an expiration time and deletes the
+// expired ones, it returns the number of keys sampled and deleted. The scan is
+// bounded so that persistent keys do not make it go over the whole cache.
+func (c *cache[K, V]) ExpireSample(n int) (int, int) {
+	now := time.Now()
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	sampled, expired, visited := 0, 0, 0
+	for key, cacheValue := range c.data {
+		if sampled == n || visited == n*ActiveExpireScanFactor {
+			break
+		}
+		visited++
+		if cacheValue.ExpiresAt().IsZero() {
+			continue
+		}
+		sampled++
+		if isExpired(cacheValue, now) {
+			delete(c.data, key)
+			c.scan.Delete(key)
+			c.records.Delete(key, cacheValue.ExpiresAt())
+			c.events.Emit(ExpiredEvent, key)
+			expired++
+		}
+	}
+	return sampled, expired
+}
+
+// ClearExpired deletes the keys whose expiration time is over. The records of
+// the keys are taken at once, then the keys are deleted ClearExpiredBatchSize at
+// a time, releasing the lock in between so that commands are not held up by
+// the sweep of a large cache.
 func (c *cache[K, V]) ClearExpired() int {
 	now := time.Now()
 	c.logger.Info("[MAIN_CACHE_EVENT] clearing expired keys...")
-	nbrKeys := c.records.DeleteBefore(now, func(keys []K) {
-		c.DeleteMany(keys)
+	var keys []K
+	c.locker.Lock()
+	c.records.DeleteBefore(now, func(expired []K) {
+		keys = append(keys, expired...)
 	})
+	c.locker.Unlock()
+	nbrKeys := 0
+	for batch := range slices.Chunk(keys, ClearExpiredBatchSize) {
+		nbrKeys += c.clearExpired(batch, now)
+	}
 	c.logger.Info(fmt.Sprintf("[MAIN_CACHE_EVENT] clearing expired keys done: cleared %d keys", nbrKeys))
 	return nbrKeys
 }
 
+// clearExpired deletes the given keys, whose records were deleted, if they are
+// expired and returns the number of keys deleted.
+func (c *cache[K, V]) clearExpired(keys []K, now time.Time) int {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	nbrKeys := 0
+	for _, key := range keys {
+		cacheValue, ok := c.data[key]
+		if !ok {
+			continue
+		}
+		if !isExpired(cacheValue, now) {
+			// The record was cleared too early or the key was written since
+			c.records.Add(key, cacheValue.ExpiresAt())
+			continue
+		}
+		delete(c.data, key)
+		c.scan.Delete(key)
+		c.events.Emit(ExpiredEvent, key)
+		nbrKeys++
+	}
+	return nbrKeys
+}
+
 func (c *cache[K, V]) Clear() {
+	c.locker.Lock()
+	defer c.locker.Unlock()
 	c.logger.Info("[MAIN_CACHE_EVENT] clearing all...")
 	clear(c.data)
+	c.scan.Clear()
 	c.records.Clear()
 	c.logger.Info("[MAIN_CACHE_EVENT] clearing all done")
 }
 
+// All iterates over the keys and values that are not expired. The keys are
+// collected first so that no lock is held while the loop runs: keys deleted
+// before being reached are skipped, keys added meanwhile are not seen.
+func (c *cache[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		c.locker.RLock()
+		keys := make([]K, 0, len(c.data))
+		for key := range c.data {
+			keys = append(keys, key)
+		}
+		c.locker.RUnlock()
+		now := time.Now()
+		for _, key := range keys {
+			cacheValue, ok := c.get(key)
+			if !ok || isExpired(cacheValue, now) {
+				continue
+			}
+			if !yield(key, cacheValue.Value()) {
+				return
+			}
+		}
+	}
+}
+
+// Keys iterates over the keys that are not expired, like All.
+func (c *cache[K, V]) Keys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for key := range c.All() {
+			if !yield(key) {
+				return
+			}
+		}
+	}
+}
+
+// Scan returns the keys that are not expired among the count ones coming next
+// from cursor on in the order of their position, along with the cursor of the
+// next call, see scanIndex. The cache is read locked during the call.
+func (c *cache[K, V]) Scan(cursor int, count int) ([]K, int) {
+	now := time.Now()
+	c.locker.RLock()
+	defer c.locker.RUnlock()
+	return c.scan.Scan(cursor, count, func(key K) bool {
+		return isExpired(c.data[key], now)
+	})
+}
+
+// Events returns the bus of the events happening to the keys of the cache.
+// Keys deleted by Clear are not notified.
+func (c *cache[K, V]) Events() KeyspaceEvents[K] {
+	return c.events
+}
+
 func (c *cache[K, V]) String() string {
 	return "Main Cache"
 }
 
 type syncCache[K comparable, V any] struct {
-	data    sync.Map
-	records Records[K]
-	logger  Logger
+	data       sync.Map
+	records    Records[K]
+	scan       *scanIndex[K]
+	idlePolicy IdlePolicy
+	// version is the last version given to a value, written under the locker
+	version uint64
+	// locker serializes writes so that records stay in line with data, reads
+	// do not take it
+	locker sync.Mutex
+	events KeyspaceEvents[K]
+	logger Logger
 }
 
-func NewSyncCache[K comparable, V any](precision time.Duration, logger Logger) (*syncCache[K, V], Error) {
-	records, err := NewRecords[K](precision, logger)
+func NewSyncCache[K comparable, V any](config CacheConfig, logger Logger) (*syncCache[K, V], Error) {
+	records, err := NewRecordsOfKind[K](config.Records, config.Precision, logger)
 	if err != nil {
 		return nil, err
 	}
 
 	return &syncCache[K, V]{
-		data:    sync.Map{},
-		records: records,
-		logger:  logger,
+		data:       sync.Map{},
+		records:    records,
+		scan:       newScanIndex[K](),
+		idlePolicy: config.IdlePolicy,
+		events:     NewKeyspaceEvents[K](logger),
+		logger:     logger,
 	}, nil
 }
 
@@ -163,13 +746,19 @@ func (c *syncCache[K, V]) get(key K) (CacheValue[V], bool) {
 	return nil, false
 }
 
+// Get returns the value of the key, expired values are deleted on read so
+// that they are never returned even before the janitor clears them.
 func (c *syncCache[K, V]) Get(key K) (*V, bool) {
 	cacheValue, ok := c.get(key)
 	if ok {
-		if cacheValue.ExpiresAt().After(time.Now()) {
-			c.logger.Warning(fmt.Sprintf("[SYNC_CACHE_EVENT] value with key %v exists after expired!", key))
+		if isExpired(cacheValue, time.Now()) {
+			c.deleteIfExpired(key)
+			c.logger.Info(fmt.Sprintf("[SYNC_CACHE_EVENT] Key expired: %v", key))
 			return nil, false
 		}
+		if cacheValue.IdleTimeout() > 0 {
+			c.touch(key)
+		}
 		value := cacheValue.Value()
 		return &value, true
 	}
@@ -177,53 +766,442 @@ func (c *syncCache[K, V]) Get(key K) (*V, bool) {
 	return nil, false
 }
 
-func (c *syncCache[K, V]) set(key K, cacheValue CacheValue[V]) {
+// setIf stores the value with a new version when the condition, if any, holds
+// for the current value of the key. It returns the value replaced or kept and
+// whether the write took place.
+func (c *syncCache[K, V]) setIf(key K, value *cacheValue[V], condition WriteCondition[V]) (CacheValue[V], bool) {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	return c.store(key, value, condition)
+}
+
+// store is setIf without locking, it must be called with the lock held.
+func (c *syncCache[K, V]) store(key K, value *cacheValue[V], condition WriteCondition[V]) (CacheValue[V], bool) {
+	var current CacheValue[V]
+	if oldValue, exists := c.get(key); exists {
+		if isExpired(oldValue, time.Now()) {
+			c.data.Delete(key)
+			c.scan.Delete(key)
+			c.records.Delete(key, oldValue.ExpiresAt())
+			c.events.Emit(ExpiredEvent, key)
+		} else {
+			current = oldValue
+		}
+	}
+	if condition != nil && !condition(current) {
+		return current, false
+	}
+	if current != nil {
+		c.records.Delete(key, current.ExpiresAt())
+	}
+	c.version++
+	value.version = c.version
+	c.data.Store(key, value)
+	c.records.Add(key, value.ExpiresAt())
+	c.scan.Add(key)
+	c.events.Emit(writeEvent(value.Value()), key)
+	return current, true
+}
+
+// touch pushes back the expiration time of a key read before its idle timeout
+// elapsed.
+func (c *syncCache[K, V]) touch(key K) {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	c.refresh(key, time.Now())
+}
+
+// refresh is touch without locking, it must be called with the lock held.
+func (c *syncCache[K, V]) refresh(key K, now time.Time) {
+	cacheValue, ok := c.get(key)
+	if !ok || isExpired(cacheValue, now) {
+		return
+	}
+	refreshed, ok := touched(cacheValue, now)
+	if !ok {
+		return
+	}
+	c.data.Store(key, refreshed)
+	c.records.Move(key, cacheValue.ExpiresAt(), refreshed.ExpiresAt())
+}
+
+// Set stores the value until expiresAt, a zero expiresAt means the key never
+// expires unless the cache has an idle policy.
+func (c *syncCache[K, V]) Set(key K, value V, expiresAt time.Time) {
+	c.SetIf(key, value, expiresAt, nil)
+}
+
+// SetIf stores the value like Set when the condition holds for the current
+// value of the key, atomically. It returns the value replaced or kept and
+// whether the write took place.
+func (c *syncCache[K, V]) SetIf(key K, value V, expiresAt time.Time, condition WriteCondition[V]) (CacheValue[V], bool) {
+	now := time.Now()
+	if !expiresAt.IsZero() && expiresAt.Before(now) {
+		c.logger.Warning(fmt.Sprintf("[SYNC_CACHE_EVENT] Attempted to set key %v with past expiration time", key))
+		return nil, false
+	}
+	return c.setIf(key, newValue(value, expiresAt, c.idlePolicy, now), condition)
+}
+
+// SetIdle stores the value until it has not been read for idleTimeout, and at
+// the latest until deadline when it is not zero.
+func (c *syncCache[K, V]) SetIdle(key K, value V, idleTimeout time.Duration, deadline time.Time) {
+	c.SetIdleIf(key, value, idleTimeout, deadline, nil)
+}
+
+// SetIdleIf stores the value like SetIdle when the condition holds for the
+// current value of the key, atomically.
+func (c *syncCache[K, V]) SetIdleIf(key K, value V, idleTimeout time.Duration, deadline time.Time, condition WriteCondition[V]) (CacheValue[V], bool) {
+	now := time.Now()
+	if !deadline.IsZero() && deadline.Before(now) {
+		c.logger.Warning(fmt.Sprintf("[SYNC_CACHE_EVENT] Attempted to set key %v with past expiration time", key))
+		return nil, false
+	}
+	return c.setIf(key, newIdleValue(value, idleTimeout, deadline, now), condition)
+}
+
+// Update atomically replaces the value of a key by the one computed from its
+// current value, which is nil when the key does not exist. An existing key
+// keeps its expiration while a created one expires at expiresAt. Nothing is
+// stored when update returns an error. Like every write it is serialized by
+// the locker, concurrent reads keep seeing the previous value until it is
+// stored.
+func (c *syncCache[K, V]) Update(key K, update func(*V) (V, Error), expiresAt time.Time) (V, Error) {
+	return c.Compute(key, func(current *V) (V, ComputeAction, Error) {
+		value, err := update(current)
+		return value, StoreValue, err
+	}, expiresAt)
+}
+
+// Compute is Update where compute also decides whether the value returned is
+// stored, the key left unchanged or deleted. Values holding pointers may be
+// changed in place by compute since it runs with the lock held, as long as
+// they are only read through Compute as well.
+func (c *syncCache[K, V]) Compute(key K, compute func(*V) (V, ComputeAction, Error), expiresAt time.Time) (V, Error) {
+	now := time.Now()
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	var current *V
 	oldValue, ok := c.get(key)
+	if ok && isExpired(oldValue, now) {
+		c.data.Delete(key)
+		c.scan.Delete(key)
+		c.records.Delete(key, oldValue.ExpiresAt())
+		c.events.Emit(ExpiredEvent, key)
+		ok = false
+	}
 	if ok {
+		value := oldValue.Value()
+		current = &value
+	}
+	value, action, err := compute(current)
+	if err != nil || action == KeepValue {
+		return value, err
+	}
+	if action == DeleteValue {
+		if ok {
+			c.data.Delete(key)
+			c.scan.Delete(key)
+			c.records.Delete(key, oldValue.ExpiresAt())
+			c.events.Emit(DelEvent, key)
+		}
+		return value, nil
+	}
+	c.version++
+	if ok {
+		updated := withValue(oldValue, value)
+		updated.version = c.version
+		c.data.Store(key, updated)
+		c.events.Emit(writeEvent(value), key)
+		return value, nil
+	}
+	newValue := newValue(value, expiresAt, c.idlePolicy, now)
+	newValue.version = c.version
+	c.data.Store(key, newValue)
+	c.records.Add(key, newValue.ExpiresAt())
+	c.scan.Add(key)
+	c.events.Emit(writeEvent(value), key)
+	return value, nil
+}
+
+// Expire changes the expiration time of an existing key, moving it to its new
+// record. A zero expiresAt makes the key persistent and a past one deletes it.
+// Keys with an idle timeout keep expiring on idle, at expiresAt at the latest.
+func (c *syncCache[K, V]) Expire(key K, expiresAt time.Time) bool {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	oldValue, ok := c.get(key)
+	if !ok || isExpired(oldValue, time.Now()) {
+		return false
+	}
+	if !expiresAt.IsZero() && !expiresAt.After(time.Now()) {
+		c.data.Delete(key)
+		c.scan.Delete(key)
 		c.records.Delete(key, oldValue.ExpiresAt())
+		c.events.Emit(DelEvent, key)
+		return true
+	}
+	newValue := withExpiration(oldValue, expiresAt)
+	c.version++
+	newValue.version = c.version
+	c.data.Store(key, newValue)
+	c.records.Move(key, oldValue.ExpiresAt(), newValue.ExpiresAt())
+	if newValue.ExpiresAt().IsZero() {
+		c.events.Emit(PersistEvent, key)
+	} else {
+		c.events.Emit(ExpireEvent, key)
 	}
-	c.data.Store(key, cacheValue)
+	return true
 }
 
-func (c *syncCache[K, V]) Set(key K, value V, expiresAt time.Time) {
-	if expiresAt.Before(time.Now()) {
-		c.logger.Warning(fmt.Sprintf("[SYNC_CACHE_EVENT] Attempted to set key %v with past expiration time", key))
-		return
+// Persist removes the expiration time of a key, it returns false when the key
+// does not exist, has no expiration time or expires on idle.
+func (c *syncCache[K, V]) Persist(key K) bool {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	oldValue, ok := c.get(key)
+	if !ok || oldValue.ExpiresAt().IsZero() || oldValue.IdleTimeout() > 0 || isExpired(oldValue, time.Now()) {
+		return false
 	}
-	c.set(key, &cacheValue[V]{value: &value, expiresAt: expiresAt})
+	newValue := withExpiration(oldValue, time.Time{})
+	c.version++
+	newValue.version = c.version
+	c.data.Store(key, newValue)
+	c.records.Delete(key, oldValue.ExpiresAt())
+	c.events.Emit(PersistEvent, key)
+	return true
 }
 
-func (c *syncCache[K, V]) Delete(key K) {
+func (c *syncCache[K, V]) Delete(key K) bool {
+	c.locker.Lock()
+	defer c.locker.Unlock()
 	cacheValue, ok := c.get(key)
 	if !ok {
 		c.logger.Info(fmt.Sprintf("[SYNC_CACHE_EVENT] tried to delete inexistant key: %v", key))
-		return
+		return false
 	}
 	expiresAt := cacheValue.ExpiresAt()
 	c.data.Delete(key)
+	c.scan.Delete(key)
 	c.records.Delete(key, expiresAt)
+	c.events.Emit(DelEvent, key)
+	return true
+}
+
+func (c *syncCache[K, V]) deleteIfExpired(key K) {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	c.removeIfExpired(key, time.Now())
+}
+
+// removeIfExpired is deleteIfExpired without locking, it must be called with
+// the lock held.
+func (c *syncCache[K, V]) removeIfExpired(key K, now time.Time) {
+	cacheValue, ok := c.get(key)
+	if ok && isExpired(cacheValue, now) {
+		c.data.Delete(key)
+		c.scan.Delete(key)
+		c.records.Delete(key, cacheValue.ExpiresAt())
+		c.events.Emit(ExpiredEvent, key)
+	}
 }
 
-func (c *syncCache[K, V]) DeleteMany(keys []K) {
+// GetMany returns the values of the keys in order, with nil for the missing
+// ones. The values are read without locking, the lock is only taken once to
+// delete the expired keys and push back idle expirations.
+func (c *syncCache[K, V]) GetMany(keys []K) []*V {
+	now := time.Now()
+	values := make([]*V, len(keys))
+	var expired, idle []K
+	for i, key := range keys {
+		cacheValue, ok := c.get(key)
+		if !ok {
+			continue
+		}
+		if isExpired(cacheValue, now) {
+			expired = append(expired, key)
+			continue
+		}
+		if cacheValue.IdleTimeout() > 0 {
+			idle = append(idle, key)
+		}
+		value := cacheValue.Value()
+		values[i] = &value
+	}
+	if len(expired) > 0 || len(idle) > 0 {
+		c.locker.Lock()
+		defer c.locker.Unlock()
+		for _, key := range expired {
+			c.removeIfExpired(key, now)
+		}
+		for _, key := range idle {
+			c.refresh(key, now)
+		}
+	}
+	return values
+}
+
+// SetMany stores the values under a single lock, like Set would one by one.
+func (c *syncCache[K, V]) SetMany(entries []KeyValue[K, V], expiresAt time.Time) {
+	now := time.Now()
+	if !expiresAt.IsZero() && expiresAt.Before(now) {
+		c.logger.Warning(fmt.Sprintf("[SYNC_CACHE_EVENT] Attempted to set %d keys with past expiration time", len(entries)))
+		return
+	}
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	for _, entry := range entries {
+		c.store(entry.Key, newValue(entry.Value, expiresAt, c.idlePolicy, now), nil)
+	}
+}
+
+// DeleteMany deletes the keys under a single lock and returns the number of
+// keys deleted.
+func (c *syncCache[K, V]) DeleteMany(keys []K) int {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	deleted := 0
 	for _, key := range keys {
-		c.Delete(key)
+		cacheValue, ok := c.get(key)
+		if !ok {
+			continue
+		}
+		c.data.Delete(key)
+		c.scan.Delete(key)
+		c.records.Delete(key, cacheValue.ExpiresAt())
+		c.events.Emit(DelEvent, key)
+		deleted++
 	}
+	return deleted
 }
 
+// ExpireSample looks at up to n keys having an expiration time and deletes the
+// expired ones, it returns the number of keys sampled and deleted. The scan is
+// bounded so that persistent keys do not make it go over the whole cache.
+func (c *syncCache[K, V]) ExpireSample(n int) (int, int) {
+	now := time.Now()
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	sampled, expired, visited := 0, 0, 0
+	c.data.Range(func(key, value any) bool {
+		if sampled == n || visited == n*ActiveExpireScanFactor {
+			return false
+		}
+		visited++
+		cacheValue := value.(CacheValue[V])
+		if cacheValue.ExpiresAt().IsZero() {
+			return true
+		}
+		sampled++
+		if isExpired(cacheValue, now) {
+			c.data.Delete(key)
+			c.scan.Delete(key.(K))
+			c.records.Delete(key.(K), cacheValue.ExpiresAt())
+			c.events.Emit(ExpiredEvent, key.(K))
+			expired++
+		}
+		return true
+	})
+	return sampled, expired
+}
+
+// ClearExpired deletes the keys whose expiration time is over in batches, like
+// in the main cache.
 func (c *syncCache[K, V]) ClearExpired() int {
 	now := time.Now()
 	c.logger.Info("[SYNC_CACHE_EVENT] clearing expired keys...")
-	nbrKeys := c.records.DeleteBefore(now, func(keys []K) {
-		c.DeleteMany(keys)
+	var keys []K
+	c.locker.Lock()
+	c.records.DeleteBefore(now, func(expired []K) {
+		keys = append(keys, expired...)
 	})
+	c.locker.Unlock()
+	nbrKeys := 0
+	for batch := range slices.Chunk(keys, ClearExpiredBatchSize) {
+		nbrKeys += c.clearExpired(batch, now)
+	}
 	c.logger.Info(fmt.Sprintf("[SYNC_CACHE_EVENT] clearing expired keys done: cleared %d keys", nbrKeys))
 	return nbrKeys
 }
 
+// clearExpired deletes the given keys, whose records were deleted, if they are
+// expired and returns the number of keys deleted.
+func (c *syncCache[K, V]) clearExpired(keys []K, now time.Time) int {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	nbrKeys := 0
+	for _, key := range keys {
+		cacheValue, ok := c.get(key)
+		if !ok {
+			continue
+		}
+		if !isExpired(cacheValue, now) {
+			// The record was cleared too early or the key was written since
+			c.records.Add(key, cacheValue.ExpiresAt())
+			continue
+		}
+		c.data.Delete(key)
+		c.scan.Delete(key)
+		c.events.Emit(ExpiredEvent, key)
+		nbrKeys++
+	}
+	return nbrKeys
+}
+
 func (c *syncCache[K, V]) Clear() {
+	c.locker.Lock()
+	defer c.locker.Unlock()
 	c.logger.Info("[SYNC_CACHE_EVENT] clearing all...")
 	c.data.Clear()
-	c.logger.Info("[SYNC_CACHE_EVENT] clearing all...")
+	c.scan.Clear()
+	c.records.Clear()
+	c.logger.Info("[SYNC_CACHE_EVENT] clearing all done")
+}
+
+// All iterates over the keys and values that are not expired. No lock is held
+// while the loop runs, so that keys written meanwhile may or may not be seen.
+func (c *syncCache[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		now := time.Now()
+		c.data.Range(func(key, value any) bool {
+			cacheValue := value.(CacheValue[V])
+			if isExpired(cacheValue, now) {
+				return true
+			}
+			return yield(key.(K), cacheValue.Value())
+		})
+	}
+}
+
+// Keys iterates over the keys that are not expired, like All.
+func (c *syncCache[K, V]) Keys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for key := range c.All() {
+			if !yield(key) {
+				return
+			}
+		}
+	}
+}
+
+// Scan returns the keys that are not expired among the count ones coming next
+// from cursor on in the order of their position, along with the cursor of the
+// next call, see scanIndex. The locker is held during the call as the index
+// is only kept in line with data by writes.
+func (c *syncCache[K, V]) Scan(cursor int, count int) ([]K, int) {
+	now := time.Now()
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	return c.scan.Scan(cursor, count, func(key K) bool {
+		cacheValue, _ := c.get(key)
+		return isExpired(cacheValue, now)
+	})
+}
+
+// Events returns the bus of the events happening to the keys of the cache.
+// Keys deleted by Clear are not notified.
+func (c *syncCache[K, V]) Events() KeyspaceEvents[K] {
+	return c.events
 }
 
 func (c *syncCache[K, V]) String() string {
@@ -6,9 +6,9 @@ import (
 
 type CacheManager[K comparable, V any] interface {
 	Get(bool) Cache[K, V]
-	SetupMainCache(time.Duration) Error
+	SetupMainCache(CacheConfig) Error
 	SetupMainCacheJanitor(time.Duration) Error
-	SetupSyncCache(time.Duration) Error
+	SetupSyncCache(CacheConfig) Error
 	SetupSyncCacheJanitor(time.Duration) Error
 	StartJanitors()
 	StopJanitors()
@@ -34,9 +34,9 @@ func (cm *cacheManager[K, V]) Get(frequentAccess bool) Cache[K, V] {
 	return cm.cache
 }
 
-func (cm *cacheManager[K, V]) SetupMainCache(precision time.Duration) Error {
+func (cm *cacheManager[K, V]) SetupMainCache(config CacheConfig) Error {
 	cm.logger.Info("Setting up main cache...")
-	cache, err := NewCache[K, V](precision, cm.logger)
+	cache, err := NewCache[K, V](config, cm.logger)
 	if err != nil {
 		return err
 	}
@@ -56,9 +56,9 @@ func (cm *cacheManager[K, V]) SetupMainCacheJanitor(interval time.Duration) Erro
 	return nil
 }
 
-func (cm *cacheManager[K, V]) SetupSyncCache(precision time.Duration) Error {
+func (cm *cacheManager[K, V]) SetupSyncCache(config CacheConfig) Error {
 	cm.logger.Info("Setting up sync cache...")
-	syncCache, err := NewSyncCache[K, V](precision, cm.logger)
+	syncCache, err := NewSyncCache[K, V](config, cm.logger)
 	if err != nil {
 		return err
 	}
@@ -69,7 +69,7 @@ func (cm *cacheManager[K, V]) SetupSyncCache(precision time.Duration) Error {
 
 func (cm *cacheManager[K, V]) SetupSyncCacheJanitor(interval time.Duration) Error {
 	cm.logger.Info("Setting up sync cache janitor...")
-	janitor, err := NewJanitor(cm.cache, interval, cm.logger)
+	janitor, err := NewJanitor(cm.syncCache, interval, cm.logger)
 	if err != nil {
 		return err
 	}
@@ -81,20 +81,26 @@ func (cm *cacheManager[K, V]) SetupSyncCacheJanitor(interval time.Duration) Erro
 func (cm *cacheManager[K, V]) StartJanitors() {
 	cm.logger.Info("Starting janitors...")
 	cm.cacheJanitor.Start()
-	cm.syncCacheJanitor.Start()
+	if cm.syncCacheJanitor != nil {
+		cm.syncCacheJanitor.Start()
+	}
 	cm.logger.Info("Janitors started successfully")
 }
 
 func (cm *cacheManager[K, V]) StopJanitors() {
 	cm.logger.Info("Stopping janitors...")
 	cm.cacheJanitor.Stop()
-	cm.syncCacheJanitor.Stop()
+	if cm.syncCacheJanitor != nil {
+		cm.syncCacheJanitor.Stop()
+	}
 	cm.logger.Info("Janitors stopped successfully")
 }
 
 func (cm *cacheManager[K, V]) ClearCaches() {
 	cm.logger.Info("Clearing caches...")
 	cm.cache.Clear()
-	cm.syncCache.Clear()
+	if cm.syncCache != nil {
+		cm.syncCache.Clear()
+	}
 	cm.logger.Info("Caches cleared successfully")
 }
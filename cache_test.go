@@ -0,0 +1,140 @@
+package main
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestCaches(t *testing.T) map[string]Cache[string, string] {
+	logger := NewLogger(io.Discard, "", 0)
+	mainCache, err := NewCache[string, string](CacheConfig{Precision: time.Minute}, logger)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	syncCache, err := NewSyncCache[string, string](CacheConfig{Precision: time.Minute}, logger)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	return map[string]Cache[string, string]{"cache": mainCache, "syncCache": syncCache}
+}
+
+func TestExpireKeepsIdleTimeout(t *testing.T) {
+	for name, cache := range newTestCaches(t) {
+		cache.SetIdle("idl2", "v", 50*time.Millisecond, time.Time{})
+		if !cache.Expire("idl2", time.Now().Add(time.Hour)) {
+			t.Fatalf("%s: Expire failed", name)
+		}
+		value, ok := cache.get("idl2")
+		if !ok || value.IdleTimeout() != 50*time.Millisecond || value.Deadline().IsZero() {
+			t.Fatalf("%s: idle timeout or deadline lost by Expire", name)
+		}
+		if cache.Persist("idl2") {
+			t.Errorf("%s: Persist removed the idle timeout", name)
+		}
+		time.Sleep(100 * time.Millisecond)
+		if _, ok := cache.Get("idl2"); ok {
+			t.Errorf("%s: key still readable after its idle timeout", name)
+		}
+	}
+}
+
+func TestExpireCappedAtDeadline(t *testing.T) {
+	for name, cache := range newTestCaches(t) {
+		deadline := time.Now().Add(time.Hour)
+		cache.SetIdle("k", "v", time.Minute, deadline)
+		cache.Expire("k", deadline.Add(time.Hour))
+		if value, _ := cache.get("k"); !value.Deadline().Equal(deadline) || value.ExpiresAt().After(deadline) {
+			t.Errorf("%s: Expire went past the deadline", name)
+		}
+		earlier := deadline.Add(-30 * time.Minute)
+		cache.Expire("k", earlier)
+		if value, _ := cache.get("k"); !value.Deadline().Equal(earlier) {
+			t.Errorf("%s: got deadline %v, want %v", name, value.Deadline(), earlier)
+		}
+	}
+}
+
+func TestExpireWithoutIdleTimeout(t *testing.T) {
+	for name, cache := range newTestCaches(t) {
+		cache.Set("k", "v", time.Time{})
+		expiresAt := time.Now().Add(time.Hour)
+		cache.Expire("k", expiresAt)
+		if value, _ := cache.get("k"); !value.ExpiresAt().Equal(expiresAt) {
+			t.Errorf("%s: got expiration %v, want %v", name, value.ExpiresAt(), expiresAt)
+		}
+		if !cache.Persist("k") {
+			t.Errorf("%s: Persist failed", name)
+		}
+		if value, _ := cache.get("k"); !value.ExpiresAt().IsZero() {
+			t.Errorf("%s: key still expiring after Persist", name)
+		}
+	}
+}
+
+func TestExpireChangesVersion(t *testing.T) {
+	for name, cache := range newTestCaches(t) {
+		cache.Set("k", "v", time.Time{})
+		value, _ := cache.get("k")
+		version := value.Version()
+		cache.Expire("k", time.Now().Add(time.Hour))
+		value, _ = cache.get("k")
+		if value.Version() == version {
+			t.Errorf("%s: version unchanged by Expire", name)
+		}
+		version = value.Version()
+		cache.Persist("k")
+		value, _ = cache.get("k")
+		if value.Version() == version {
+			t.Errorf("%s: version unchanged by Persist", name)
+		}
+	}
+}
+
+func TestClearExpiredInBatches(t *testing.T) {
+	nbrKeys := 2*ClearExpiredBatchSize + 500
+	for _, config := range []CacheConfig{
+		{Precision: 2 * time.Second, Records: OrderedRecords},
+		{Precision: time.Millisecond, Records: TimingWheelRecords},
+	} {
+		var logs strings.Builder
+		logger := NewLogger(&logs, "", 0)
+		mainCache, err := NewCache[string, string](config, logger)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		syncCache, err := NewSyncCache[string, string](config, logger)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		caches := map[string]Cache[string, string]{"cache": mainCache, "syncCache": syncCache}
+		expiresAt := time.Now().Add(200 * time.Millisecond)
+		for _, cache := range caches {
+			for i := range nbrKeys {
+				cache.Set(strconv.Itoa(i), "v", expiresAt)
+			}
+		}
+		// Wait for the records of the keys to be over too
+		time.Sleep(time.Until(expiresAt) + config.Precision + 5*time.Millisecond)
+
+		for name, cache := range caches {
+			// A key written again once expired must be kept
+			cache.Set("0", "v", time.Now().Add(time.Hour))
+			logs.Reset()
+			if cleared := cache.ClearExpired(); cleared != nbrKeys-1 {
+				t.Fatalf("%s: got %d keys cleared, want %d", name, cleared, nbrKeys-1)
+			}
+			if warnings := strings.Count(logs.String(), "WARNING"); warnings > 0 {
+				t.Errorf("%s: got %d warnings clearing expired keys", name, warnings)
+			}
+			if _, ok := cache.Get("0"); !ok {
+				t.Fatalf("%s: key written again was cleared", name)
+			}
+			if _, ok := cache.get(strconv.Itoa(nbrKeys - 1)); ok {
+				t.Fatalf("%s: expired key was not cleared", name)
+			}
+		}
+	}
+}
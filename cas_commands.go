@@ -0,0 +1,68 @@
+package main
+
+// getsCommand replies the value of a key holding a string along with its
+// version, to be passed to CAS.
+type getsCommand struct {
+	Command
+}
+
+func NewGetsCommand() ExecutableCommand[string, Value] {
+	return &getsCommand{
+		Command: NewCommand("GETS").
+			WithArgument(KeyCommandArgument).
+			WithOption(FrequentAccessOption),
+	}
+}
+
+func (c *getsCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	// The value and its version are read together, a later read through Get
+	// only deletes the key if it expired or pushes back its idle expiration.
+	cacheValue, ok := cache.get(key)
+	if !ok {
+		return &nilResult[Value]{}, nil
+	}
+	if _, ok := cache.Get(key); !ok {
+		return &nilResult[Value]{}, nil
+	}
+	if cacheValue.Value().Type() != StringData {
+		return nil, &WrongTypeError{}
+	}
+	return &arrayResult[Value]{items: []Result[Value]{
+		&valueResult[Value]{value: cacheValue.Value()},
+		&integerResult[Value]{value: int64(cacheValue.Version())},
+	}}, nil
+}
+
+// casCommand sets a key only when its value was not written since the version
+// given was read, replying whether it was set.
+type casCommand struct {
+	Command
+}
+
+func NewCasCommand() ExecutableCommand[string, Value] {
+	return &casCommand{
+		Command: NewCommand("CAS").
+			WithArgument(KeyCommandArgument).
+			WithArgument(ValueArgument).
+			WithArgument(VersionArgument).
+			WithOption(ExpirationOption).
+			WithOption(ExpirationMsOption).
+			WithOption(FrequentAccessOption),
+	}
+}
+
+func (c *casCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	value := input.GetArgument(*ValueArgument).(string)
+	version := input.GetArgument(*VersionArgument).(int)
+	expiresAt, err := expirationFromInput(c.GetName(), input)
+	if err != nil {
+		return nil, err
+	}
+	if version <= 0 {
+		return booleanResult[Value](false), nil
+	}
+	_, ok := cache.SetIf(key, StringValue(value), expiresAt, IfVersion[Value](uint64(version)))
+	return booleanResult[Value](ok), nil
+}
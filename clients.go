@@ -0,0 +1,209 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type ClientRegistry interface {
+	Register(Connection)
+	Unregister(Connection)
+	Get(int64) (Connection, bool)
+	FindByAddr(string) []Connection
+	CountByIP(string) int
+	List() []Connection
+	Len() int
+	CloseAll()
+}
+
+type clientRegistry struct {
+	mu      sync.RWMutex
+	clients map[int64]Connection
+	logger  Logger
+}
+
+func NewClientRegistry(logger Logger) ClientRegistry {
+	return &clientRegistry{
+		clients: make(map[int64]Connection),
+		logger:  logger,
+	}
+}
+
+func (r *clientRegistry) Register(connection Connection) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.clients[connection.ID()] = connection
+	r.logger.Info(fmt.Sprintf("[CLIENTS_EVENT] Client %d registered from %s", connection.ID(), connection.RemoteAddr()))
+}
+
+func (r *clientRegistry) Unregister(connection Connection) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.clients, connection.ID())
+	r.logger.Info(fmt.Sprintf("[CLIENTS_EVENT] Client %d unregistered", connection.ID()))
+}
+
+func (r *clientRegistry) Get(id int64) (Connection, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	connection, ok := r.clients[id]
+	return connection, ok
+}
+
+func (r *clientRegistry) FindByAddr(addr string) []Connection {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var connections []Connection
+	for _, connection := range r.clients {
+		if connection.RemoteAddr().String() == addr {
+			connections = append(connections, connection)
+		}
+	}
+	return connections
+}
+
+func (r *clientRegistry) CountByIP(ip string) int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	count := 0
+	for _, connection := range r.clients {
+		if connectionIP(connection) == ip {
+			count++
+		}
+	}
+	return count
+}
+
+// List returns the registered connections ordered by ID.
+func (r *clientRegistry) List() []Connection {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	connections := make([]Connection, 0, len(r.clients))
+	for _, connection := range r.clients {
+		connections = append(connections, connection)
+	}
+	slices.SortFunc(connections, func(a, b Connection) int {
+		return cmp.Compare(a.ID(), b.ID())
+	})
+	return connections
+}
+
+func (r *clientRegistry) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.clients)
+}
+
+func (r *clientRegistry) CloseAll() {
+	for _, connection := range r.List() {
+		connection.Close()
+	}
+}
+
+func connectionIP(connection Connection) string {
+	addr := connection.RemoteAddr().String()
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
+
+func formatConnectionInfo(info ConnectionInfo, now time.Time) string {
+	return fmt.Sprintf("id=%d addr=%s name=%s age=%d idle=%d cmd=%s bytes-in=%d bytes-out=%d",
+		info.ID,
+		info.Addr,
+		info.Name,
+		int64(now.Sub(info.ConnectedAt).Seconds()),
+		int64(now.Sub(info.LastActivity).Seconds()),
+		strings.ToLower(info.LastCommand),
+		info.BytesIn,
+		info.BytesOut,
+	)
+}
+
+type clientCommand[K comparable, V any] struct {
+	connectionCommand[K, V]
+	clients ClientRegistry
+	logger  Logger
+}
+
+func NewClientCommand[K comparable, V any](clients ClientRegistry, logger Logger) ExecutableCommand[K, V] {
+	return &clientCommand[K, V]{
+		connectionCommand: connectionCommand[K, V]{
+			Command: NewCommand("CLIENT").
+				WithArgument(SubcommandArgument).
+				WithArgument(FilterArgument).
+				WithArgument(FilterValueArgument),
+		},
+		clients: clients,
+		logger:  logger,
+	}
+}
+
+func (c *clientCommand[K, V]) RunOnConnection(connection Connection, input CommandInput, cache Cache[K, V]) (Result[V], Error) {
+	subcommand := input.GetArgument(*SubcommandArgument).(string)
+	filter, hasFilter := input.GetArgument(*FilterArgument).(string)
+	filterValue, hasFilterValue := input.GetArgument(*FilterValueArgument).(string)
+
+	switch strings.ToUpper(subcommand) {
+	case "LIST":
+		now := time.Now()
+		lines := []string{}
+		for _, client := range c.clients.List() {
+			lines = append(lines, formatConnectionInfo(client.Info(), now))
+		}
+		return &textResult[V]{text: strings.Join(lines, "\n")}, nil
+	case "ID":
+		return &integerResult[V]{value: connection.ID()}, nil
+	case "GETNAME":
+		name := connection.Name()
+		if name == "" {
+			return &nilResult[V]{}, nil
+		}
+		return &textResult[V]{text: fmt.Sprintf("%q", name)}, nil
+	case "SETNAME":
+		if !hasFilter || hasFilterValue {
+			return nil, &InvalidCommandUsageError{command: c.GetName()}
+		}
+		if strings.ContainsAny(filter, " \n") {
+			return nil, &CommandError{message: "Client names cannot contain spaces or newlines"}
+		}
+		connection.SetName(filter)
+		return &okResult[V]{}, nil
+	case "KILL":
+		var targets []Connection
+		switch {
+		case hasFilter && !hasFilterValue:
+			targets = c.clients.FindByAddr(filter)
+		case hasFilter && strings.EqualFold(filter, "ADDR"):
+			targets = c.clients.FindByAddr(filterValue)
+		case hasFilter && strings.EqualFold(filter, "ID"):
+			id, err := strconv.ParseInt(filterValue, 10, 64)
+			if err != nil {
+				return nil, &InvalidCommandUsageError{command: c.GetName()}
+			}
+			if target, ok := c.clients.Get(id); ok {
+				targets = append(targets, target)
+			}
+		default:
+			return nil, &InvalidCommandUsageError{command: c.GetName()}
+		}
+		if len(targets) == 0 {
+			return nil, &CommandError{message: "No such client"}
+		}
+		for _, target := range targets {
+			c.logger.Info(fmt.Sprintf("[CLIENTS_EVENT] Killing client %d (%s)", target.ID(), target.RemoteAddr()))
+			target.Close()
+		}
+		return &integerResult[V]{value: int64(len(targets))}, nil
+	default:
+		return nil, &InvalidCommandUsageError{command: c.GetName()}
+	}
+}
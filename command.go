@@ -2,6 +2,7 @@ package main
 
 import (
 	"slices"
+	"strings"
 )
 
 type commandArgument struct {
@@ -9,6 +10,10 @@ type commandArgument struct {
 	description string
 	position    int
 	valueType   ValueType
+	optional    bool
+	// variadic arguments are the last ones and take all the remaining
+	// positional tokens, they are parsed as a []any of their type.
+	variadic bool
 }
 
 type commandOption struct {
@@ -22,11 +27,13 @@ type commandOption struct {
 type CommandInput interface {
 	GetArgument(commandArgument) any
 	GetOption(commandOption) any
+	Args() []string
 }
 
 type commandInput struct {
 	arguments map[commandArgument]any
 	options   map[commandOption]any
+	args      []string
 }
 
 func (c *commandInput) GetArgument(arg commandArgument) any {
@@ -37,9 +44,16 @@ func (c *commandInput) GetOption(opt commandOption) any {
 	return c.options[opt]
 }
 
+func (c *commandInput) Args() []string {
+	return c.args
+}
+
 type Command interface {
+	GetName() string
 	AddArgument(int, ValueType) Command
 	AddOption(rune, string, ValueType) Command
+	WithArgument(*commandArgument) Command
+	WithOption(*commandOption) Command
 	Parse([]string) (CommandInput, Error)
 }
 
@@ -53,6 +67,10 @@ func NewCommand(name string) Command {
 	return &command{Name: name}
 }
 
+func (c *command) GetName() string {
+	return c.Name
+}
+
 func (c *command) AddArgument(position int, valueType ValueType) Command {
 	c.Arguments = append(c.Arguments, commandArgument{
 		position:  position,
@@ -70,57 +88,130 @@ func (c *command) AddOption(letter rune, name string, valueType ValueType) Comma
 	return c
 }
 
+// WithArgument registers a predefined argument so that it can later be
+// retrieved from the parsed input with GetArgument.
+func (c *command) WithArgument(arg *commandArgument) Command {
+	c.Arguments = append(c.Arguments, *arg)
+	return c
+}
+
+// WithOption registers a predefined option so that it can later be
+// retrieved from the parsed input with GetOption.
+func (c *command) WithOption(opt *commandOption) Command {
+	c.Options = append(c.Options, *opt)
+	return c
+}
+
+// Parse reads the arguments and options of the command from its input. The
+// required arguments come first, options may follow in any order and the
+// remaining tokens fill the optional arguments. When the last argument is
+// variadic, options come before its values: every token from its first value
+// on is one of them, even one matching an option, so that keys and values are
+// never taken for options.
 func (c *command) Parse(input []string) (CommandInput, Error) {
 	inputLength := len(input)
 
-	if inputLength == 0 {
-		return nil, &InvalidCommandUsageError{command: c.Name}
-	}
-
 	inputArgs := make(map[commandArgument]any)
 	inputOpts := make(map[commandOption]any)
 
-	// Parse arguments
-	nbrArguments := len(c.Arguments)
+	nbrArguments := 0
+	var variadic *commandArgument
+	for i, arg := range c.Arguments {
+		if !arg.optional {
+			nbrArguments++
+		}
+		if arg.variadic {
+			variadic = &c.Arguments[i]
+		}
+	}
 	if inputLength < nbrArguments {
 		return nil, &InvalidCommandUsageError{command: c.Name}
 	}
-	for _, arg := range c.Arguments {
-		value, err := ParseValue(arg.valueType, input[arg.position])
+
+	// Parse options, which are only looked for after the required arguments
+	// and before the values of a variadic argument
+	nbrFixed := nbrArguments
+	if variadic != nil && !variadic.optional {
+		nbrFixed--
+	}
+	positional := slices.Clone(input[:nbrFixed])
+	for index := nbrFixed; index < inputLength; index++ {
+		if variadic != nil && len(positional) > variadic.position {
+			positional = append(positional, input[index:]...)
+			break
+		}
+		opt, ok := c.findOption(input[index])
+		if ok && variadic != nil && !variadic.optional && len(positional) == variadic.position {
+			// a required variadic argument keeps at least its last token
+			ok = index+opt.nbrTokens() < inputLength
+		}
+		if !ok {
+			positional = append(positional, input[index])
+			continue
+		}
+		if opt.valueType == NoType {
+			inputOpts[opt] = true
+			continue
+		}
+		index++
+		if index >= inputLength {
+			return nil, &InvalidCommandUsageError{command: c.Name}
+		}
+		value, err := ParseValue(opt.valueType, input[index])
 		if err != nil {
 			return nil, &InvalidCommandUsageError{command: c.Name}
 		}
-		inputArgs[arg] = value
+		inputOpts[opt] = value
 	}
 
-	// Parse options
-	for _, opt := range c.Options {
-		index := slices.Index(input, string(opt.letter))
-		if index == -1 {
-			index = slices.Index(input, opt.name)
+	// Parse arguments
+	for _, arg := range c.Arguments {
+		if arg.optional && arg.position >= len(positional) {
+			continue
 		}
-		if index != -1 {
-			if opt.valueType == NoType {
-				inputOpts[opt] = true
-			} else {
-				if index+1 >= inputLength {
-					return nil, &InvalidCommandUsageError{command: c.Name}
-				}
-				value, err := ParseValue(opt.valueType, input[index+1])
+		if arg.variadic {
+			values := make([]any, 0, len(positional)-arg.position)
+			for _, token := range positional[arg.position:] {
+				value, err := ParseValue(arg.valueType, token)
 				if err != nil {
 					return nil, &InvalidCommandUsageError{command: c.Name}
 				}
-				inputOpts[opt] = value
+				values = append(values, value)
 			}
+			inputArgs[arg] = values
+			continue
+		}
+		value, err := ParseValue(arg.valueType, positional[arg.position])
+		if err != nil {
+			return nil, &InvalidCommandUsageError{command: c.Name}
 		}
+		inputArgs[arg] = value
 	}
 
 	return &commandInput{
 		arguments: inputArgs,
 		options:   inputOpts,
+		args:      input,
 	}, nil
 }
 
+// nbrTokens is the number of tokens taken by the option, its value included.
+func (o commandOption) nbrTokens() int {
+	if o.valueType == NoType {
+		return 1
+	}
+	return 2
+}
+
+func (c *command) findOption(token string) (commandOption, bool) {
+	for _, opt := range c.Options {
+		if token == string(opt.letter) || strings.EqualFold(token, opt.name) {
+			return opt, true
+		}
+	}
+	return commandOption{}, false
+}
+
 type CommandManager interface {
 	Get(string) (Command, Error)
 	AddCommand(string, Command) CommandManager
@@ -131,7 +222,7 @@ type commandManager struct {
 }
 
 func NewCommandManager() CommandManager {
-	command_manager := &commandManager{}
+	command_manager := &commandManager{commands: make(map[string]Command)}
 	return command_manager
 }
 
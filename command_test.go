@@ -0,0 +1,88 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// testValuesArgument follows a key, like the values of the commands writing
+// several fields or items of a key.
+var testValuesArgument = &commandArgument{label: "values", position: 1, valueType: TypeString, variadic: true}
+
+func TestParseVariadicTokensMatchingOptions(t *testing.T) {
+	mget := NewCommand("MGET").WithArgument(KeysArgument).WithOption(FrequentAccessOption)
+	mset := NewCommand("MSET").WithArgument(KeyValuesArgument).WithOption(ExpirationOption).WithOption(ExpirationMsOption)
+	add := NewCommand("ADD").WithArgument(KeyCommandArgument).WithArgument(testValuesArgument).WithOption(ExpirationOption).WithOption(ExpirationMsOption)
+	tests := []struct {
+		command  Command
+		input    []string
+		argument *commandArgument
+		values   []string
+		options  map[*commandOption]any
+	}{
+		{mget, []string{"a", "f"}, KeysArgument, []string{"a", "f"}, nil},
+		{mget, []string{"f"}, KeysArgument, []string{"f"}, nil},
+		{mget, []string{"f", "a"}, KeysArgument, []string{"a"}, map[*commandOption]any{FrequentAccessOption: true}},
+		{mget, []string{"frequent-access", "a", "frequent-access"}, KeysArgument, []string{"a", "frequent-access"}, map[*commandOption]any{FrequentAccessOption: true}},
+		{mset, []string{"k1", "v1", "k2", "e"}, KeyValuesArgument, []string{"k1", "v1", "k2", "e"}, nil},
+		{mset, []string{"e", "10", "k1", "m"}, KeyValuesArgument, []string{"k1", "m"}, map[*commandOption]any{ExpirationOption: 10}},
+		{mset, []string{"e", "10"}, KeyValuesArgument, []string{"e", "10"}, nil},
+		{add, []string{"h", "a", "b", "e", "100"}, testValuesArgument, []string{"a", "b", "e", "100"}, nil},
+		{add, []string{"h", "e", "100", "a", "b"}, testValuesArgument, []string{"a", "b"}, map[*commandOption]any{ExpirationOption: 100}},
+		{add, []string{"h", "m", "5", "e"}, testValuesArgument, []string{"e"}, map[*commandOption]any{ExpirationMsOption: 5}},
+	}
+	for _, test := range tests {
+		input, err := test.command.Parse(test.input)
+		if err != nil {
+			t.Errorf("%s %v: unexpected error %v", test.command.GetName(), test.input, err)
+			continue
+		}
+		values, _ := input.GetArgument(*test.argument).([]any)
+		if got := toStrings(values); !slices.Equal(got, test.values) {
+			t.Errorf("%s %v: got %v, want %v", test.command.GetName(), test.input, got, test.values)
+		}
+		for _, opt := range []*commandOption{FrequentAccessOption, ExpirationOption, ExpirationMsOption} {
+			if got, want := input.GetOption(*opt), test.options[opt]; got != want {
+				t.Errorf("%s %v: got option %s %v, want %v", test.command.GetName(), test.input, opt.name, got, want)
+			}
+		}
+	}
+}
+
+func TestParseOptionsAfterRequiredArguments(t *testing.T) {
+	set := NewCommand("SET").
+		WithArgument(KeyCommandArgument).
+		WithArgument(ValueArgument).
+		WithArgument(TTLArgument).
+		WithOption(ExpirationOption).
+		WithOption(FrequentAccessOption)
+	input, err := set.Parse([]string{"e", "f", "e", "10", "60", "f"})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if key := input.GetArgument(*KeyCommandArgument); key != "e" {
+		t.Errorf("got key %v, want e", key)
+	}
+	if value := input.GetArgument(*ValueArgument); value != "f" {
+		t.Errorf("got value %v, want f", value)
+	}
+	if ttl := input.GetArgument(*TTLArgument); ttl != 60 {
+		t.Errorf("got ttl %v, want 60", ttl)
+	}
+	if expiresIn := input.GetOption(*ExpirationOption); expiresIn != 10 {
+		t.Errorf("got expires-in %v, want 10", expiresIn)
+	}
+	if input.GetOption(*FrequentAccessOption) != true {
+		t.Errorf("frequent-access not set")
+	}
+}
+
+func TestParseMissingVariadicValues(t *testing.T) {
+	add := NewCommand("ADD").WithArgument(KeyCommandArgument).WithArgument(testValuesArgument).WithOption(ExpirationOption)
+	if _, err := add.Parse([]string{"h"}); err == nil {
+		t.Errorf("expected an error without values")
+	}
+	if _, err := add.Parse([]string{"h", "e"}); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -2,11 +2,113 @@ package main
 
 // Command arguments
 var (
-	KeyCommandArgument = &commandArgument{label: "key", position: 0, valueType: TypeString, description: "a unique identifier for quickly storing and retrieving specific data"}
+	KeyCommandArgument        = &commandArgument{label: "key", position: 0, valueType: TypeString, description: "a unique identifier for quickly storing and retrieving specific data"}
+	KeysArgument              = &commandArgument{label: "keys", position: 0, valueType: TypeString, variadic: true, description: "one or more keys"}
+	KeyValuesArgument         = &commandArgument{label: "key value pairs", position: 0, valueType: TypeString, variadic: true, description: "one or more keys each followed by its value"}
+	PatternArgument           = &commandArgument{label: "pattern", position: 0, valueType: TypeString, description: "a glob-style pattern matched by keys, supporting *, ? and [abc]"}
+	CursorArgument            = &commandArgument{label: "cursor", position: 0, valueType: TypeInt, description: "the cursor returned by the previous call, 0 to start a new iteration"}
+	ChannelArgument           = &commandArgument{label: "channel", position: 0, valueType: TypeString, description: "the channel a message is published to"}
+	MessageArgument           = &commandArgument{label: "message", position: 1, valueType: TypeString, description: "the message published"}
+	ChannelsArgument          = &commandArgument{label: "channels", position: 0, valueType: TypeString, variadic: true, description: "one or more channels or channel patterns"}
+	OptionalChannelsArgument  = &commandArgument{label: "channels", position: 0, valueType: TypeString, variadic: true, optional: true, description: "the channels or channel patterns to unsubscribe from, all of them when there are none"}
+	SubcommandArgument        = &commandArgument{label: "subcommand", position: 0, valueType: TypeString, description: "the operation to perform for commands grouping several ones"}
+	CountArgument             = &commandArgument{label: "count", position: 1, valueType: TypeInt, optional: true, description: "maximum number of entries to return"}
+	FilterArgument            = &commandArgument{label: "filter", position: 1, valueType: TypeString, optional: true, description: "the name or the kind of filter used to select the targets of a subcommand"}
+	FilterValueArgument       = &commandArgument{label: "filter value", position: 2, valueType: TypeString, optional: true, description: "the value matched by the filter"}
+	ValueArgument             = &commandArgument{label: "value", position: 1, valueType: TypeString, description: "the value stored under the key"}
+	TTLArgument               = &commandArgument{label: "ttl", position: 2, valueType: TypeInt, optional: true, description: "period in seconds until the key value pair are deleted"}
+	SecondsArgument           = &commandArgument{label: "seconds", position: 1, valueType: TypeInt, description: "period in seconds until the key is deleted"}
+	MillisecondsArgument      = &commandArgument{label: "milliseconds", position: 1, valueType: TypeInt, description: "period in milliseconds until the key is deleted"}
+	TimestampArgument         = &commandArgument{label: "timestamp", position: 1, valueType: TypeInt, description: "unix time in seconds at which the key is deleted"}
+	IncrementArgument         = &commandArgument{label: "increment", position: 1, valueType: TypeInt, description: "the integer added to the value of the key"}
+	DecrementArgument         = &commandArgument{label: "decrement", position: 1, valueType: TypeInt, description: "the integer subtracted from the value of the key"}
+	FloatIncrementArgument    = &commandArgument{label: "increment", position: 1, valueType: TypeFloat, description: "the float added to the value of the key"}
+	ElementsArgument          = &commandArgument{label: "elements", position: 1, valueType: TypeString, variadic: true, description: "one or more elements pushed to the list"}
+	StartArgument             = &commandArgument{label: "start", position: 1, valueType: TypeInt, description: "the index of the first element, negative indexes counting from the end"}
+	StopArgument              = &commandArgument{label: "stop", position: 2, valueType: TypeInt, description: "the index of the last element included, negative indexes counting from the end"}
+	KeysTimeoutArgument       = &commandArgument{label: "keys and timeout", position: 0, valueType: TypeString, variadic: true, description: "one or more keys followed by the timeout in seconds, 0 to wait forever"}
+	FieldArgument             = &commandArgument{label: "field", position: 1, valueType: TypeString, description: "a field of the hash stored under the key"}
+	FieldsArgument            = &commandArgument{label: "fields", position: 1, valueType: TypeString, variadic: true, description: "one or more fields of the hash stored under the key"}
+	FieldValuesArgument       = &commandArgument{label: "field value pairs", position: 1, valueType: TypeString, variadic: true, description: "one or more fields each followed by its value"}
+	FieldIncrementArgument    = &commandArgument{label: "increment", position: 2, valueType: TypeInt, description: "the integer added to the value of the field"}
+	ExpiringFieldsArgument    = &commandArgument{label: "fields", position: 2, valueType: TypeString, variadic: true, description: "one or more fields whose expiration is set"}
+	MemberArgument            = &commandArgument{label: "member", position: 1, valueType: TypeString, description: "a member of the set stored under the key"}
+	MembersArgument           = &commandArgument{label: "members", position: 1, valueType: TypeString, variadic: true, description: "one or more members of the set stored under the key"}
+	ScoreMembersArgument      = &commandArgument{label: "score member pairs", position: 1, valueType: TypeString, variadic: true, description: "one or more scores each followed by its member"}
+	IncrementedMemberArgument = &commandArgument{label: "member", position: 2, valueType: TypeString, description: "the member whose score is incremented"}
+	MinScoreArgument          = &commandArgument{label: "min", position: 1, valueType: TypeString, description: "the lowest score included, excluded when prefixed by '(', or -inf"}
+	MaxScoreArgument          = &commandArgument{label: "max", position: 2, valueType: TypeString, description: "the highest score included, excluded when prefixed by '(', or +inf"}
+	VersionArgument           = &commandArgument{label: "version", position: 2, valueType: TypeInt, description: "the version of the value expected by a compare-and-swap, as returned by GETS"}
+	JSONPathArgument          = &commandArgument{label: "path", position: 1, valueType: TypeString, description: "a JSONPath-like path such as $.a.b[0] in the document, $ being the whole document"}
+	OptionalJSONPathArgument  = &commandArgument{label: "path", position: 1, valueType: TypeString, optional: true, description: "a JSONPath-like path such as $.a.b[0] in the document, the whole document when there is none"}
+	JSONValueArgument         = &commandArgument{label: "value", position: 2, valueType: TypeString, description: "a JSON value, written without spaces"}
+	JSONValuesArgument        = &commandArgument{label: "values", position: 2, valueType: TypeString, variadic: true, description: "one or more JSON values, written without spaces"}
+	NumberIncrementArgument   = &commandArgument{label: "increment", position: 2, valueType: TypeString, description: "the number added to the number at the path"}
+	ItemArgument              = &commandArgument{label: "item", position: 1, valueType: TypeString, description: "an item added to or looked up in the structure stored under the key"}
+	ItemsArgument             = &commandArgument{label: "items", position: 1, valueType: TypeString, variadic: true, description: "one or more items added to or looked up in the structure stored under the key"}
+	ItemIncrementsArgument    = &commandArgument{label: "item increment pairs", position: 1, valueType: TypeString, variadic: true, description: "one or more items each followed by the positive integer added to its count"}
+	SourceKeysArgument        = &commandArgument{label: "source keys", position: 1, valueType: TypeString, variadic: true, description: "one or more keys whose values are merged into the key"}
+	ErrorRateArgument         = &commandArgument{label: "error rate", position: 1, valueType: TypeFloat, description: "the rate of false positives allowed, between 0 and 1 excluded"}
+	CapacityArgument          = &commandArgument{label: "capacity", position: 2, valueType: TypeInt, description: "the number of items expected before the filter grows"}
+	WidthArgument             = &commandArgument{label: "width", position: 1, valueType: TypeInt, description: "the number of counters in each row of the sketch"}
+	DepthArgument             = &commandArgument{label: "depth", position: 2, valueType: TypeInt, description: "the number of rows of the sketch"}
+	EstimateErrorArgument     = &commandArgument{label: "error", position: 1, valueType: TypeFloat, description: "the overestimate allowed, as a fraction of the total of the increments between 0 and 1 excluded"}
+	ProbabilityArgument       = &commandArgument{label: "probability", position: 2, valueType: TypeFloat, description: "the probability of an estimate exceeding the error allowed, between 0 and 1 excluded"}
+	StreamIDArgument          = &commandArgument{label: "id", position: 1, valueType: TypeString, description: "the ID of the entry written ms-seq, * to generate it or ms-* to only generate its sequence number"}
+	StreamFieldValuesArgument = &commandArgument{label: "field value pairs", position: 2, valueType: TypeString, variadic: true, description: "one or more fields of the entry each followed by its value"}
+	StartIDArgument           = &commandArgument{label: "start", position: 1, valueType: TypeString, description: "the lowest ID included, - for the first entry"}
+	EndIDArgument             = &commandArgument{label: "end", position: 2, valueType: TypeString, description: "the highest ID included, + for the last entry"}
+	KeysIDsArgument           = &commandArgument{label: "keys and ids", position: 0, valueType: TypeString, variadic: true, description: "one or more keys followed by as many IDs, the entries added after each ID being read and $ standing for the last entry"}
+	ReadGroupArgument         = &commandArgument{label: "group", position: 0, valueType: TypeString, description: "the consumer group reading the streams"}
+	ReadConsumerArgument      = &commandArgument{label: "consumer", position: 1, valueType: TypeString, description: "the consumer of the group reading the entries"}
+	GroupKeysIDsArgument      = &commandArgument{label: "keys and ids", position: 2, valueType: TypeString, variadic: true, description: "one or more keys followed by as many IDs, > reading the entries never delivered to the group and an ID the pending entries of the consumer after it"}
+	GroupArgument             = &commandArgument{label: "group", position: 1, valueType: TypeString, description: "a consumer group of the stream stored under the key"}
+	ConsumerArgument          = &commandArgument{label: "consumer", position: 2, valueType: TypeString, description: "the consumer of the group the entries are given to"}
+	EntryIDsArgument          = &commandArgument{label: "ids", position: 2, valueType: TypeString, variadic: true, description: "one or more IDs of entries of the stream"}
+	MinIdleTimeArgument       = &commandArgument{label: "min idle time", position: 3, valueType: TypeInt, description: "the time in milliseconds an entry must have been pending for to be claimed"}
+	ClaimIDsArgument          = &commandArgument{label: "ids", position: 4, valueType: TypeString, variadic: true, description: "one or more IDs of pending entries"}
+	ClaimStartArgument        = &commandArgument{label: "start", position: 4, valueType: TypeString, description: "the ID the pending entries are looked at from, 0-0 for the first call"}
+	PendingStartArgument      = &commandArgument{label: "start", position: 2, valueType: TypeString, optional: true, description: "the lowest ID of the pending entries listed, - for the first one"}
+	PendingEndArgument        = &commandArgument{label: "end", position: 3, valueType: TypeString, optional: true, description: "the highest ID of the pending entries listed, + for the last one"}
+	PendingCountArgument      = &commandArgument{label: "count", position: 4, valueType: TypeInt, optional: true, description: "the maximum number of pending entries listed"}
+	StreamKeyArgument         = &commandArgument{label: "key", position: 1, valueType: TypeString, description: "the key of the stream"}
+	GroupNameArgument         = &commandArgument{label: "group", position: 2, valueType: TypeString, description: "the name of the consumer group"}
+	GroupStartIDArgument      = &commandArgument{label: "id", position: 3, valueType: TypeString, optional: true, description: "the ID after which the group reads the entries, $ for the entries added from now on"}
+	LonLatMembersArgument     = &commandArgument{label: "longitude latitude member triples", position: 1, valueType: TypeString, variadic: true, description: "one or more longitudes in degrees each followed by a latitude in degrees and the member located there"}
+	FirstMemberArgument       = &commandArgument{label: "member", position: 1, valueType: TypeString, description: "a member of the sorted set stored under the key"}
+	SecondMemberArgument      = &commandArgument{label: "other member", position: 2, valueType: TypeString, description: "another member of the sorted set stored under the key"}
+	DistanceUnitArgument      = &commandArgument{label: "unit", position: 3, valueType: TypeString, optional: true, description: "the unit of the distance replied: m, km, mi or ft"}
+	GeoSearchArgument         = &commandArgument{label: "search", position: 1, valueType: TypeString, variadic: true, description: "FROMMEMBER member or FROMLONLAT longitude latitude, then BYRADIUS radius unit or BYBOX width height unit, and optionally ASC or DESC, COUNT count, WITHDIST, WITHHASH and WITHCOORD"}
+	DimensionArgument         = &commandArgument{label: "dimension", position: 1, valueType: TypeInt, description: "the number of values of the vectors of the index"}
+	MetricArgument            = &commandArgument{label: "metric", position: 2, valueType: TypeString, description: "how vectors are compared: COSINE, L2 or DOT"}
+	AlgorithmArgument         = &commandArgument{label: "algorithm", position: 3, valueType: TypeString, optional: true, description: "FLAT for exact searches only or HNSW for an approximate index, FLAT when there is none"}
+	ConnectionsArgument       = &commandArgument{label: "connections", position: 4, valueType: TypeInt, optional: true, description: "the number of neighbours each vector is linked to in the HNSW graph"}
+	EfConstructionArgument    = &commandArgument{label: "ef construction", position: 5, valueType: TypeInt, optional: true, description: "the number of candidates the neighbours of a vector are picked from in the HNSW graph"}
+	VectorKeyArgument         = &commandArgument{label: "vector key", position: 1, valueType: TypeString, description: "the key of a vector in the index"}
+	VectorArgument            = &commandArgument{label: "vector", position: 2, valueType: TypeString, variadic: true, description: "the values of the vector, as many as the dimension of the index"}
+	VectorKeysArgument        = &commandArgument{label: "vector keys", position: 1, valueType: TypeString, variadic: true, description: "one or more keys of vectors in the index"}
+	NeighboursCountArgument   = &commandArgument{label: "k", position: 1, valueType: TypeInt, description: "the number of nearest vectors replied"}
+	QueryVectorArgument       = &commandArgument{label: "query vector", position: 2, valueType: TypeString, variadic: true, description: "the values of the vector the nearest vectors are looked for, as many as the dimension of the index"}
 )
 
 // Command options
 var (
 	FrequentAccessOption = &commandOption{label: "frequent access cache", letter: 'f', name: "frequent-access", valueType: NoType, description: "pass this option for frequently accessed values"}
 	ExpirationOption     = &commandOption{label: "expiration time", letter: 'e', name: "expires-in", valueType: TypeInt, description: "period in seconds until the key value pair are deleted"}
+	ExpirationMsOption   = &commandOption{label: "expiration time in milliseconds", letter: 'm', name: "expires-in-ms", valueType: TypeInt, description: "period in milliseconds until the key value pair are deleted"}
+	IdleTimeoutOption    = &commandOption{label: "idle timeout", letter: 'i', name: "idle-timeout", valueType: TypeInt, description: "period in seconds without reads until the key value pair are deleted"}
+	IfAbsentOption       = &commandOption{label: "only if absent", letter: 'n', name: "nx", valueType: NoType, description: "pass this option to only set keys that do not exist"}
+	IfExistsOption       = &commandOption{label: "only if exists", letter: 'x', name: "xx", valueType: NoType, description: "pass this option to only set keys that already exist"}
+	GetPreviousOption    = &commandOption{label: "get previous value", letter: 'g', name: "get", valueType: NoType, description: "pass this option to reply the value replaced by the command"}
+	MatchOption          = &commandOption{label: "match", letter: 'p', name: "match", valueType: TypeString, description: "a glob-style pattern filtering the keys returned"}
+	WithScoresOption     = &commandOption{label: "with scores", letter: 's', name: "withscores", valueType: NoType, description: "pass this option to reply the score of each member after it"}
+	CountOption          = &commandOption{label: "count", letter: 'c', name: "count", valueType: TypeInt, description: "the number of keys looked at by a call"}
+	EntriesCountOption   = &commandOption{label: "count", letter: 'c', name: "count", valueType: TypeInt, description: "the maximum number of entries replied per stream"}
+	BlockOption          = &commandOption{label: "block", letter: 'b', name: "block", valueType: TypeInt, description: "period in milliseconds to wait for entries when there are none, 0 to wait forever"}
+	MaxLenOption         = &commandOption{label: "max length", letter: 'l', name: "maxlen", valueType: TypeInt, description: "trims the oldest entries of the stream beyond this number"}
+	MaxAgeOption         = &commandOption{label: "max age", letter: 'a', name: "maxage", valueType: TypeInt, description: "trims the entries of the stream added more than this number of seconds ago"}
+	MkStreamOption       = &commandOption{label: "make stream", letter: 's', name: "mkstream", valueType: NoType, description: "pass this option to create the stream when it does not exist"}
+	PrefixOption         = &commandOption{label: "prefix", letter: 'p', name: "prefix", valueType: TypeString, description: "only the vectors whose keys start with this prefix are looked for"}
+	EfSearchOption       = &commandOption{label: "ef", letter: 'r', name: "ef", valueType: TypeInt, description: "the number of candidates looked at by an approximate search, more giving more accurate results"}
+	ExactOption          = &commandOption{label: "exact", letter: 'x', name: "exact", valueType: NoType, description: "pass this option to compare the query to every vector rather than search the HNSW graph"}
 )
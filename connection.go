@@ -2,41 +2,109 @@ package main
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
+	"io"
 	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type Connection interface {
 	Read() (string, Error)
 	Send(output string) Error
 	Close() Error
+	RemoteAddr() net.Addr
+	ID() int64
+	Name() string
+	SetName(string)
+	SetLastCommand(string)
+	SetIdleTimeout(time.Duration) time.Duration
+	WatchClosed() (<-chan struct{}, func())
+	Info() ConnectionInfo
 }
 
+// ConnectionTimeouts holds the deadlines applied to a connection, a zero
+// value disables the matching deadline.
+type ConnectionTimeouts struct {
+	// Read is the time allowed to receive a whole command once it started.
+	Read time.Duration
+	// Write is the time allowed to send a reply.
+	Write time.Duration
+	// Idle is the time allowed between two commands.
+	Idle time.Duration
+}
+
+// ConnectionInfo is a snapshot of the state of a connection.
+type ConnectionInfo struct {
+	ID           int64
+	Addr         string
+	Name         string
+	ConnectedAt  time.Time
+	LastCommand  string
+	LastActivity time.Time
+	BytesIn      int64
+	BytesOut     int64
+}
+
+var nextConnectionID atomic.Int64
+
 type TCPConnection struct {
 	net.Conn
-	logger Logger
+	reader       *bufio.Reader
+	timeouts     ConnectionTimeouts
+	id           int64
+	connectedAt  time.Time
+	mu           sync.Mutex
+	name         string
+	lastCommand  string
+	lastActivity time.Time
+	bytesIn      atomic.Int64
+	bytesOut     atomic.Int64
+	logger       Logger
 }
 
-func NewTCPConnection(conn net.Conn, logger Logger) *TCPConnection {
+func NewTCPConnection(conn net.Conn, timeouts ConnectionTimeouts, logger Logger) *TCPConnection {
 	if conn == nil {
 		panic("connection cannot be nil")
 	}
+	now := time.Now()
 	connection := &TCPConnection{
-		Conn:   conn,
-		logger: logger,
+		Conn:         conn,
+		reader:       bufio.NewReader(conn),
+		timeouts:     timeouts,
+		id:           nextConnectionID.Add(1),
+		connectedAt:  now,
+		lastActivity: now,
+		logger:       logger,
 	}
 	connection.logger.Info(fmt.Sprintf("[CONNECTION_EVENT] New connection from %s", conn.RemoteAddr()))
 	return connection
 }
 
 func (connection *TCPConnection) Read() (string, Error) {
-	reader := bufio.NewReader(connection.Conn)
-	s, err := reader.ReadString('\n')
+	connection.mu.Lock()
+	timeouts := connection.timeouts
+	connection.mu.Unlock()
+
+	// Wait for the next command within the idle timeout, then give the client
+	// the read timeout to send the rest of it
+	connection.setReadDeadline(timeouts.Idle)
+	_, err := connection.reader.Peek(1)
 	if err != nil {
-		err := &UnexpectedError{message: "Error reading command", err: err}
-		connection.logger.Error(fmt.Sprintf("[CONNECTION_EVENT] %s", err.Error()))
-		return "", err
+		return "", connection.readError(err)
+	}
+	connection.setReadDeadline(timeouts.Read)
+	s, err := connection.reader.ReadString('\n')
+	connection.bytesIn.Add(int64(len(s)))
+	if err != nil {
+		return "", connection.readError(err)
 	}
+	connection.mu.Lock()
+	connection.lastActivity = time.Now()
+	connection.mu.Unlock()
 	connection.logger.Info(fmt.Sprintf("[CONNECTION_EVENT] [%s] > %s", connection.RemoteAddr(), s))
 	return s, nil
 }
@@ -47,7 +115,11 @@ func (connection *TCPConnection) Send(output string) Error {
 		connection.logger.Error(fmt.Sprintf("[CONNECTION_EVENT] %s", err.Error()))
 		return err
 	}
-	_, err := (*connection).Write([]byte(output))
+	if connection.timeouts.Write > 0 {
+		connection.SetWriteDeadline(time.Now().Add(connection.timeouts.Write))
+	}
+	n, err := (*connection).Write([]byte(output))
+	connection.bytesOut.Add(int64(n))
 	if err != nil {
 		err := &UnexpectedError{message: "error sending data", err: err}
 		connection.logger.Error(fmt.Sprintf("[CONNECTION_EVENT] %s", err.Error()))
@@ -57,6 +129,51 @@ func (connection *TCPConnection) Send(output string) Error {
 	return nil
 }
 
+// WatchClosed returns a channel closed once the client closes the connection
+// while the server is not reading from it, the input sent meanwhile is left
+// for the next Read. The returned function stops the watch, it must be called
+// before reading again.
+func (connection *TCPConnection) WatchClosed() (<-chan struct{}, func()) {
+	closed := make(chan struct{})
+	done := make(chan struct{})
+	connection.SetReadDeadline(time.Time{})
+	go func() {
+		defer close(done)
+		_, err := connection.reader.Peek(1)
+		if err != nil && !errors.Is(err, os.ErrDeadlineExceeded) {
+			close(closed)
+		}
+	}()
+	stop := func() {
+		// Unblock the peek, Read sets its own deadline afterwards
+		connection.SetReadDeadline(time.Now())
+		<-done
+	}
+	return closed, stop
+}
+
+func (connection *TCPConnection) setReadDeadline(timeout time.Duration) {
+	if timeout > 0 {
+		connection.SetReadDeadline(time.Now().Add(timeout))
+	} else {
+		connection.SetReadDeadline(time.Time{})
+	}
+}
+
+func (connection *TCPConnection) readError(err error) Error {
+	if errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed) {
+		connection.logger.Info(fmt.Sprintf("[CONNECTION_EVENT] [%s] Connection closed", connection.RemoteAddr()))
+		return &UnexpectedError{message: "Connection closed", err: err}
+	}
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		connection.logger.Info(fmt.Sprintf("[CONNECTION_EVENT] [%s] Connection timed out", connection.RemoteAddr()))
+		return &UnexpectedError{message: "Connection timed out", err: err}
+	}
+	readErr := &UnexpectedError{message: "Error reading command", err: err}
+	connection.logger.Error(fmt.Sprintf("[CONNECTION_EVENT] %s", readErr.Error()))
+	return readErr
+}
+
 func (connection *TCPConnection) Close() Error {
 	if connection.Conn == nil {
 		err := &UnexpectedError{message: "tried closing inexistant connection", err: nil}
@@ -64,10 +181,57 @@ func (connection *TCPConnection) Close() Error {
 		return err
 	}
 	err := connection.Conn.Close()
-	if err != nil {
+	if err != nil && !errors.Is(err, net.ErrClosed) {
 		err := &UnexpectedError{message: "error closing connection", err: err}
 		connection.logger.Error(fmt.Sprintf("[CONNECTION_EVENT] %s", err.Error()))
 		return err
 	}
 	return nil
 }
+
+func (connection *TCPConnection) ID() int64 {
+	return connection.id
+}
+
+func (connection *TCPConnection) Name() string {
+	connection.mu.Lock()
+	defer connection.mu.Unlock()
+	return connection.name
+}
+
+func (connection *TCPConnection) SetName(name string) {
+	connection.mu.Lock()
+	defer connection.mu.Unlock()
+	connection.name = name
+}
+
+func (connection *TCPConnection) SetLastCommand(command string) {
+	connection.mu.Lock()
+	defer connection.mu.Unlock()
+	connection.lastCommand = command
+}
+
+// SetIdleTimeout changes the idle timeout of the connection and returns the
+// previous one.
+func (connection *TCPConnection) SetIdleTimeout(timeout time.Duration) time.Duration {
+	connection.mu.Lock()
+	defer connection.mu.Unlock()
+	previous := connection.timeouts.Idle
+	connection.timeouts.Idle = timeout
+	return previous
+}
+
+func (connection *TCPConnection) Info() ConnectionInfo {
+	connection.mu.Lock()
+	defer connection.mu.Unlock()
+	return ConnectionInfo{
+		ID:           connection.id,
+		Addr:         connection.RemoteAddr().String(),
+		Name:         connection.name,
+		ConnectedAt:  connection.connectedAt,
+		LastCommand:  connection.lastCommand,
+		LastActivity: connection.lastActivity,
+		BytesIn:      connection.bytesIn.Load(),
+		BytesOut:     connection.bytesOut.Load(),
+	}
+}
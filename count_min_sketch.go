@@ -0,0 +1,75 @@
+package main
+
+import "math"
+
+// maxCountMinSketchCounters bounds the memory of a sketch to 128MB
+const maxCountMinSketchCounters = 1 << 24
+
+// CountMinSketch estimates how many times items were counted in a fixed
+// amount of memory: each item has a counter in every row, picked by hashing,
+// and its estimate is the lowest of them. Estimates never fall short of the
+// actual counts and may exceed them because of the items sharing counters.
+type CountMinSketch struct {
+	width    int
+	depth    int
+	counters []int64
+}
+
+func NewCountMinSketch(width, depth int) *CountMinSketch {
+	return &CountMinSketch{
+		width:    width,
+		depth:    depth,
+		counters: make([]int64, width*depth),
+	}
+}
+
+// countMinSketchDimensions sizes a sketch whose estimates exceed the actual
+// counts by more than errorRate times the total of the increments with the
+// given probability at most. It returns false when the sketch would be too
+// large.
+func countMinSketchDimensions(errorRate, probability float64) (int, int, bool) {
+	width := math.Ceil(math.E / errorRate)
+	depth := max(math.Ceil(math.Log(1/probability)), 1)
+	if width*depth > maxCountMinSketchCounters {
+		return 0, 0, false
+	}
+	return int(width), int(depth), true
+}
+
+// validCountMinSketchDimensions returns whether a sketch of the dimensions can
+// be created.
+func validCountMinSketchDimensions(width, depth int) bool {
+	return width > 0 && depth > 0 && width <= maxCountMinSketchCounters/depth
+}
+
+// cells returns the index of the counter of the item in each row, derived
+// from its hash by double hashing.
+func (s *CountMinSketch) cells(item string) []int {
+	hash := hashString(item)
+	step := mixHash(hash) | 1
+	cells := make([]int, s.depth)
+	for row := range cells {
+		cells[row] = row*s.width + int((hash+uint64(row)*step)%uint64(s.width))
+	}
+	return cells
+}
+
+// IncrBy adds a positive increment to the count of the item and returns its
+// new estimate. Counters saturate at the largest int64 rather than overflow.
+func (s *CountMinSketch) IncrBy(item string, increment int64) int64 {
+	estimate := int64(math.MaxInt64)
+	for _, cell := range s.cells(item) {
+		s.counters[cell] += min(increment, math.MaxInt64-s.counters[cell])
+		estimate = min(estimate, s.counters[cell])
+	}
+	return estimate
+}
+
+// Count returns the estimated count of the item.
+func (s *CountMinSketch) Count(item string) int64 {
+	estimate := int64(math.MaxInt64)
+	for _, cell := range s.cells(item) {
+		estimate = min(estimate, s.counters[cell])
+	}
+	return estimate
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"math"
+	"strconv"
+	"time"
+)
+
+// CountMinSketches holds the Count-Min Sketches of the cache.
+type CountMinSketches interface {
+	Init(key string, width, depth int, expiresAt time.Time) (bool, Error)
+	IncrBy(key string, items []KeyValue[string, int64]) ([]int64, Error)
+	Query(key string, items []string) ([]int64, Error)
+}
+
+type countMinSketches struct {
+	cache Cache[string, Value]
+}
+
+func NewCountMinSketches(cache Cache[string, Value]) CountMinSketches {
+	return &countMinSketches{cache: cache}
+}
+
+// Init creates a sketch of the dimensions, which expires at expiresAt, and
+// returns whether the key did not exist yet.
+func (c *countMinSketches) Init(key string, width, depth int, expiresAt time.Time) (bool, Error) {
+	created := false
+	_, err := computeAs(c.cache, key, CountMinSketchData, func(current **CountMinSketch) (*CountMinSketch, ComputeAction, Error) {
+		if current != nil {
+			return *current, KeepValue, nil
+		}
+		created = true
+		return NewCountMinSketch(width, depth), StoreValue, nil
+	}, expiresAt)
+	return created, err
+}
+
+// initSketch creates the sketch of the command, which fails when the key
+// already exists.
+func initSketch(commandName string, sketches CountMinSketches, key string, width, depth int, input CommandInput) (Result[Value], Error) {
+	expiresAt, err := expirationFromInput(commandName, input)
+	if err != nil {
+		return nil, err
+	}
+	created, err := sketches.Init(key, width, depth, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return nil, &CommandError{message: "Key already exists in " + commandName}
+	}
+	return &okResult[Value]{}, nil
+}
+
+// IncrBy adds to the counts of the items and returns their new estimates, the
+// sketch must have been created by Init.
+func (c *countMinSketches) IncrBy(key string, items []KeyValue[string, int64]) ([]int64, Error) {
+	estimates := make([]int64, len(items))
+	_, err := computeAs(c.cache, key, CountMinSketchData, func(current **CountMinSketch) (*CountMinSketch, ComputeAction, Error) {
+		if current == nil {
+			return nil, KeepValue, &CommandError{message: "Key does not exist in CMS.INCRBY"}
+		}
+		sketch := *current
+		for i, item := range items {
+			estimates[i] = sketch.IncrBy(item.Key, item.Value)
+		}
+		return sketch, StoreValue, nil
+	}, time.Time{})
+	return estimates, err
+}
+
+// Query returns the estimated counts of the items, 0 when the key does not
+// exist.
+func (c *countMinSketches) Query(key string, items []string) ([]int64, Error) {
+	estimates := make([]int64, len(items))
+	_, err := computeAs(c.cache, key, CountMinSketchData, func(current **CountMinSketch) (*CountMinSketch, ComputeAction, Error) {
+		if current != nil {
+			for i, item := range items {
+				estimates[i] = (*current).Count(item)
+			}
+		}
+		return nil, KeepValue, nil
+	}, time.Time{})
+	return estimates, err
+}
+
+// countsResult replies each count as an integer.
+func countsResult(counts []int64) Result[Value] {
+	items := make([]Result[Value], len(counts))
+	for i, count := range counts {
+		items[i] = &integerResult[Value]{value: count}
+	}
+	return &arrayResult[Value]{items: items}
+}
+
+type cmsInitByDimCommand struct {
+	Command
+	sketches CountMinSketches
+}
+
+func NewCMSInitByDimCommand(sketches CountMinSketches) ExecutableCommand[string, Value] {
+	return &cmsInitByDimCommand{
+		Command: NewCommand("CMS.INITBYDIM").
+			WithArgument(KeyCommandArgument).
+			WithArgument(WidthArgument).
+			WithArgument(DepthArgument).
+			WithOption(ExpirationOption).
+			WithOption(ExpirationMsOption),
+		sketches: sketches,
+	}
+}
+
+func (c *cmsInitByDimCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	width := input.GetArgument(*WidthArgument).(int)
+	depth := input.GetArgument(*DepthArgument).(int)
+	if !validCountMinSketchDimensions(width, depth) {
+		return nil, &CommandError{message: "Invalid dimensions in " + c.GetName()}
+	}
+	return initSketch(c.GetName(), c.sketches, key, width, depth, input)
+}
+
+type cmsInitByProbCommand struct {
+	Command
+	sketches CountMinSketches
+}
+
+func NewCMSInitByProbCommand(sketches CountMinSketches) ExecutableCommand[string, Value] {
+	return &cmsInitByProbCommand{
+		Command: NewCommand("CMS.INITBYPROB").
+			WithArgument(KeyCommandArgument).
+			WithArgument(EstimateErrorArgument).
+			WithArgument(ProbabilityArgument).
+			WithOption(ExpirationOption).
+			WithOption(ExpirationMsOption),
+		sketches: sketches,
+	}
+}
+
+func (c *cmsInitByProbCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	errorRate := input.GetArgument(*EstimateErrorArgument).(float64)
+	probability := input.GetArgument(*ProbabilityArgument).(float64)
+	if math.IsNaN(errorRate) || errorRate <= 0 || errorRate >= 1 || math.IsNaN(probability) || probability <= 0 || probability >= 1 {
+		return nil, &CommandError{message: "Invalid error or probability in " + c.GetName()}
+	}
+	width, depth, ok := countMinSketchDimensions(errorRate, probability)
+	if !ok {
+		return nil, &CommandError{message: "Invalid dimensions in " + c.GetName()}
+	}
+	return initSketch(c.GetName(), c.sketches, key, width, depth, input)
+}
+
+type cmsIncrByCommand struct {
+	Command
+	sketches CountMinSketches
+}
+
+func NewCMSIncrByCommand(sketches CountMinSketches) ExecutableCommand[string, Value] {
+	return &cmsIncrByCommand{
+		Command: NewCommand("CMS.INCRBY").
+			WithArgument(KeyCommandArgument).
+			WithArgument(ItemIncrementsArgument),
+		sketches: sketches,
+	}
+}
+
+// Run replies the estimated count of each item once incremented.
+func (c *cmsIncrByCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	tokens := toStrings(input.GetArgument(*ItemIncrementsArgument).([]any))
+	if len(tokens)%2 != 0 {
+		return nil, &InvalidCommandUsageError{command: c.GetName()}
+	}
+	items := make([]KeyValue[string, int64], 0, len(tokens)/2)
+	for i := 0; i < len(tokens); i += 2 {
+		increment, err := strconv.ParseInt(tokens[i+1], 10, 64)
+		if err != nil || increment <= 0 {
+			return nil, &ValueTypeError{valueType: TypeInt}
+		}
+		items = append(items, KeyValue[string, int64]{Key: tokens[i], Value: increment})
+	}
+	estimates, err := c.sketches.IncrBy(key, items)
+	if err != nil {
+		return nil, err
+	}
+	return countsResult(estimates), nil
+}
+
+type cmsQueryCommand struct {
+	Command
+	sketches CountMinSketches
+}
+
+func NewCMSQueryCommand(sketches CountMinSketches) ExecutableCommand[string, Value] {
+	return &cmsQueryCommand{
+		Command: NewCommand("CMS.QUERY").
+			WithArgument(KeyCommandArgument).
+			WithArgument(ItemsArgument),
+		sketches: sketches,
+	}
+}
+
+// Run replies the estimated count of each item.
+func (c *cmsQueryCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	items := toStrings(input.GetArgument(*ItemsArgument).([]any))
+	estimates, err := c.sketches.Query(key, items)
+	if err != nil {
+		return nil, err
+	}
+	return countsResult(estimates), nil
+}
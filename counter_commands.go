@@ -0,0 +1,159 @@
+package main
+
+import (
+	"math"
+	"strconv"
+)
+
+// incrCommand adds a delta to the integer value of a key, a missing key
+// counts as 0 and is created with the expiration given as option. The delta
+// is either fixed or read from an argument, negated for decrements.
+type incrCommand struct {
+	Command
+	delta    int
+	argument *commandArgument
+	negate   bool
+}
+
+func NewIncrCommand() ExecutableCommand[string, Value] {
+	return &incrCommand{
+		Command: NewCommand("INCR").
+			WithArgument(KeyCommandArgument).
+			WithOption(ExpirationOption).
+			WithOption(ExpirationMsOption).
+			WithOption(FrequentAccessOption),
+		delta: 1,
+	}
+}
+
+func NewDecrCommand() ExecutableCommand[string, Value] {
+	return &incrCommand{
+		Command: NewCommand("DECR").
+			WithArgument(KeyCommandArgument).
+			WithOption(ExpirationOption).
+			WithOption(ExpirationMsOption).
+			WithOption(FrequentAccessOption),
+		delta: -1,
+	}
+}
+
+func NewIncrByCommand() ExecutableCommand[string, Value] {
+	return &incrCommand{
+		Command: NewCommand("INCRBY").
+			WithArgument(KeyCommandArgument).
+			WithArgument(IncrementArgument).
+			WithOption(ExpirationOption).
+			WithOption(ExpirationMsOption).
+			WithOption(FrequentAccessOption),
+		argument: IncrementArgument,
+	}
+}
+
+func NewDecrByCommand() ExecutableCommand[string, Value] {
+	return &incrCommand{
+		Command: NewCommand("DECRBY").
+			WithArgument(KeyCommandArgument).
+			WithArgument(DecrementArgument).
+			WithOption(ExpirationOption).
+			WithOption(ExpirationMsOption).
+			WithOption(FrequentAccessOption),
+		argument: DecrementArgument,
+		negate:   true,
+	}
+}
+
+func (c *incrCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	delta := c.delta
+	if c.argument != nil {
+		delta = input.GetArgument(*c.argument).(int)
+	}
+	if c.negate {
+		if delta == math.MinInt {
+			return nil, &OverflowError{command: c.GetName()}
+		}
+		delta = -delta
+	}
+	expiresAt, err := expirationFromInput(c.GetName(), input)
+	if err != nil {
+		return nil, err
+	}
+
+	var result int
+	_, err = cache.Update(key, func(current *Value) (Value, Error) {
+		value := 0
+		if current != nil {
+			text, err := valueAs[string](*current, StringData)
+			if err != nil {
+				return Value{}, err
+			}
+			parsed, parseErr := ParseValue(TypeInt, text)
+			if parseErr != nil {
+				return Value{}, &ValueTypeError{valueType: TypeInt}
+			}
+			value = parsed.(int)
+		}
+		if (delta > 0 && value > math.MaxInt-delta) || (delta < 0 && value < math.MinInt-delta) {
+			return Value{}, &OverflowError{command: c.GetName()}
+		}
+		result = value + delta
+		return StringValue(strconv.Itoa(result)), nil
+	}, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return &integerResult[Value]{value: int64(result)}, nil
+}
+
+// incrByFloatCommand adds a float increment to the value of a key, a missing
+// key counts as 0 and is created with the expiration given as option.
+type incrByFloatCommand struct {
+	Command
+}
+
+func NewIncrByFloatCommand() ExecutableCommand[string, Value] {
+	return &incrByFloatCommand{
+		Command: NewCommand("INCRBYFLOAT").
+			WithArgument(KeyCommandArgument).
+			WithArgument(FloatIncrementArgument).
+			WithOption(ExpirationOption).
+			WithOption(ExpirationMsOption).
+			WithOption(FrequentAccessOption),
+	}
+}
+
+func (c *incrByFloatCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	increment := input.GetArgument(*FloatIncrementArgument).(float64)
+	if math.IsNaN(increment) || math.IsInf(increment, 0) {
+		return nil, &ValueTypeError{valueType: TypeFloat}
+	}
+	expiresAt, err := expirationFromInput(c.GetName(), input)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := cache.Update(key, func(current *Value) (Value, Error) {
+		value := 0.0
+		if current != nil {
+			text, err := valueAs[string](*current, StringData)
+			if err != nil {
+				return Value{}, err
+			}
+			parsed, parseErr := ParseValue(TypeFloat, text)
+			if parseErr != nil || math.IsNaN(parsed.(float64)) || math.IsInf(parsed.(float64), 0) {
+				return Value{}, &ValueTypeError{valueType: TypeFloat}
+			}
+			value = parsed.(float64)
+		}
+		sum := value + increment
+		if math.IsNaN(sum) || math.IsInf(sum, 0) {
+			return Value{}, &OverflowError{command: c.GetName()}
+		}
+		return StringValue(strconv.FormatFloat(sum, 'f', -1, 64)), nil
+	}, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return &valueResult[Value]{value: result}, nil
+}
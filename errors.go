@@ -57,6 +57,56 @@ func (e *CommandError) Display() string {
 	return e.message
 }
 
+// ValueTypeError is returned when a command expects a value of a type the
+// stored value cannot be parsed as.
+type ValueTypeError struct {
+	valueType ValueType
+}
+
+func (e *ValueTypeError) Error() string {
+	return fmt.Sprintf("Value is not %s", e.valueType.Description())
+}
+
+func (e *ValueTypeError) Display() string {
+	return fmt.Sprintf("Value is not %s", e.valueType.Description())
+}
+
+// WrongTypeError is returned when a command is run on a key holding a value of
+// another data type.
+type WrongTypeError struct{}
+
+func (e *WrongTypeError) Error() string {
+	return "WRONGTYPE Operation against a key holding the wrong kind of value"
+}
+
+func (e *WrongTypeError) Display() string {
+	return "WRONGTYPE Operation against a key holding the wrong kind of value"
+}
+
+type OverflowError struct {
+	command string
+}
+
+func (e *OverflowError) Error() string {
+	return fmt.Sprintf("Increment or decrement would overflow in %s", e.command)
+}
+
+func (e *OverflowError) Display() string {
+	return fmt.Sprintf("Increment or decrement would overflow in %s", e.command)
+}
+
+type ConnectionLimitError struct {
+	message string
+}
+
+func (e *ConnectionLimitError) Error() string {
+	return e.message
+}
+
+func (e *ConnectionLimitError) Display() string {
+	return e.message
+}
+
 type UnexpectedError struct {
 	message string
 	err     error
@@ -2,6 +2,11 @@
 
 package main
 
+import (
+	"sync"
+	"time"
+)
+
 type Result[T any] interface {
 	String() string
 }
@@ -11,24 +16,104 @@ type ExecutableCommand[K comparable, V any] interface {
 	Run(input CommandInput, cache Cache[K, V]) (Result[V], Error)
 }
 
+// ConnectionCommand is implemented by commands acting on the connection they
+// were received from, such as naming or tracking the client.
+type ConnectionCommand[K comparable, V any] interface {
+	ExecutableCommand[K, V]
+	RunOnConnection(connection Connection, input CommandInput, cache Cache[K, V]) (Result[V], Error)
+}
+
+// connectionCommand is embedded by commands which can only be run through
+// RunOnConnection.
+type connectionCommand[K comparable, V any] struct {
+	Command
+}
+
+func (c *connectionCommand[K, V]) Run(input CommandInput, cache Cache[K, V]) (Result[V], Error) {
+	return nil, &CommandNotExecutableError{command: c.GetName()}
+}
+
 type Executor[K comparable, V any] interface {
-	Execute(ExecutableCommand[K, V], CommandInput) (Result[V], Error)
+	Execute(Connection, ExecutableCommand[K, V], CommandInput) (Result[V], Error)
+	ExecuteAll(Connection, []QueuedCommand[K, V], []WatchCheck) ([]Result[V], bool)
+	ExecuteFunc(func())
+	SlowLog() SlowLog
 }
 
 type executor[K comparable, V any] struct {
 	cacheManager CacheManager[K, V]
+	slowLog      SlowLog
+	// exclusive is held for writing by transactions so that no other command
+	// runs in between their commands
+	exclusive sync.RWMutex
 }
 
-func NewExecutor[K comparable, V any](cacheManager CacheManager[K, V]) Executor[K, V] {
+func NewExecutor[K comparable, V any](cacheManager CacheManager[K, V], slowLog SlowLog) Executor[K, V] {
 	return &executor[K, V]{
 		cacheManager: cacheManager,
+		slowLog:      slowLog,
 	}
 }
 
-func (ch *executor[K, V]) Execute(command ExecutableCommand[K, V], input CommandInput) (Result[V], Error) {
+func (ch *executor[K, V]) Execute(connection Connection, command ExecutableCommand[K, V], input CommandInput) (Result[V], Error) {
+	ch.exclusive.RLock()
+	defer ch.exclusive.RUnlock()
+	return ch.execute(connection, command, input)
+}
+
+// ExecuteAll runs the commands of a transaction one after the other without
+// any other command in between. Nothing is run when one of the watch checks
+// fails, which is reported by returning false. The errors of the commands are
+// part of the results.
+func (ch *executor[K, V]) ExecuteAll(connection Connection, commands []QueuedCommand[K, V], watches []WatchCheck) ([]Result[V], bool) {
+	ch.exclusive.Lock()
+	defer ch.exclusive.Unlock()
+	for _, check := range watches {
+		if !check() {
+			return nil, false
+		}
+	}
+	results := make([]Result[V], len(commands))
+	for i, queued := range commands {
+		result, err := ch.execute(connection, queued.Command, queued.Input)
+		if err != nil {
+			result = &textResult[V]{text: err.Display()}
+		}
+		results[i] = result
+	}
+	return results, true
+}
+
+// ExecuteFunc runs fn the way a single command is run, never in the middle of
+// a transaction. It is meant for the work commands keep doing once they
+// returned, such as blocking commands reading the keys they wait for.
+func (ch *executor[K, V]) ExecuteFunc(fn func()) {
+	ch.exclusive.RLock()
+	defer ch.exclusive.RUnlock()
+	fn()
+}
+
+func (ch *executor[K, V]) execute(connection Connection, command ExecutableCommand[K, V], input CommandInput) (Result[V], Error) {
 	// Get the appropriate cache from the CacheManager
 	frequentAccessOption := input.GetOption(*FrequentAccessOption)
 	useSyncCache := frequentAccessOption != nil
 	cache := ch.cacheManager.Get(useSyncCache)
-	return command.Run(input, cache)
+	if cache == nil {
+		return nil, &CommandError{message: "Frequent access cache is not enabled"}
+	}
+
+	startedAt := time.Now()
+	var result Result[V]
+	var err Error
+	if connectionCommand, ok := command.(ConnectionCommand[K, V]); ok {
+		result, err = connectionCommand.RunOnConnection(connection, input, cache)
+	} else {
+		result, err = command.Run(input, cache)
+	}
+	ch.slowLog.Add(connection.RemoteAddr().String(), command.GetName(), input.Args(), startedAt, time.Since(startedAt))
+	return result, err
+}
+
+func (ch *executor[K, V]) SlowLog() SlowLog {
+	return ch.slowLog
 }
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"time"
+)
+
+type expireCommand[V any] struct {
+	Command
+}
+
+func NewExpireCommand[V any]() ExecutableCommand[string, V] {
+	return &expireCommand[V]{
+		Command: NewCommand("EXPIRE").
+			WithArgument(KeyCommandArgument).
+			WithArgument(SecondsArgument).
+			WithOption(FrequentAccessOption),
+	}
+}
+
+func (c *expireCommand[V]) Run(input CommandInput, cache Cache[string, V]) (Result[V], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	seconds := input.GetArgument(*SecondsArgument).(int)
+	expiresAt := time.Now().Add(time.Duration(seconds) * time.Second)
+	return booleanResult[V](cache.Expire(key, expiresAt)), nil
+}
+
+type pexpireCommand[V any] struct {
+	Command
+}
+
+func NewPExpireCommand[V any]() ExecutableCommand[string, V] {
+	return &pexpireCommand[V]{
+		Command: NewCommand("PEXPIRE").
+			WithArgument(KeyCommandArgument).
+			WithArgument(MillisecondsArgument).
+			WithOption(FrequentAccessOption),
+	}
+}
+
+func (c *pexpireCommand[V]) Run(input CommandInput, cache Cache[string, V]) (Result[V], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	milliseconds := input.GetArgument(*MillisecondsArgument).(int)
+	expiresAt := time.Now().Add(time.Duration(milliseconds) * time.Millisecond)
+	return booleanResult[V](cache.Expire(key, expiresAt)), nil
+}
+
+type expireAtCommand[V any] struct {
+	Command
+}
+
+func NewExpireAtCommand[V any]() ExecutableCommand[string, V] {
+	return &expireAtCommand[V]{
+		Command: NewCommand("EXPIREAT").
+			WithArgument(KeyCommandArgument).
+			WithArgument(TimestampArgument).
+			WithOption(FrequentAccessOption),
+	}
+}
+
+func (c *expireAtCommand[V]) Run(input CommandInput, cache Cache[string, V]) (Result[V], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	timestamp := input.GetArgument(*TimestampArgument).(int)
+	return booleanResult[V](cache.Expire(key, time.Unix(int64(timestamp), 0))), nil
+}
+
+type persistCommand[V any] struct {
+	Command
+}
+
+func NewPersistCommand[V any]() ExecutableCommand[string, V] {
+	return &persistCommand[V]{
+		Command: NewCommand("PERSIST").
+			WithArgument(KeyCommandArgument).
+			WithOption(FrequentAccessOption),
+	}
+}
+
+func (c *persistCommand[V]) Run(input CommandInput, cache Cache[string, V]) (Result[V], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	return booleanResult[V](cache.Persist(key)), nil
+}
+
+// ttlCommand reports the remaining time to live of a key in the given unit:
+// -2 when the key does not exist and -1 when it never expires.
+type ttlCommand[V any] struct {
+	Command
+	unit time.Duration
+}
+
+func NewTTLCommand[V any]() ExecutableCommand[string, V] {
+	return &ttlCommand[V]{
+		Command: NewCommand("TTL").
+			WithArgument(KeyCommandArgument).
+			WithOption(FrequentAccessOption),
+		unit: time.Second,
+	}
+}
+
+func NewPTTLCommand[V any]() ExecutableCommand[string, V] {
+	return &ttlCommand[V]{
+		Command: NewCommand("PTTL").
+			WithArgument(KeyCommandArgument).
+			WithOption(FrequentAccessOption),
+		unit: time.Millisecond,
+	}
+}
+
+func (c *ttlCommand[V]) Run(input CommandInput, cache Cache[string, V]) (Result[V], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	cacheValue, ok := cache.get(key)
+	if !ok {
+		return &integerResult[V]{value: -2}, nil
+	}
+	expiresAt := cacheValue.ExpiresAt()
+	if expiresAt.IsZero() {
+		return &integerResult[V]{value: -1}, nil
+	}
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return &integerResult[V]{value: -2}, nil
+	}
+	return &integerResult[V]{value: int64((ttl + c.unit/2) / c.unit)}, nil
+}
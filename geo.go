@@ -0,0 +1,214 @@
+package main
+
+import (
+	"math"
+	"slices"
+	"strings"
+)
+
+// Positions are encoded as geohashes of geoHashStep bits per coordinate, the
+// 52 bits of which fit exactly in the score of a sorted set member. Latitudes
+// are limited to the ones of the Web Mercator projection.
+const (
+	geoHashStep  = 26
+	minLongitude = -180.0
+	maxLongitude = 180.0
+	minLatitude  = -85.05112878
+	maxLatitude  = 85.05112878
+	// earthRadius is the radius in meters used to compute distances
+	earthRadius = 6372797.560856
+)
+
+// geoUnits are the distance units, in meters.
+var geoUnits = map[string]float64{
+	"m":  1,
+	"km": 1000,
+	"mi": 1609.34,
+	"ft": 0.3048,
+}
+
+// parseGeoUnit reads a distance unit and returns its length in meters.
+func parseGeoUnit(token string) (float64, bool) {
+	meters, ok := geoUnits[strings.ToLower(token)]
+	return meters, ok
+}
+
+// GeoPosition is a position on Earth in degrees.
+type GeoPosition struct {
+	Longitude float64
+	Latitude  float64
+}
+
+// Valid returns whether the position can be encoded.
+func (p GeoPosition) Valid() bool {
+	return p.Longitude >= minLongitude && p.Longitude <= maxLongitude &&
+		p.Latitude >= minLatitude && p.Latitude <= maxLatitude
+}
+
+// Distance returns the great-circle distance in meters between the positions,
+// with the haversine formula.
+func (p GeoPosition) Distance(other GeoPosition) float64 {
+	lat1, lat2 := degreesToRadians(p.Latitude), degreesToRadians(other.Latitude)
+	u := math.Sin((lat2 - lat1) / 2)
+	v := math.Sin(degreesToRadians(other.Longitude-p.Longitude) / 2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(u*u+math.Cos(lat1)*math.Cos(lat2)*v*v))
+}
+
+func degreesToRadians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
+
+func radiansToDegrees(radians float64) float64 {
+	return radians * 180 / math.Pi
+}
+
+// geoCell is a cell of the geohash grid cut in 2^step parts along each
+// coordinate.
+type geoCell struct {
+	longitude uint32
+	latitude  uint32
+	step      uint
+}
+
+func geoCellOf(position GeoPosition, step uint) geoCell {
+	cells := float64(uint64(1) << step)
+	longitude := (position.Longitude - minLongitude) / (maxLongitude - minLongitude) * cells
+	latitude := (position.Latitude - minLatitude) / (maxLatitude - minLatitude) * cells
+	return geoCell{
+		longitude: uint32(min(longitude, cells-1)),
+		latitude:  uint32(min(latitude, cells-1)),
+		step:      step,
+	}
+}
+
+// hash interleaves the bits of the coordinates, those of the longitude coming
+// first, so that close positions mostly share the same prefix.
+func (c geoCell) hash() uint64 {
+	return spreadBits(c.latitude) | spreadBits(c.longitude)<<1
+}
+
+// scores returns the range of the scores of the positions in the cell, the
+// upper bound being excluded.
+func (c geoCell) scores() (uint64, uint64) {
+	shift := 2 * (geoHashStep - c.step)
+	return c.hash() << shift, (c.hash() + 1) << shift
+}
+
+// neighbours returns the cell and the ones around it, longitudes wrapping
+// around the antimeridian. Cells appear once even when the grid is too small
+// for them to be distinct.
+func (c geoCell) neighbours() []geoCell {
+	cells := int64(1) << c.step
+	var neighbours []geoCell
+	for dLatitude := int64(-1); dLatitude <= 1; dLatitude++ {
+		latitude := int64(c.latitude) + dLatitude
+		if latitude < 0 || latitude >= cells {
+			continue
+		}
+		for dLongitude := int64(-1); dLongitude <= 1; dLongitude++ {
+			longitude := (int64(c.longitude) + dLongitude + cells) % cells
+			cell := geoCell{longitude: uint32(longitude), latitude: uint32(latitude), step: c.step}
+			if !slices.Contains(neighbours, cell) {
+				neighbours = append(neighbours, cell)
+			}
+		}
+	}
+	return neighbours
+}
+
+// EncodeGeoHash returns the score of a valid position.
+func EncodeGeoHash(position GeoPosition) float64 {
+	return float64(geoCellOf(position, geoHashStep).hash())
+}
+
+// DecodeGeoHash returns the position of a score, the center of its cell.
+func DecodeGeoHash(score float64) GeoPosition {
+	hash := uint64(score)
+	cells := float64(uint64(1) << geoHashStep)
+	longitude := float64(squashBits(hash>>1)) + 0.5
+	latitude := float64(squashBits(hash)) + 0.5
+	return GeoPosition{
+		Longitude: minLongitude + longitude/cells*(maxLongitude-minLongitude),
+		Latitude:  minLatitude + latitude/cells*(maxLatitude-minLatitude),
+	}
+}
+
+// spreadBits moves the bits of x to the even positions.
+func spreadBits(x uint32) uint64 {
+	v := uint64(x)
+	v = (v | v<<16) & 0x0000FFFF0000FFFF
+	v = (v | v<<8) & 0x00FF00FF00FF00FF
+	v = (v | v<<4) & 0x0F0F0F0F0F0F0F0F
+	v = (v | v<<2) & 0x3333333333333333
+	v = (v | v<<1) & 0x5555555555555555
+	return v
+}
+
+// squashBits gathers the bits of x at the even positions, undoing spreadBits.
+func squashBits(x uint64) uint32 {
+	v := x & 0x5555555555555555
+	v = (v | v>>1) & 0x3333333333333333
+	v = (v | v>>2) & 0x0F0F0F0F0F0F0F0F
+	v = (v | v>>4) & 0x00FF00FF00FF00FF
+	v = (v | v>>8) & 0x0000FFFF0000FFFF
+	v = (v | v>>16) & 0x00000000FFFFFFFF
+	return uint32(v)
+}
+
+// GeoShape is the area of a search around a center: a circle of Radius
+// meters, or a box of Width by Height meters when IsBox is set.
+type GeoShape struct {
+	IsBox  bool
+	Radius float64
+	Width  float64
+	Height float64
+}
+
+// halfExtents returns the distances in meters from the center to the sides of
+// the box bounding the shape.
+func (s GeoShape) halfExtents() (float64, float64) {
+	if !s.IsBox {
+		return s.Radius, s.Radius
+	}
+	return s.Width / 2, s.Height / 2
+}
+
+// Distance returns the distance between the center and the position, and
+// whether the position is inside the shape. Boxes are checked along the
+// meridian and the parallel of the position.
+func (s GeoShape) Distance(center, position GeoPosition) (float64, bool) {
+	distance := center.Distance(position)
+	if !s.IsBox {
+		return distance, distance <= s.Radius
+	}
+	halfWidth, halfHeight := s.halfExtents()
+	if center.Distance(GeoPosition{Longitude: center.Longitude, Latitude: position.Latitude}) > halfHeight {
+		return distance, false
+	}
+	if position.Distance(GeoPosition{Longitude: center.Longitude, Latitude: position.Latitude}) > halfWidth {
+		return distance, false
+	}
+	return distance, true
+}
+
+// searchCells returns cells covering the shape around the center: the cell
+// of the center and its neighbours, at the finest step where cells are at
+// least as large as the bounding box of the shape in degrees.
+func (s GeoShape) searchCells(center GeoPosition) []geoCell {
+	halfWidth, halfHeight := s.halfExtents()
+	latitudeDelta := radiansToDegrees(halfHeight / earthRadius)
+	longitudeDelta := 2 * (maxLongitude - minLongitude)
+	// the bounding box is widest at its latitude closest to a pole
+	if farthestLatitude := math.Abs(center.Latitude) + latitudeDelta; farthestLatitude < 90 {
+		longitudeDelta = radiansToDegrees(halfWidth / earthRadius / math.Cos(degreesToRadians(farthestLatitude)))
+	}
+	step := uint(geoHashStep)
+	for step > 0 {
+		cells := float64(uint64(1) << step)
+		if (maxLongitude-minLongitude)/cells >= longitudeDelta && (maxLatitude-minLatitude)/cells >= latitudeDelta {
+			break
+		}
+		step--
+	}
+	return geoCellOf(center, step).neighbours()
+}
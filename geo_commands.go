@@ -0,0 +1,413 @@
+package main
+
+import (
+	"cmp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// geoSearch is a search of GEOSEARCH: the members of a sorted set inside the
+// shape around the position of a member or around a given position.
+type geoSearch struct {
+	member     string
+	fromMember bool
+	center     GeoPosition
+	shape      GeoShape
+}
+
+// geoMatch is a member found by a search along with its position.
+type geoMatch struct {
+	member   string
+	hash     float64
+	position GeoPosition
+	distance float64
+}
+
+// GeoSets holds the positions stored as geohash scores in the sorted sets of
+// the cache.
+type GeoSets interface {
+	Add(key string, members []ScoredMember[string]) (int, Error)
+	Positions(key string, members []string) ([]*GeoPosition, Error)
+	Distance(key string, member, other string) (float64, bool, Error)
+	Search(key string, search geoSearch) ([]geoMatch, Error)
+}
+
+type geoSets struct {
+	sortedSets SortedSets
+	cache      Cache[string, Value]
+}
+
+func NewGeoSets(cache Cache[string, Value]) GeoSets {
+	return &geoSets{sortedSets: NewSortedSets(cache), cache: cache}
+}
+
+// read runs read on the sorted set of the key when it exists.
+func (g *geoSets) read(key string, read func(set *SortedSet[string]) Error) Error {
+	_, err := computeAs(g.cache, key, SortedSetData, func(current **SortedSet[string]) (*SortedSet[string], ComputeAction, Error) {
+		var set *SortedSet[string]
+		if current != nil {
+			set = *current
+		}
+		return nil, KeepValue, read(set)
+	}, time.Time{})
+	return err
+}
+
+// Add sets the positions of the members, encoded as scores, and returns the
+// number of members added.
+func (g *geoSets) Add(key string, members []ScoredMember[string]) (int, Error) {
+	return g.sortedSets.Add(key, members)
+}
+
+// Positions returns the position of each member, nil for the missing ones.
+func (g *geoSets) Positions(key string, members []string) ([]*GeoPosition, Error) {
+	positions := make([]*GeoPosition, len(members))
+	err := g.read(key, func(set *SortedSet[string]) Error {
+		if set == nil {
+			return nil
+		}
+		for i, member := range members {
+			if score, ok := set.Score(member); ok {
+				position := DecodeGeoHash(score)
+				positions[i] = &position
+			}
+		}
+		return nil
+	})
+	return positions, err
+}
+
+// Distance returns the distance in meters between the members, and false when
+// one of them is missing.
+func (g *geoSets) Distance(key string, member, other string) (float64, bool, Error) {
+	positions, err := g.Positions(key, []string{member, other})
+	if err != nil || positions[0] == nil || positions[1] == nil {
+		return 0, false, err
+	}
+	return positions[0].Distance(*positions[1]), true, nil
+}
+
+// Search returns the members inside the shape, ordered by distance. Only the
+// members in the cells covering the shape are looked at.
+func (g *geoSets) Search(key string, search geoSearch) ([]geoMatch, Error) {
+	var matches []geoMatch
+	err := g.read(key, func(set *SortedSet[string]) Error {
+		center := search.center
+		if search.fromMember {
+			score, ok := 0.0, false
+			if set != nil {
+				score, ok = set.Score(search.member)
+			}
+			if !ok {
+				return &CommandError{message: "Could not find the member in GEOSEARCH"}
+			}
+			center = DecodeGeoHash(score)
+		}
+		if set == nil {
+			return nil
+		}
+		for _, cell := range search.shape.searchCells(center) {
+			min, max := cell.scores()
+			members := set.RangeByScore(ScoreBound{Score: float64(min)}, ScoreBound{Score: float64(max), Exclusive: true})
+			for _, member := range members {
+				position := DecodeGeoHash(member.Score)
+				if distance, inside := search.shape.Distance(center, position); inside {
+					matches = append(matches, geoMatch{member: member.Member, hash: member.Score, position: position, distance: distance})
+				}
+			}
+		}
+		return nil
+	})
+	slices.SortFunc(matches, func(a, b geoMatch) int {
+		if c := cmp.Compare(a.distance, b.distance); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.member, b.member)
+	})
+	return matches, err
+}
+
+// formatDistance formats a distance in meters in the unit.
+func formatDistance(distance, unit float64) string {
+	return strconv.FormatFloat(distance/unit, 'f', 4, 64)
+}
+
+func positionResult(position GeoPosition) Result[Value] {
+	return &arrayResult[Value]{items: []Result[Value]{
+		&valueResult[Value]{value: StringValue(formatScore(position.Longitude))},
+		&valueResult[Value]{value: StringValue(formatScore(position.Latitude))},
+	}}
+}
+
+// parsePosition reads a longitude followed by a latitude.
+func parsePosition(longitudeToken, latitudeToken string) (GeoPosition, bool) {
+	longitude, longitudeOk := parseScore(longitudeToken)
+	latitude, latitudeOk := parseScore(latitudeToken)
+	position := GeoPosition{Longitude: longitude, Latitude: latitude}
+	return position, longitudeOk && latitudeOk && position.Valid()
+}
+
+// parseDistance reads a distance that is not negative in the unit and returns
+// it in meters.
+func parseDistance(distanceToken, unitToken string) (float64, bool) {
+	distance, distanceOk := parseScore(distanceToken)
+	unit, unitOk := parseGeoUnit(unitToken)
+	return distance * unit, distanceOk && unitOk && distance >= 0
+}
+
+type geoaddCommand struct {
+	Command
+	geoSets GeoSets
+}
+
+func NewGeoAddCommand(geoSets GeoSets) ExecutableCommand[string, Value] {
+	return &geoaddCommand{
+		Command: NewCommand("GEOADD").
+			WithArgument(KeyCommandArgument).
+			WithArgument(LonLatMembersArgument),
+		geoSets: geoSets,
+	}
+}
+
+// Run replies the number of members added, the positions of the existing ones
+// being updated.
+func (c *geoaddCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	tokens := toStrings(input.GetArgument(*LonLatMembersArgument).([]any))
+	if len(tokens)%3 != 0 {
+		return nil, &InvalidCommandUsageError{command: c.GetName()}
+	}
+	members := make([]ScoredMember[string], 0, len(tokens)/3)
+	for i := 0; i < len(tokens); i += 3 {
+		position, ok := parsePosition(tokens[i], tokens[i+1])
+		if !ok {
+			return nil, &CommandError{message: "Invalid longitude or latitude in " + c.GetName()}
+		}
+		members = append(members, ScoredMember[string]{Member: tokens[i+2], Score: EncodeGeoHash(position)})
+	}
+	added, err := c.geoSets.Add(key, members)
+	if err != nil {
+		return nil, err
+	}
+	return &integerResult[Value]{value: int64(added)}, nil
+}
+
+type geoposCommand struct {
+	Command
+	geoSets GeoSets
+}
+
+func NewGeoPosCommand(geoSets GeoSets) ExecutableCommand[string, Value] {
+	return &geoposCommand{
+		Command: NewCommand("GEOPOS").
+			WithArgument(KeyCommandArgument).
+			WithArgument(MembersArgument),
+		geoSets: geoSets,
+	}
+}
+
+// Run replies the longitude and latitude of each member, nil for the missing
+// ones. Positions are precise to less than a meter.
+func (c *geoposCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	members := toStrings(input.GetArgument(*MembersArgument).([]any))
+	positions, err := c.geoSets.Positions(key, members)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]Result[Value], len(positions))
+	for i, position := range positions {
+		if position == nil {
+			items[i] = &nilResult[Value]{}
+			continue
+		}
+		items[i] = positionResult(*position)
+	}
+	return &arrayResult[Value]{items: items}, nil
+}
+
+type geodistCommand struct {
+	Command
+	geoSets GeoSets
+}
+
+func NewGeoDistCommand(geoSets GeoSets) ExecutableCommand[string, Value] {
+	return &geodistCommand{
+		Command: NewCommand("GEODIST").
+			WithArgument(KeyCommandArgument).
+			WithArgument(FirstMemberArgument).
+			WithArgument(SecondMemberArgument).
+			WithArgument(DistanceUnitArgument),
+		geoSets: geoSets,
+	}
+}
+
+// Run replies the distance between the members in the unit (default: m), nil
+// when one of them is missing.
+func (c *geodistCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	member := input.GetArgument(*FirstMemberArgument).(string)
+	other := input.GetArgument(*SecondMemberArgument).(string)
+	unit := 1.0
+	if unitToken, ok := input.GetArgument(*DistanceUnitArgument).(string); ok {
+		if unit, ok = parseGeoUnit(unitToken); !ok {
+			return nil, &CommandError{message: "Unsupported unit in " + c.GetName()}
+		}
+	}
+	distance, ok, err := c.geoSets.Distance(key, member, other)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return &nilResult[Value]{}, nil
+	}
+	return &valueResult[Value]{value: StringValue(formatDistance(distance, unit))}, nil
+}
+
+// geoSearchReply tells how GEOSEARCH replies the members found.
+type geoSearchReply struct {
+	unit      float64
+	desc      bool
+	count     int
+	withCoord bool
+	withDist  bool
+	withHash  bool
+}
+
+type geosearchCommand struct {
+	Command
+	geoSets GeoSets
+}
+
+func NewGeoSearchCommand(geoSets GeoSets) ExecutableCommand[string, Value] {
+	return &geosearchCommand{
+		Command: NewCommand("GEOSEARCH").
+			WithArgument(KeyCommandArgument).
+			WithArgument(GeoSearchArgument),
+		geoSets: geoSets,
+	}
+}
+
+// parse reads the center, the shape and the reply of the search, which take
+// several tokens each and are therefore read by the command rather than as
+// options.
+func (c *geosearchCommand) parse(tokens []string) (geoSearch, geoSearchReply, Error) {
+	var search geoSearch
+	var reply geoSearchReply
+	hasCenter, hasShape := false, false
+	usageError := &InvalidCommandUsageError{command: c.GetName()}
+	for i := 0; i < len(tokens); i++ {
+		remaining := len(tokens) - i - 1
+		switch strings.ToUpper(tokens[i]) {
+		case "FROMMEMBER":
+			if hasCenter || remaining < 1 {
+				return search, reply, usageError
+			}
+			search.member, search.fromMember, hasCenter = tokens[i+1], true, true
+			i++
+		case "FROMLONLAT":
+			if hasCenter || remaining < 2 {
+				return search, reply, usageError
+			}
+			position, ok := parsePosition(tokens[i+1], tokens[i+2])
+			if !ok {
+				return search, reply, &CommandError{message: "Invalid longitude or latitude in " + c.GetName()}
+			}
+			search.center, hasCenter = position, true
+			i += 2
+		case "BYRADIUS":
+			if hasShape || remaining < 2 {
+				return search, reply, usageError
+			}
+			radius, ok := parseDistance(tokens[i+1], tokens[i+2])
+			if !ok {
+				return search, reply, &CommandError{message: "Invalid radius or unit in " + c.GetName()}
+			}
+			search.shape, hasShape = GeoShape{Radius: radius}, true
+			reply.unit, _ = parseGeoUnit(tokens[i+2])
+			i += 2
+		case "BYBOX":
+			if hasShape || remaining < 3 {
+				return search, reply, usageError
+			}
+			width, widthOk := parseDistance(tokens[i+1], tokens[i+3])
+			height, heightOk := parseDistance(tokens[i+2], tokens[i+3])
+			if !widthOk || !heightOk {
+				return search, reply, &CommandError{message: "Invalid width, height or unit in " + c.GetName()}
+			}
+			search.shape, hasShape = GeoShape{IsBox: true, Width: width, Height: height}, true
+			reply.unit, _ = parseGeoUnit(tokens[i+3])
+			i += 3
+		case "ASC":
+			reply.desc = false
+		case "DESC":
+			reply.desc = true
+		case "COUNT":
+			if remaining < 1 {
+				return search, reply, usageError
+			}
+			count, err := strconv.Atoi(tokens[i+1])
+			if err != nil || count <= 0 {
+				return search, reply, usageError
+			}
+			reply.count = count
+			i++
+		case "WITHCOORD":
+			reply.withCoord = true
+		case "WITHDIST":
+			reply.withDist = true
+		case "WITHHASH":
+			reply.withHash = true
+		default:
+			return search, reply, usageError
+		}
+	}
+	if !hasCenter || !hasShape {
+		return search, reply, usageError
+	}
+	return search, reply, nil
+}
+
+// Run replies the members inside the shape from the nearest to the farthest,
+// or the other way around with DESC, up to COUNT of them. Each member
+// is followed, when asked for, by its distance in the unit of the shape, its
+// geohash score and its position.
+func (c *geosearchCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	search, reply, err := c.parse(toStrings(input.GetArgument(*GeoSearchArgument).([]any)))
+	if err != nil {
+		return nil, err
+	}
+	matches, err := c.geoSets.Search(key, search)
+	if err != nil {
+		return nil, err
+	}
+	if reply.desc {
+		slices.Reverse(matches)
+	}
+	if reply.count > 0 && len(matches) > reply.count {
+		matches = matches[:reply.count]
+	}
+	items := make([]Result[Value], len(matches))
+	for i, match := range matches {
+		member := &valueResult[Value]{value: StringValue(match.member)}
+		if !reply.withCoord && !reply.withDist && !reply.withHash {
+			items[i] = member
+			continue
+		}
+		fields := []Result[Value]{member}
+		if reply.withDist {
+			fields = append(fields, &valueResult[Value]{value: StringValue(formatDistance(match.distance, reply.unit))})
+		}
+		if reply.withHash {
+			fields = append(fields, &integerResult[Value]{value: int64(match.hash)})
+		}
+		if reply.withCoord {
+			fields = append(fields, positionResult(match.position))
+		}
+		items[i] = &arrayResult[Value]{items: fields}
+	}
+	return &arrayResult[Value]{items: items}, nil
+}
@@ -0,0 +1,88 @@
+package main
+
+// globMatch reports whether the whole text matches the pattern, where `*`
+// matches any sequence of characters, `?` any single character, `[abc]` one of
+// the characters listed, `[a-z]` one in the range and `[^abc]` one not listed.
+// A backslash matches the character following it literally. Unlike
+// path.Match, `*` also matches slashes since keys are not paths.
+func globMatch(pattern, text string) bool {
+	p, t := []rune(pattern), []rune(text)
+	// Position to go back to when a mismatch happens after a star
+	starP, starT := -1, 0
+	i, j := 0, 0
+	for j < len(t) {
+		if i < len(p) {
+			switch p[i] {
+			case '*':
+				starP, starT = i, j
+				i++
+				continue
+			case '?':
+				i++
+				j++
+				continue
+			case '[':
+				if end, ok := matchClass(p, i, t[j]); ok {
+					i = end
+					j++
+					continue
+				}
+			case '\\':
+				if i+1 < len(p) && p[i+1] == t[j] {
+					i += 2
+					j++
+					continue
+				}
+			default:
+				if p[i] == t[j] {
+					i++
+					j++
+					continue
+				}
+			}
+		}
+		if starP < 0 {
+			return false
+		}
+		// Let the last star match one more character
+		starT++
+		i, j = starP+1, starT
+	}
+	for i < len(p) && p[i] == '*' {
+		i++
+	}
+	return i == len(p)
+}
+
+// matchClass matches the character against the class starting at p[start],
+// it returns the position after the class and whether the character is in it.
+// An unterminated class is matched as a literal '['.
+func matchClass(p []rune, start int, c rune) (int, bool) {
+	i := start + 1
+	negate := false
+	if i < len(p) && p[i] == '^' {
+		negate = true
+		i++
+	}
+	matched := false
+	for first := true; i < len(p) && (first || p[i] != ']'); first = false {
+		low := p[i]
+		if low == '\\' && i+1 < len(p) {
+			i++
+			low = p[i]
+		}
+		high := low
+		if i+2 < len(p) && p[i+1] == '-' && p[i+2] != ']' {
+			high = p[i+2]
+			i += 2
+		}
+		if low <= c && c <= high {
+			matched = true
+		}
+		i++
+	}
+	if i >= len(p) {
+		return start + 1, c == '['
+	}
+	return i + 1, matched != negate
+}
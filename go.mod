@@ -2,10 +2,13 @@ module github.com/yassirdeveloper/cacher
 
 go 1.23.5
 
+require (
+	github.com/buger/jsonparser v1.1.1
+	github.com/wk8/go-ordered-map/v2 v2.1.8
+)
+
 require (
 	github.com/bahlo/generic-list-go v0.2.0 // indirect
-	github.com/buger/jsonparser v1.1.1 // indirect
 	github.com/mailru/easyjson v0.9.0 // indirect
-	github.com/wk8/go-ordered-map/v2 v2.1.8 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
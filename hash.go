@@ -0,0 +1,122 @@
+package main
+
+import (
+	"iter"
+	"slices"
+	"time"
+)
+
+type hashField[V any] struct {
+	value V
+	// expiresAt is zero for the fields that do not expire
+	expiresAt time.Time
+}
+
+// Hash maps fields to values, each field may expire on its own. Expired fields
+// are only deleted by RemoveExpired.
+type Hash[V any] struct {
+	fields map[string]hashField[V]
+	// expiring is the number of fields having an expiration time, so that
+	// hashes without any are not scanned by RemoveExpired
+	expiring int
+}
+
+func NewHash[V any]() *Hash[V] {
+	return &Hash[V]{fields: make(map[string]hashField[V])}
+}
+
+func (h *Hash[V]) Len() int {
+	return len(h.fields)
+}
+
+func (h *Hash[V]) Get(field string) (V, bool) {
+	hashField, ok := h.fields[field]
+	return hashField.value, ok
+}
+
+// Set stores the value of the field, removing its expiration, and returns
+// whether the field is new.
+func (h *Hash[V]) Set(field string, value V) bool {
+	current, exists := h.fields[field]
+	if exists && !current.expiresAt.IsZero() {
+		h.expiring--
+	}
+	h.fields[field] = hashField[V]{value: value}
+	return !exists
+}
+
+// Update replaces the value of the field while keeping its expiration, it
+// returns whether the field is new.
+func (h *Hash[V]) Update(field string, value V) bool {
+	current, exists := h.fields[field]
+	h.fields[field] = hashField[V]{value: value, expiresAt: current.expiresAt}
+	return !exists
+}
+
+func (h *Hash[V]) Delete(field string) bool {
+	current, exists := h.fields[field]
+	if !exists {
+		return false
+	}
+	if !current.expiresAt.IsZero() {
+		h.expiring--
+	}
+	delete(h.fields, field)
+	return true
+}
+
+// Expire sets the expiration time of an existing field, a zero expiresAt
+// makes it persistent.
+func (h *Hash[V]) Expire(field string, expiresAt time.Time) bool {
+	current, exists := h.fields[field]
+	if !exists {
+		return false
+	}
+	if !current.expiresAt.IsZero() {
+		h.expiring--
+	}
+	if !expiresAt.IsZero() {
+		h.expiring++
+	}
+	current.expiresAt = expiresAt
+	h.fields[field] = current
+	return true
+}
+
+// ExpiresAt returns the expiration time of the field, zero when it has none.
+func (h *Hash[V]) ExpiresAt(field string) (time.Time, bool) {
+	current, exists := h.fields[field]
+	return current.expiresAt, exists
+}
+
+// RemoveExpired deletes the fields expired at now and returns their number.
+func (h *Hash[V]) RemoveExpired(now time.Time) int {
+	if h.expiring == 0 {
+		return 0
+	}
+	removed := 0
+	for field, current := range h.fields {
+		if !current.expiresAt.IsZero() && !current.expiresAt.After(now) {
+			delete(h.fields, field)
+			h.expiring--
+			removed++
+		}
+	}
+	return removed
+}
+
+// All iterates over the fields and their values sorted by field.
+func (h *Hash[V]) All() iter.Seq2[string, V] {
+	return func(yield func(string, V) bool) {
+		fields := make([]string, 0, len(h.fields))
+		for field := range h.fields {
+			fields = append(fields, field)
+		}
+		slices.Sort(fields)
+		for _, field := range fields {
+			if !yield(field, h.fields[field].value) {
+				return
+			}
+		}
+	}
+}
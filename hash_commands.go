@@ -0,0 +1,470 @@
+package main
+
+import (
+	"math"
+	"strconv"
+	"time"
+)
+
+// Hashes holds the hashes of the cache, the expiration of their keys is
+// recorded and cleared like the one of any value while their fields expire on
+// access.
+type Hashes interface {
+	Set(key string, fields []KeyValue[string, string], expiresAt time.Time) (int, Error)
+	Get(key string, fields []string) ([]*string, Error)
+	Delete(key string, fields []string) (int, Error)
+	All(key string) ([]KeyValue[string, string], Error)
+	IncrBy(key string, field string, delta int, expiresAt time.Time) (int, Error)
+	Len(key string) (int, Error)
+	Expire(key string, fields []string, expiresAt time.Time) ([]bool, Error)
+	ExpiresAt(key string, field string) (time.Time, bool, Error)
+}
+
+type hashes struct {
+	cache Cache[string, Value]
+}
+
+func NewHashes(cache Cache[string, Value]) Hashes {
+	return &hashes{cache: cache}
+}
+
+// update runs change on the hash of the key once its expired fields are
+// removed. A missing hash is created when create is true and change is not
+// called otherwise. change reports whether it modified the hash, which is
+// deleted once it has no field left.
+func (h *hashes) update(key string, create bool, expiresAt time.Time, change func(hash *Hash[string]) (bool, Error)) Error {
+	_, err := computeAs(h.cache, key, HashData, func(current **Hash[string]) (*Hash[string], ComputeAction, Error) {
+		var hash *Hash[string]
+		switch {
+		case current != nil:
+			hash = *current
+			hash.RemoveExpired(time.Now())
+		case create:
+			hash = NewHash[string]()
+		default:
+			return nil, KeepValue, nil
+		}
+		changed, err := change(hash)
+		if err != nil {
+			return nil, KeepValue, err
+		}
+		if hash.Len() == 0 {
+			return hash, DeleteValue, nil
+		}
+		if !changed {
+			return hash, KeepValue, nil
+		}
+		return hash, StoreValue, nil
+	}, expiresAt)
+	return err
+}
+
+// Set stores the values of the fields, the hash expires at expiresAt when it
+// is created. It returns the number of fields added.
+func (h *hashes) Set(key string, fields []KeyValue[string, string], expiresAt time.Time) (int, Error) {
+	added := 0
+	err := h.update(key, true, expiresAt, func(hash *Hash[string]) (bool, Error) {
+		for _, field := range fields {
+			if hash.Set(field.Key, field.Value) {
+				added++
+			}
+		}
+		return true, nil
+	})
+	return added, err
+}
+
+func (h *hashes) Get(key string, fields []string) ([]*string, Error) {
+	values := make([]*string, len(fields))
+	err := h.update(key, false, time.Time{}, func(hash *Hash[string]) (bool, Error) {
+		for i, field := range fields {
+			if value, ok := hash.Get(field); ok {
+				values[i] = &value
+			}
+		}
+		return false, nil
+	})
+	return values, err
+}
+
+func (h *hashes) Delete(key string, fields []string) (int, Error) {
+	deleted := 0
+	err := h.update(key, false, time.Time{}, func(hash *Hash[string]) (bool, Error) {
+		for _, field := range fields {
+			if hash.Delete(field) {
+				deleted++
+			}
+		}
+		return deleted > 0, nil
+	})
+	return deleted, err
+}
+
+func (h *hashes) All(key string) ([]KeyValue[string, string], Error) {
+	var fields []KeyValue[string, string]
+	err := h.update(key, false, time.Time{}, func(hash *Hash[string]) (bool, Error) {
+		for field, value := range hash.All() {
+			fields = append(fields, KeyValue[string, string]{Key: field, Value: value})
+		}
+		return false, nil
+	})
+	return fields, err
+}
+
+// IncrBy adds delta to the integer value of the field, a missing field counts
+// as 0. The hash expires at expiresAt when it is created.
+func (h *hashes) IncrBy(key string, field string, delta int, expiresAt time.Time) (int, Error) {
+	var result int
+	err := h.update(key, true, expiresAt, func(hash *Hash[string]) (bool, Error) {
+		value := 0
+		if current, ok := hash.Get(field); ok {
+			parsed, err := ParseValue(TypeInt, current)
+			if err != nil {
+				return false, &ValueTypeError{valueType: TypeInt}
+			}
+			value = parsed.(int)
+		}
+		if (delta > 0 && value > math.MaxInt-delta) || (delta < 0 && value < math.MinInt-delta) {
+			return false, &OverflowError{command: "HINCRBY"}
+		}
+		result = value + delta
+		hash.Update(field, strconv.Itoa(result))
+		return true, nil
+	})
+	return result, err
+}
+
+func (h *hashes) Len(key string) (int, Error) {
+	length := 0
+	err := h.update(key, false, time.Time{}, func(hash *Hash[string]) (bool, Error) {
+		length = hash.Len()
+		return false, nil
+	})
+	return length, err
+}
+
+// Expire sets the expiration time of the fields, a past one deletes them. It
+// returns for each field whether it exists.
+func (h *hashes) Expire(key string, fields []string, expiresAt time.Time) ([]bool, Error) {
+	expired := make([]bool, len(fields))
+	err := h.update(key, false, time.Time{}, func(hash *Hash[string]) (bool, Error) {
+		changed := false
+		for i, field := range fields {
+			expired[i] = hash.Expire(field, expiresAt)
+			changed = changed || expired[i]
+		}
+		hash.RemoveExpired(time.Now())
+		return changed, nil
+	})
+	return expired, err
+}
+
+func (h *hashes) ExpiresAt(key string, field string) (time.Time, bool, Error) {
+	var expiresAt time.Time
+	exists := false
+	err := h.update(key, false, time.Time{}, func(hash *Hash[string]) (bool, Error) {
+		expiresAt, exists = hash.ExpiresAt(field)
+		return false, nil
+	})
+	return expiresAt, exists, err
+}
+
+type hsetCommand struct {
+	Command
+	hashes Hashes
+}
+
+func NewHSetCommand(hashes Hashes) ExecutableCommand[string, Value] {
+	return &hsetCommand{
+		Command: NewCommand("HSET").
+			WithArgument(KeyCommandArgument).
+			WithArgument(FieldValuesArgument).
+			WithOption(ExpirationOption).
+			WithOption(ExpirationMsOption),
+		hashes: hashes,
+	}
+}
+
+// Run replies the number of fields added. The TTL given as option applies to
+// the whole hash when it is created, like for INCR.
+func (c *hsetCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	tokens := toStrings(input.GetArgument(*FieldValuesArgument).([]any))
+	if len(tokens)%2 != 0 {
+		return nil, &InvalidCommandUsageError{command: c.GetName()}
+	}
+	expiresAt, err := expirationFromInput(c.GetName(), input)
+	if err != nil {
+		return nil, err
+	}
+	fields := make([]KeyValue[string, string], 0, len(tokens)/2)
+	for i := 0; i < len(tokens); i += 2 {
+		fields = append(fields, KeyValue[string, string]{Key: tokens[i], Value: tokens[i+1]})
+	}
+	added, err := c.hashes.Set(key, fields, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return &integerResult[Value]{value: int64(added)}, nil
+}
+
+type hgetCommand struct {
+	Command
+	hashes Hashes
+}
+
+func NewHGetCommand(hashes Hashes) ExecutableCommand[string, Value] {
+	return &hgetCommand{
+		Command: NewCommand("HGET").
+			WithArgument(KeyCommandArgument).
+			WithArgument(FieldArgument),
+		hashes: hashes,
+	}
+}
+
+func (c *hgetCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	field := input.GetArgument(*FieldArgument).(string)
+	values, err := c.hashes.Get(key, []string{field})
+	if err != nil {
+		return nil, err
+	}
+	if values[0] == nil {
+		return &nilResult[Value]{}, nil
+	}
+	return &valueResult[Value]{value: StringValue(*values[0])}, nil
+}
+
+type hmgetCommand struct {
+	Command
+	hashes Hashes
+}
+
+func NewHMGetCommand(hashes Hashes) ExecutableCommand[string, Value] {
+	return &hmgetCommand{
+		Command: NewCommand("HMGET").
+			WithArgument(KeyCommandArgument).
+			WithArgument(FieldsArgument),
+		hashes: hashes,
+	}
+}
+
+func (c *hmgetCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	fields := toStrings(input.GetArgument(*FieldsArgument).([]any))
+	values, err := c.hashes.Get(key, fields)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]Result[Value], len(values))
+	for i, value := range values {
+		if value == nil {
+			items[i] = &nilResult[Value]{}
+			continue
+		}
+		items[i] = &valueResult[Value]{value: StringValue(*value)}
+	}
+	return &arrayResult[Value]{items: items}, nil
+}
+
+type hdelCommand struct {
+	Command
+	hashes Hashes
+}
+
+func NewHDelCommand(hashes Hashes) ExecutableCommand[string, Value] {
+	return &hdelCommand{
+		Command: NewCommand("HDEL").
+			WithArgument(KeyCommandArgument).
+			WithArgument(FieldsArgument),
+		hashes: hashes,
+	}
+}
+
+func (c *hdelCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	fields := toStrings(input.GetArgument(*FieldsArgument).([]any))
+	deleted, err := c.hashes.Delete(key, fields)
+	if err != nil {
+		return nil, err
+	}
+	return &integerResult[Value]{value: int64(deleted)}, nil
+}
+
+type hgetallCommand struct {
+	Command
+	hashes Hashes
+}
+
+func NewHGetAllCommand(hashes Hashes) ExecutableCommand[string, Value] {
+	return &hgetallCommand{
+		Command: NewCommand("HGETALL").
+			WithArgument(KeyCommandArgument),
+		hashes: hashes,
+	}
+}
+
+// Run replies each field followed by its value, sorted by field.
+func (c *hgetallCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	fields, err := c.hashes.All(key)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]Result[Value], 0, 2*len(fields))
+	for _, field := range fields {
+		items = append(items, &valueResult[Value]{value: StringValue(field.Key)}, &valueResult[Value]{value: StringValue(field.Value)})
+	}
+	return &arrayResult[Value]{items: items}, nil
+}
+
+type hincrbyCommand struct {
+	Command
+	hashes Hashes
+}
+
+func NewHIncrByCommand(hashes Hashes) ExecutableCommand[string, Value] {
+	return &hincrbyCommand{
+		Command: NewCommand("HINCRBY").
+			WithArgument(KeyCommandArgument).
+			WithArgument(FieldArgument).
+			WithArgument(FieldIncrementArgument).
+			WithOption(ExpirationOption).
+			WithOption(ExpirationMsOption),
+		hashes: hashes,
+	}
+}
+
+func (c *hincrbyCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	field := input.GetArgument(*FieldArgument).(string)
+	delta := input.GetArgument(*FieldIncrementArgument).(int)
+	expiresAt, err := expirationFromInput(c.GetName(), input)
+	if err != nil {
+		return nil, err
+	}
+	result, err := c.hashes.IncrBy(key, field, delta, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return &integerResult[Value]{value: int64(result)}, nil
+}
+
+type hlenCommand struct {
+	Command
+	hashes Hashes
+}
+
+func NewHLenCommand(hashes Hashes) ExecutableCommand[string, Value] {
+	return &hlenCommand{
+		Command: NewCommand("HLEN").
+			WithArgument(KeyCommandArgument),
+		hashes: hashes,
+	}
+}
+
+func (c *hlenCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	length, err := c.hashes.Len(key)
+	if err != nil {
+		return nil, err
+	}
+	return &integerResult[Value]{value: int64(length)}, nil
+}
+
+type hexpireCommand struct {
+	Command
+	hashes   Hashes
+	argument *commandArgument
+	unit     time.Duration
+}
+
+func NewHExpireCommand(hashes Hashes) ExecutableCommand[string, Value] {
+	return &hexpireCommand{
+		Command: NewCommand("HEXPIRE").
+			WithArgument(KeyCommandArgument).
+			WithArgument(SecondsArgument).
+			WithArgument(ExpiringFieldsArgument),
+		hashes:   hashes,
+		argument: SecondsArgument,
+		unit:     time.Second,
+	}
+}
+
+func NewHPExpireCommand(hashes Hashes) ExecutableCommand[string, Value] {
+	return &hexpireCommand{
+		Command: NewCommand("HPEXPIRE").
+			WithArgument(KeyCommandArgument).
+			WithArgument(MillisecondsArgument).
+			WithArgument(ExpiringFieldsArgument),
+		hashes:   hashes,
+		argument: MillisecondsArgument,
+		unit:     time.Millisecond,
+	}
+}
+
+// Run replies for each field 1 when its expiration was set and 0 when it does
+// not exist.
+func (c *hexpireCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	ttl := input.GetArgument(*c.argument).(int)
+	fields := toStrings(input.GetArgument(*ExpiringFieldsArgument).([]any))
+	expiresAt := time.Now().Add(time.Duration(ttl) * c.unit)
+	expired, err := c.hashes.Expire(key, fields, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]Result[Value], len(expired))
+	for i, ok := range expired {
+		items[i] = booleanResult[Value](ok)
+	}
+	return &arrayResult[Value]{items: items}, nil
+}
+
+type httlCommand struct {
+	Command
+	hashes Hashes
+	unit   time.Duration
+}
+
+func NewHTTLCommand(hashes Hashes) ExecutableCommand[string, Value] {
+	return &httlCommand{
+		Command: NewCommand("HTTL").
+			WithArgument(KeyCommandArgument).
+			WithArgument(FieldArgument),
+		hashes: hashes,
+		unit:   time.Second,
+	}
+}
+
+func NewHPTTLCommand(hashes Hashes) ExecutableCommand[string, Value] {
+	return &httlCommand{
+		Command: NewCommand("HPTTL").
+			WithArgument(KeyCommandArgument).
+			WithArgument(FieldArgument),
+		hashes: hashes,
+		unit:   time.Millisecond,
+	}
+}
+
+// Run replies the time left before the field expires, -1 when it does not
+// expire and -2 when it does not exist, like TTL does for keys.
+func (c *httlCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	field := input.GetArgument(*FieldArgument).(string)
+	expiresAt, ok, err := c.hashes.ExpiresAt(key, field)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return &integerResult[Value]{value: -2}, nil
+	}
+	if expiresAt.IsZero() {
+		return &integerResult[Value]{value: -1}, nil
+	}
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return &integerResult[Value]{value: -2}, nil
+	}
+	return &integerResult[Value]{value: int64((ttl + c.unit/2) / c.unit)}, nil
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"cmp"
+	"math"
+	"math/rand/v2"
+	"slices"
+)
+
+// priorityQueue is a binary heap whose top is the item coming before all the
+// others.
+type priorityQueue[T any] struct {
+	items  []T
+	before func(a, b T) bool
+}
+
+func (q *priorityQueue[T]) Len() int {
+	return len(q.items)
+}
+
+func (q *priorityQueue[T]) top() T {
+	return q.items[0]
+}
+
+func (q *priorityQueue[T]) push(item T) {
+	q.items = append(q.items, item)
+	for i := len(q.items) - 1; i > 0; {
+		parent := (i - 1) / 2
+		if !q.before(q.items[i], q.items[parent]) {
+			break
+		}
+		q.items[i], q.items[parent] = q.items[parent], q.items[i]
+		i = parent
+	}
+}
+
+func (q *priorityQueue[T]) pop() T {
+	top := q.items[0]
+	last := len(q.items) - 1
+	q.items[0] = q.items[last]
+	q.items = q.items[:last]
+	for i := 0; ; {
+		first := i
+		for child := 2*i + 1; child <= 2*i+2 && child < len(q.items); child++ {
+			if q.before(q.items[child], q.items[first]) {
+				first = child
+			}
+		}
+		if first == i {
+			break
+		}
+		q.items[i], q.items[first] = q.items[first], q.items[i]
+		i = first
+	}
+	return top
+}
+
+type hnswNode struct {
+	key    string
+	vector []float32
+	// links are the neighbours of the node in each layer it belongs to
+	links   [][]int32
+	deleted bool
+}
+
+type hnswCandidate struct {
+	id       int32
+	distance float64
+}
+
+func closerCandidate(a, b hnswCandidate) bool {
+	return a.distance < b.distance
+}
+
+func fartherCandidate(a, b hnswCandidate) bool {
+	return a.distance > b.distance
+}
+
+// hnswGraph is a Hierarchical Navigable Small World graph: every vector is a
+// node linked to its nearest neighbours, in layer 0 and in a number of upper
+// layers decreasing exponentially. Searches go down from the sparse top layer
+// to the dense layer 0, walking towards the query in each of them.
+//
+// Removed vectors stay in the graph as deleted nodes to keep it connected,
+// they are only left out of the results.
+type hnswGraph struct {
+	metric         VectorMetric
+	connections    int
+	efConstruction int
+	levelFactor    float64
+	nodes          []hnswNode
+	ids            map[string]int32
+	entry          int32
+	deleted        int
+}
+
+func newHNSWGraph(metric VectorMetric, connections, efConstruction int) *hnswGraph {
+	return &hnswGraph{
+		metric:         metric,
+		connections:    connections,
+		efConstruction: efConstruction,
+		levelFactor:    1 / math.Log(float64(connections)),
+		ids:            make(map[string]int32),
+		entry:          -1,
+	}
+}
+
+// maxLinks is the number of neighbours a node keeps in the layer, twice as
+// many in layer 0 where all the nodes are.
+func (g *hnswGraph) maxLinks(level int) int {
+	if level == 0 {
+		return 2 * g.connections
+	}
+	return g.connections
+}
+
+func (g *hnswGraph) randomLevel() int {
+	return min(int(-math.Log(1-rand.Float64())*g.levelFactor), maxHNSWLevel)
+}
+
+func (g *hnswGraph) distance(vector []float32, id int32) float64 {
+	return g.metric.distance(vector, g.nodes[id].vector)
+}
+
+func (g *hnswGraph) remove(key string) {
+	id, ok := g.ids[key]
+	if !ok {
+		return
+	}
+	g.nodes[id].deleted = true
+	delete(g.ids, key)
+	g.deleted++
+}
+
+// insert adds a node for the vector, replacing the node of the key if any.
+func (g *hnswGraph) insert(key string, vector []float32) {
+	g.remove(key)
+	id := int32(len(g.nodes))
+	level := g.randomLevel()
+	g.nodes = append(g.nodes, hnswNode{key: key, vector: vector, links: make([][]int32, level+1)})
+	g.ids[key] = id
+	if g.entry < 0 {
+		g.entry = id
+		return
+	}
+	entryLevel := len(g.nodes[g.entry].links) - 1
+	nearest := []hnswCandidate{{id: g.entry, distance: g.distance(vector, g.entry)}}
+	for l := entryLevel; l > level; l-- {
+		nearest = g.searchLayer(vector, nearest, 1, l, nil)
+	}
+	for l := min(level, entryLevel); l >= 0; l-- {
+		nearest = g.searchLayer(vector, nearest, g.efConstruction, l, nil)
+		g.nodes[id].links[l] = g.selectNeighbours(nearest, g.connections)
+		for _, neighbour := range g.nodes[id].links[l] {
+			g.link(neighbour, id, l)
+		}
+	}
+	if level > entryLevel {
+		g.entry = id
+	}
+}
+
+// link adds a link in the layer, the links of the node being pruned once there
+// are too many.
+func (g *hnswGraph) link(from, to int32, level int) {
+	links := append(g.nodes[from].links[level], to)
+	if len(links) > g.maxLinks(level) {
+		candidates := make([]hnswCandidate, len(links))
+		for i, id := range links {
+			candidates[i] = hnswCandidate{id: id, distance: g.distance(g.nodes[from].vector, id)}
+		}
+		slices.SortFunc(candidates, compareCandidates)
+		links = g.selectNeighbours(candidates, g.maxLinks(level))
+	}
+	g.nodes[from].links[level] = links
+}
+
+func compareCandidates(a, b hnswCandidate) int {
+	if c := cmp.Compare(a.distance, b.distance); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.id, b.id)
+}
+
+// selectNeighbours picks up to m neighbours among the candidates ordered by
+// distance, preferring the candidates closer to the node than to the
+// neighbours already picked so that links spread in every direction. The other
+// candidates fill the remaining links.
+func (g *hnswGraph) selectNeighbours(candidates []hnswCandidate, m int) []int32 {
+	selected := make([]int32, 0, m)
+	var pruned []int32
+	for _, candidate := range candidates {
+		if len(selected) >= m {
+			break
+		}
+		diverse := true
+		for _, id := range selected {
+			if g.distance(g.nodes[candidate.id].vector, id) < candidate.distance {
+				diverse = false
+				break
+			}
+		}
+		if diverse {
+			selected = append(selected, candidate.id)
+		} else {
+			pruned = append(pruned, candidate.id)
+		}
+	}
+	for _, id := range pruned {
+		if len(selected) >= m {
+			break
+		}
+		selected = append(selected, id)
+	}
+	return selected
+}
+
+// searchLayer walks the layer from the entries towards the query and returns
+// the ef nearest nodes accepted, all of them when accept is nil, ordered by
+// distance. The walk goes on while it finds nodes nearer than the ones
+// returned, or until ef nodes are accepted.
+func (g *hnswGraph) searchLayer(query []float32, entries []hnswCandidate, ef int, level int, accept func(id int32) bool) []hnswCandidate {
+	visited := make(map[int32]struct{})
+	candidates := &priorityQueue[hnswCandidate]{before: closerCandidate}
+	results := &priorityQueue[hnswCandidate]{before: fartherCandidate}
+	for _, entry := range entries {
+		visited[entry.id] = struct{}{}
+		candidates.push(entry)
+		if accept == nil || accept(entry.id) {
+			results.push(entry)
+			if results.Len() > ef {
+				results.pop()
+			}
+		}
+	}
+	for candidates.Len() > 0 {
+		candidate := candidates.pop()
+		if results.Len() >= ef && candidate.distance > results.top().distance {
+			break
+		}
+		for _, id := range g.nodes[candidate.id].links[level] {
+			if _, seen := visited[id]; seen {
+				continue
+			}
+			visited[id] = struct{}{}
+			distance := g.distance(query, id)
+			if results.Len() >= ef && distance >= results.top().distance {
+				continue
+			}
+			candidates.push(hnswCandidate{id: id, distance: distance})
+			if accept == nil || accept(id) {
+				results.push(hnswCandidate{id: id, distance: distance})
+				if results.Len() > ef {
+					results.pop()
+				}
+			}
+		}
+	}
+	nearest := results.items
+	slices.SortFunc(nearest, compareCandidates)
+	return nearest
+}
+
+// search returns the k nodes nearest to the query among the ones not deleted
+// whose keys are accepted, looking at ef candidates in layer 0.
+func (g *hnswGraph) search(query []float32, k, ef int, accept func(key string) bool) []VectorMatch {
+	if g.entry < 0 {
+		return nil
+	}
+	nearest := []hnswCandidate{{id: g.entry, distance: g.distance(query, g.entry)}}
+	for l := len(g.nodes[g.entry].links) - 1; l > 0; l-- {
+		nearest = g.searchLayer(query, nearest, 1, l, nil)
+	}
+	nearest = g.searchLayer(query, nearest, ef, 0, func(id int32) bool {
+		return !g.nodes[id].deleted && accept(g.nodes[id].key)
+	})
+	matches := make([]VectorMatch, len(nearest))
+	for i, candidate := range nearest {
+		matches[i] = VectorMatch{Key: g.nodes[candidate.id].key, Distance: candidate.distance}
+	}
+	slices.SortFunc(matches, compareVectorMatches)
+	return matches[:min(k, len(matches))]
+}
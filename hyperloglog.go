@@ -0,0 +1,63 @@
+package main
+
+import (
+	"math"
+	"math/bits"
+)
+
+const (
+	hllPrecision = 14
+	hllRegisters = 1 << hllPrecision
+)
+
+// HyperLogLog estimates the number of distinct items added to it with a
+// standard error of 0.81%, in 16KB whatever the number of items. Each item is
+// hashed to one of its registers, which keeps the longest run of leading zeros
+// seen in the rest of the hashes.
+type HyperLogLog struct {
+	registers []uint8
+}
+
+func NewHyperLogLog() *HyperLogLog {
+	return &HyperLogLog{registers: make([]uint8, hllRegisters)}
+}
+
+// Add adds the item and returns whether the estimate may have changed.
+func (h *HyperLogLog) Add(item string) bool {
+	hash := hashString(item)
+	register := hash >> (64 - hllPrecision)
+	// The bit set past the precision bounds the run of zeros
+	rank := uint8(bits.LeadingZeros64(hash<<hllPrecision|1<<(hllPrecision-1))) + 1
+	if rank <= h.registers[register] {
+		return false
+	}
+	h.registers[register] = rank
+	return true
+}
+
+// Merge adds the items of other, the estimate then being the one of the union
+// of both.
+func (h *HyperLogLog) Merge(other *HyperLogLog) {
+	for i, rank := range other.registers {
+		h.registers[i] = max(h.registers[i], rank)
+	}
+}
+
+// Count returns the estimated number of distinct items, small cardinalities
+// being counted from the number of empty registers which is more accurate.
+func (h *HyperLogLog) Count() int64 {
+	sum := 0.0
+	empty := 0
+	for _, rank := range h.registers {
+		sum += 1 / float64(uint64(1)<<rank)
+		if rank == 0 {
+			empty++
+		}
+	}
+	m := float64(hllRegisters)
+	estimate := 0.7213 / (1 + 1.079/m) * m * m / sum
+	if estimate <= 2.5*m && empty > 0 {
+		estimate = m * math.Log(m/float64(empty))
+	}
+	return int64(estimate + 0.5)
+}
@@ -0,0 +1,169 @@
+package main
+
+import "time"
+
+// HyperLogLogs holds the HyperLogLogs of the cache.
+type HyperLogLogs interface {
+	Add(key string, items []string, expiresAt time.Time) (bool, Error)
+	Count(keys []string) (int64, Error)
+	Merge(key string, sourceKeys []string) Error
+}
+
+type hyperLogLogs struct {
+	cache Cache[string, Value]
+}
+
+func NewHyperLogLogs(cache Cache[string, Value]) HyperLogLogs {
+	return &hyperLogLogs{cache: cache}
+}
+
+// Add adds the items to the HyperLogLog, which is created when needed and then
+// expires at expiresAt. It returns whether the estimate may have changed.
+func (h *hyperLogLogs) Add(key string, items []string, expiresAt time.Time) (bool, Error) {
+	changed := false
+	_, err := computeAs(h.cache, key, HyperLogLogData, func(current **HyperLogLog) (*HyperLogLog, ComputeAction, Error) {
+		var hll *HyperLogLog
+		if current != nil {
+			hll = *current
+		} else {
+			hll = NewHyperLogLog()
+			changed = true
+		}
+		for _, item := range items {
+			if hll.Add(item) {
+				changed = true
+			}
+		}
+		if !changed {
+			return hll, KeepValue, nil
+		}
+		return hll, StoreValue, nil
+	}, expiresAt)
+	return changed, err
+}
+
+// union merges the HyperLogLogs of the keys into a new one, missing keys
+// counting as empty.
+func (h *hyperLogLogs) union(keys []string) (*HyperLogLog, Error) {
+	union := NewHyperLogLog()
+	for _, key := range keys {
+		_, err := computeAs(h.cache, key, HyperLogLogData, func(current **HyperLogLog) (*HyperLogLog, ComputeAction, Error) {
+			if current != nil {
+				union.Merge(*current)
+			}
+			return nil, KeepValue, nil
+		}, time.Time{})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return union, nil
+}
+
+// Count returns the estimated number of distinct items added to any of the
+// HyperLogLogs of the keys.
+func (h *hyperLogLogs) Count(keys []string) (int64, Error) {
+	union, err := h.union(keys)
+	if err != nil {
+		return 0, err
+	}
+	return union.Count(), nil
+}
+
+// Merge adds the items of the HyperLogLogs of the source keys to the one of
+// the key, which is created when needed.
+func (h *hyperLogLogs) Merge(key string, sourceKeys []string) Error {
+	union, err := h.union(sourceKeys)
+	if err != nil {
+		return err
+	}
+	_, err = computeAs(h.cache, key, HyperLogLogData, func(current **HyperLogLog) (*HyperLogLog, ComputeAction, Error) {
+		if current == nil {
+			return union, StoreValue, nil
+		}
+		(*current).Merge(union)
+		return *current, StoreValue, nil
+	}, time.Time{})
+	return err
+}
+
+type pfaddCommand struct {
+	Command
+	hyperLogLogs HyperLogLogs
+}
+
+func NewPFAddCommand(hyperLogLogs HyperLogLogs) ExecutableCommand[string, Value] {
+	return &pfaddCommand{
+		Command: NewCommand("PFADD").
+			WithArgument(KeyCommandArgument).
+			WithArgument(ItemsArgument).
+			WithOption(ExpirationOption).
+			WithOption(ExpirationMsOption),
+		hyperLogLogs: hyperLogLogs,
+	}
+}
+
+// Run replies 1 when the estimate may have changed and 0 otherwise. The TTL
+// given as option applies to the HyperLogLog when it is created.
+func (c *pfaddCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	items := toStrings(input.GetArgument(*ItemsArgument).([]any))
+	expiresAt, err := expirationFromInput(c.GetName(), input)
+	if err != nil {
+		return nil, err
+	}
+	changed, err := c.hyperLogLogs.Add(key, items, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return booleanResult[Value](changed), nil
+}
+
+type pfcountCommand struct {
+	Command
+	hyperLogLogs HyperLogLogs
+}
+
+func NewPFCountCommand(hyperLogLogs HyperLogLogs) ExecutableCommand[string, Value] {
+	return &pfcountCommand{
+		Command: NewCommand("PFCOUNT").
+			WithArgument(KeysArgument),
+		hyperLogLogs: hyperLogLogs,
+	}
+}
+
+// Run replies the estimated number of distinct items added to the union of the
+// HyperLogLogs.
+func (c *pfcountCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	keys := toStrings(input.GetArgument(*KeysArgument).([]any))
+	count, err := c.hyperLogLogs.Count(keys)
+	if err != nil {
+		return nil, err
+	}
+	return &integerResult[Value]{value: count}, nil
+}
+
+type pfmergeCommand struct {
+	Command
+	hyperLogLogs HyperLogLogs
+}
+
+func NewPFMergeCommand(hyperLogLogs HyperLogLogs) ExecutableCommand[string, Value] {
+	return &pfmergeCommand{
+		Command: NewCommand("PFMERGE").
+			WithArgument(KeyCommandArgument).
+			WithArgument(SourceKeysArgument),
+		hyperLogLogs: hyperLogLogs,
+	}
+}
+
+// Run merges the source HyperLogLogs into the one of the key, which then
+// estimates the union of all of them.
+func (c *pfmergeCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	sourceKeys := toStrings(input.GetArgument(*SourceKeysArgument).([]any))
+	if err := c.hyperLogLogs.Merge(key, sourceKeys); err != nil {
+		return nil, err
+	}
+	return &okResult[Value]{}, nil
+}
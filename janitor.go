@@ -8,6 +8,25 @@ import (
 
 const MinJanitorInterval = time.Second
 
+// Active expiration samples keys with an expiration time every
+// ActiveExpireInterval and deletes the expired ones. Sampling is repeated while
+// more than ActiveExpireRepeatRatio of the sampled keys were expired, within
+// ActiveExpireTimeBudget, so that memory is reclaimed promptly without going
+// over the whole cache at once.
+const (
+	ActiveExpireInterval    = 100 * time.Millisecond
+	ActiveExpireSampleSize  = 20
+	ActiveExpireRepeatRatio = 0.1
+	ActiveExpireTimeBudget  = 25 * time.Millisecond
+	// ActiveExpireScanFactor bounds the number of keys looked at for a sample
+	// to this factor of the sample size.
+	ActiveExpireScanFactor = 10
+)
+
+// ClearExpiredBatchSize is the number of expired keys deleted under a single
+// lock by the periodic sweep of the janitor.
+const ClearExpiredBatchSize = 1000
+
 type Janitor interface {
 	Start()
 	Stop()
@@ -50,18 +69,24 @@ func (j *janitor[K, V]) Start() {
 	j.logger.Info(fmt.Sprintf("[JANITOR_EVENT] Starting %s...", j.String()))
 	j.wg.Add(1)
 	j.isRunning = true
+	j.stop = make(chan struct{})
+	stop := j.stop
 	go func() {
 		defer j.wg.Done()
 		ticker := time.NewTicker(j.interval)
 		defer ticker.Stop()
+		activeTicker := time.NewTicker(ActiveExpireInterval)
+		defer activeTicker.Stop()
 
 		for {
 			select {
+			case <-activeTicker.C:
+				j.activeExpire()
 			case <-ticker.C:
 				j.logger.Info(fmt.Sprintf("[JANITOR_EVENT] %s running...", j.String()))
 				nbrExpired := j.cache.ClearExpired()
 				j.logger.Info(fmt.Sprintf("[JANITOR_EVENT] %s done, cleared %d keys", j.String(), nbrExpired))
-			case <-j.stop:
+			case <-stop:
 				j.isRunning = false
 				return
 			}
@@ -70,6 +95,24 @@ func (j *janitor[K, V]) Start() {
 	j.logger.Info(fmt.Sprintf("[JANITOR_EVENT] %s started", j.String()))
 }
 
+// activeExpire samples the cache for expired keys, repeating while the
+// expired ratio of the sample is high and the time budget is not spent.
+func (j *janitor[K, V]) activeExpire() int {
+	deadline := time.Now().Add(ActiveExpireTimeBudget)
+	nbrExpired := 0
+	for {
+		sampled, expired := j.cache.ExpireSample(ActiveExpireSampleSize)
+		nbrExpired += expired
+		if sampled == 0 || float64(expired) <= float64(sampled)*ActiveExpireRepeatRatio || time.Now().After(deadline) {
+			break
+		}
+	}
+	if nbrExpired > 0 {
+		j.logger.Info(fmt.Sprintf("[JANITOR_EVENT] %s actively expired %d keys", j.String(), nbrExpired))
+	}
+	return nbrExpired
+}
+
 func (j *janitor[K, V]) Stop() {
 	if !j.IsRunning() {
 		j.logger.Warning("[JANITOR_EVENT] Janitor is not running!")
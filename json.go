@@ -0,0 +1,324 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/buger/jsonparser"
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+// jsonObject keeps the keys of a JSON object in the order they were added.
+type jsonObject = *orderedmap.OrderedMap[string, any]
+
+// JSONDocument is a JSON value decoded into a tree, so that it is queried and
+// updated by path without being parsed again. Objects are ordered maps,
+// arrays are []any, numbers are json.Number so that integers keep their
+// precision, along with strings, booleans and nil for null.
+type JSONDocument struct {
+	root any
+}
+
+func NewJSONDocument(root any) *JSONDocument {
+	return &JSONDocument{root: root}
+}
+
+// ParseJSON validates a JSON text and decodes it into a tree.
+func ParseJSON(data []byte) (any, error) {
+	if !json.Valid(data) {
+		return nil, fmt.Errorf("invalid JSON")
+	}
+	value, dataType, _, err := jsonparser.Get(data)
+	if err != nil {
+		return nil, err
+	}
+	return decodeJSON(value, dataType)
+}
+
+// decodeJSON decodes a value as returned by jsonparser, strings being given
+// without their quotes.
+func decodeJSON(data []byte, dataType jsonparser.ValueType) (any, error) {
+	switch dataType {
+	case jsonparser.Object:
+		object := orderedmap.New[string, any]()
+		err := jsonparser.ObjectEach(data, func(key []byte, value []byte, valueType jsonparser.ValueType, _ int) error {
+			name, err := jsonparser.ParseString(key)
+			if err != nil {
+				return err
+			}
+			decoded, err := decodeJSON(value, valueType)
+			if err != nil {
+				return err
+			}
+			object.Set(name, decoded)
+			return nil
+		})
+		return object, err
+	case jsonparser.Array:
+		array := []any{}
+		var decodeErr error
+		_, err := jsonparser.ArrayEach(data, func(value []byte, valueType jsonparser.ValueType, _ int, err error) {
+			if decodeErr != nil {
+				return
+			}
+			if err != nil {
+				decodeErr = err
+				return
+			}
+			decoded, err := decodeJSON(value, valueType)
+			if err != nil {
+				decodeErr = err
+				return
+			}
+			array = append(array, decoded)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return array, decodeErr
+	case jsonparser.String:
+		return jsonparser.ParseString(data)
+	case jsonparser.Number:
+		return json.Number(data), nil
+	case jsonparser.Boolean:
+		return jsonparser.ParseBoolean(data)
+	case jsonparser.Null:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unexpected JSON value: %s", data)
+	}
+}
+
+// EncodeJSON encodes a value of a document tree.
+func EncodeJSON(value any) []byte {
+	// The values of a tree are always encodable
+	data, _ := json.Marshal(value)
+	return data
+}
+
+// JSONTypeOf returns the name of the JSON type of a value of a document tree.
+func JSONTypeOf(value any) string {
+	switch value := value.(type) {
+	case jsonObject:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case json.Number:
+		if _, err := value.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case bool:
+		return "boolean"
+	default:
+		return "null"
+	}
+}
+
+// jsonPathStep is either the key of an object or the index of an array,
+// negative indexes counting from the end.
+type jsonPathStep struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// JSONPath leads from the root of a document to one of its values, the root
+// itself being the empty path.
+type JSONPath []jsonPathStep
+
+// ParseJSONPath reads a JSONPath-like path: $ stands for the root and is
+// followed by keys written .key or ["key"] and array indexes written [0].
+// The $ can be left out, as well as the first dot, so that "." and "a.b" are
+// valid paths too.
+func ParseJSONPath(path string) (JSONPath, bool) {
+	rest, rooted := strings.CutPrefix(path, "$")
+	if rest == "." && !rooted {
+		return JSONPath{}, true
+	}
+	if rest != "" && rest[0] != '.' && rest[0] != '[' {
+		if rooted {
+			return nil, false
+		}
+		rest = "." + rest
+	}
+	steps := JSONPath{}
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			if end == 0 {
+				return nil, false
+			}
+			steps = append(steps, jsonPathStep{key: rest[1 : end+1]})
+			rest = rest[end+1:]
+		case '[':
+			step, length, ok := parseJSONPathBracket(rest)
+			if !ok {
+				return nil, false
+			}
+			steps = append(steps, step)
+			rest = rest[length:]
+		default:
+			return nil, false
+		}
+	}
+	return steps, true
+}
+
+// parseJSONPathBracket reads a quoted key or an index between brackets at the
+// start of the path, it returns the length read.
+func parseJSONPathBracket(path string) (jsonPathStep, int, bool) {
+	if len(path) > 1 && (path[1] == '"' || path[1] == '\'') {
+		quote := path[1]
+		end := strings.IndexByte(path[2:], quote)
+		if end < 0 || len(path) < end+4 || path[end+3] != ']' {
+			return jsonPathStep{}, 0, false
+		}
+		return jsonPathStep{key: path[2 : end+2]}, end + 4, true
+	}
+	end := strings.IndexByte(path, ']')
+	if end < 0 {
+		return jsonPathStep{}, 0, false
+	}
+	index, err := strconv.Atoi(path[1:end])
+	if err != nil {
+		return jsonPathStep{}, 0, false
+	}
+	return jsonPathStep{index: index, isIndex: true}, end + 1, true
+}
+
+// arrayIndex resolves the index of the step in an array of the given length.
+func arrayIndex(length int, step jsonPathStep) (int, bool) {
+	if !step.isIndex {
+		return 0, false
+	}
+	index := step.index
+	if index < 0 {
+		index += length
+	}
+	return index, index >= 0 && index < length
+}
+
+// child returns the value of an object or an array at the step.
+func child(container any, step jsonPathStep) (any, bool) {
+	switch container := container.(type) {
+	case jsonObject:
+		if step.isIndex {
+			return nil, false
+		}
+		return container.Get(step.key)
+	case []any:
+		index, ok := arrayIndex(len(container), step)
+		if !ok {
+			return nil, false
+		}
+		return container[index], true
+	default:
+		return nil, false
+	}
+}
+
+// Get returns the value at the path.
+func (d *JSONDocument) Get(path JSONPath) (any, bool) {
+	value := d.root
+	for _, step := range path {
+		var ok bool
+		if value, ok = child(value, step); !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// Set replaces the value at the path, a key missing from an object being
+// added. It returns false when the parent of the path does not exist, is not
+// a container or does not have the index.
+func (d *JSONDocument) Set(path JSONPath, value any) bool {
+	if len(path) == 0 {
+		d.root = value
+		return true
+	}
+	parent, ok := d.Get(path[:len(path)-1])
+	if !ok {
+		return false
+	}
+	step := path[len(path)-1]
+	switch container := parent.(type) {
+	case jsonObject:
+		if step.isIndex {
+			return false
+		}
+		container.Set(step.key, value)
+		return true
+	case []any:
+		index, ok := arrayIndex(len(container), step)
+		if !ok {
+			return false
+		}
+		container[index] = value
+		return true
+	default:
+		return false
+	}
+}
+
+// Delete removes the value at a path other than the root and returns whether
+// it existed.
+func (d *JSONDocument) Delete(path JSONPath) bool {
+	if len(path) == 0 {
+		return false
+	}
+	parentPath := path[:len(path)-1]
+	parent, ok := d.Get(parentPath)
+	if !ok {
+		return false
+	}
+	step := path[len(path)-1]
+	switch container := parent.(type) {
+	case jsonObject:
+		if step.isIndex {
+			return false
+		}
+		_, present := container.Delete(step.key)
+		return present
+	case []any:
+		index, ok := arrayIndex(len(container), step)
+		if !ok {
+			return false
+		}
+		// The shorter array replaces the one held by the grandparent
+		return d.Set(parentPath, slices.Delete(container, index, index+1))
+	default:
+		return false
+	}
+}
+
+// addJSONNumbers adds two numbers as integers when both are integers and as
+// floats otherwise. It returns false when the sum overflows.
+func addJSONNumbers(a, b json.Number) (json.Number, bool) {
+	x, errX := a.Int64()
+	y, errY := b.Int64()
+	if errX == nil && errY == nil {
+		if (y > 0 && x > math.MaxInt64-y) || (y < 0 && x < math.MinInt64-y) {
+			return "", false
+		}
+		return json.Number(strconv.FormatInt(x+y, 10)), true
+	}
+	fx, errX := a.Float64()
+	fy, errY := b.Float64()
+	sum := fx + fy
+	if errX != nil || errY != nil || math.IsNaN(sum) || math.IsInf(sum, 0) {
+		return "", false
+	}
+	return json.Number(formatScore(sum)), true
+}
@@ -0,0 +1,427 @@
+package main
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// JSONDocuments holds the JSON documents of the cache, their values are
+// queried and updated by path on the server.
+type JSONDocuments interface {
+	Set(key string, path JSONPath, value any, condition func(exists bool) bool, expiresAt time.Time) (bool, Error)
+	Get(key string, path JSONPath) ([]byte, bool, Error)
+	Delete(key string, path JSONPath) (bool, Error)
+	Type(key string, path JSONPath) (string, bool, Error)
+	IncrBy(key string, path JSONPath, delta json.Number) (json.Number, bool, Error)
+	Append(key string, path JSONPath, values []any) (int, bool, Error)
+}
+
+type jsonDocuments struct {
+	cache Cache[string, Value]
+}
+
+func NewJSONDocuments(cache Cache[string, Value]) JSONDocuments {
+	return &jsonDocuments{cache: cache}
+}
+
+// read runs read on the document of the key when it exists.
+func (d *jsonDocuments) read(key string, read func(document *JSONDocument)) Error {
+	_, err := computeAs(d.cache, key, JSONData, func(current **JSONDocument) (*JSONDocument, ComputeAction, Error) {
+		if current != nil {
+			read(*current)
+		}
+		return nil, KeepValue, nil
+	}, time.Time{})
+	return err
+}
+
+// update runs change on the document of the key when it exists, change
+// reports whether it modified the document.
+func (d *jsonDocuments) update(key string, change func(document *JSONDocument) (bool, Error)) Error {
+	_, err := computeAs(d.cache, key, JSONData, func(current **JSONDocument) (*JSONDocument, ComputeAction, Error) {
+		if current == nil {
+			return nil, KeepValue, nil
+		}
+		changed, err := change(*current)
+		if err != nil || !changed {
+			return *current, KeepValue, err
+		}
+		return *current, StoreValue, nil
+	}, time.Time{})
+	return err
+}
+
+// Set stores the value at the path when condition, if any, accepts whether
+// the path exists. A document is created by setting its root and expires at
+// expiresAt, its other paths can only be set once their parent exists. It
+// returns whether the value was stored.
+func (d *jsonDocuments) Set(key string, path JSONPath, value any, condition func(exists bool) bool, expiresAt time.Time) (bool, Error) {
+	set := false
+	_, err := computeAs(d.cache, key, JSONData, func(current **JSONDocument) (*JSONDocument, ComputeAction, Error) {
+		if current == nil {
+			if len(path) > 0 {
+				return nil, KeepValue, &CommandError{message: "New documents must be created at the root path in JSON.SET"}
+			}
+			if condition != nil && !condition(false) {
+				return nil, KeepValue, nil
+			}
+			set = true
+			return NewJSONDocument(value), StoreValue, nil
+		}
+		document := *current
+		_, exists := document.Get(path)
+		if condition != nil && !condition(exists) {
+			return document, KeepValue, nil
+		}
+		if set = document.Set(path, value); !set {
+			return document, KeepValue, nil
+		}
+		return document, StoreValue, nil
+	}, expiresAt)
+	return set, err
+}
+
+// Get returns the encoded value at the path.
+func (d *jsonDocuments) Get(key string, path JSONPath) ([]byte, bool, Error) {
+	var data []byte
+	found := false
+	err := d.read(key, func(document *JSONDocument) {
+		var value any
+		if value, found = document.Get(path); found {
+			data = EncodeJSON(value)
+		}
+	})
+	return data, found, err
+}
+
+// Delete removes the value at the path, the whole key for the root path. It
+// returns whether the value existed.
+func (d *jsonDocuments) Delete(key string, path JSONPath) (bool, Error) {
+	deleted := false
+	_, err := computeAs(d.cache, key, JSONData, func(current **JSONDocument) (*JSONDocument, ComputeAction, Error) {
+		if current == nil {
+			return nil, KeepValue, nil
+		}
+		document := *current
+		if len(path) == 0 {
+			deleted = true
+			return document, DeleteValue, nil
+		}
+		if deleted = document.Delete(path); !deleted {
+			return document, KeepValue, nil
+		}
+		return document, StoreValue, nil
+	}, time.Time{})
+	return deleted, err
+}
+
+// Type returns the name of the JSON type of the value at the path.
+func (d *jsonDocuments) Type(key string, path JSONPath) (string, bool, Error) {
+	name := ""
+	found := false
+	err := d.read(key, func(document *JSONDocument) {
+		var value any
+		if value, found = document.Get(path); found {
+			name = JSONTypeOf(value)
+		}
+	})
+	return name, found, err
+}
+
+// IncrBy adds delta to the number at the path and returns the sum.
+func (d *jsonDocuments) IncrBy(key string, path JSONPath, delta json.Number) (json.Number, bool, Error) {
+	var sum json.Number
+	found := false
+	err := d.update(key, func(document *JSONDocument) (bool, Error) {
+		var value any
+		if value, found = document.Get(path); !found {
+			return false, nil
+		}
+		number, ok := value.(json.Number)
+		if !ok {
+			return false, &CommandError{message: "Path does not lead to a number in JSON.NUMINCRBY"}
+		}
+		if sum, ok = addJSONNumbers(number, delta); !ok {
+			return false, &OverflowError{command: "JSON.NUMINCRBY"}
+		}
+		return document.Set(path, sum), nil
+	})
+	return sum, found, err
+}
+
+// Append appends the values to the array at the path and returns its new
+// length.
+func (d *jsonDocuments) Append(key string, path JSONPath, values []any) (int, bool, Error) {
+	length := 0
+	found := false
+	err := d.update(key, func(document *JSONDocument) (bool, Error) {
+		var value any
+		if value, found = document.Get(path); !found {
+			return false, nil
+		}
+		array, ok := value.([]any)
+		if !ok {
+			return false, &CommandError{message: "Path does not lead to an array in JSON.ARRAPPEND"}
+		}
+		array = append(array, values...)
+		length = len(array)
+		return document.Set(path, array), nil
+	})
+	return length, found, err
+}
+
+// jsonPathFromInput reads the path argument of the command, the root when it
+// is optional and not given.
+func jsonPathFromInput(commandName string, input CommandInput, argument *commandArgument) (JSONPath, Error) {
+	token, ok := input.GetArgument(*argument).(string)
+	if !ok {
+		return JSONPath{}, nil
+	}
+	path, ok := ParseJSONPath(token)
+	if !ok {
+		return nil, &CommandError{message: "Invalid JSON path in " + commandName}
+	}
+	return path, nil
+}
+
+// parseJSONValue validates and decodes a value written to a document.
+func parseJSONValue(commandName string, token string) (any, Error) {
+	value, err := ParseJSON([]byte(token))
+	if err != nil {
+		return nil, &CommandError{message: "Invalid JSON value in " + commandName}
+	}
+	return value, nil
+}
+
+type jsonSetCommand struct {
+	Command
+	documents JSONDocuments
+}
+
+func NewJSONSetCommand(documents JSONDocuments) ExecutableCommand[string, Value] {
+	return &jsonSetCommand{
+		Command: NewCommand("JSON.SET").
+			WithArgument(KeyCommandArgument).
+			WithArgument(JSONPathArgument).
+			WithArgument(JSONValueArgument).
+			WithOption(IfAbsentOption).
+			WithOption(IfExistsOption).
+			WithOption(ExpirationOption).
+			WithOption(ExpirationMsOption),
+		documents: documents,
+	}
+}
+
+// Run replies OK when the value is stored and nil otherwise. The nx and xx
+// options apply to the path rather than to the key, and the TTL given as
+// option applies to the document when it is created.
+func (c *jsonSetCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	path, err := jsonPathFromInput(c.GetName(), input, JSONPathArgument)
+	if err != nil {
+		return nil, err
+	}
+	value, err := parseJSONValue(c.GetName(), input.GetArgument(*JSONValueArgument).(string))
+	if err != nil {
+		return nil, err
+	}
+	expiresAt, err := expirationFromInput(c.GetName(), input)
+	if err != nil {
+		return nil, err
+	}
+	var condition func(exists bool) bool
+	ifAbsent := input.GetOption(*IfAbsentOption) != nil
+	ifExists := input.GetOption(*IfExistsOption) != nil
+	switch {
+	case ifAbsent && ifExists:
+		return nil, &InvalidCommandUsageError{command: c.GetName()}
+	case ifAbsent:
+		condition = func(exists bool) bool { return !exists }
+	case ifExists:
+		condition = func(exists bool) bool { return exists }
+	}
+	set, err := c.documents.Set(key, path, value, condition, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	if !set {
+		return &nilResult[Value]{}, nil
+	}
+	return &okResult[Value]{}, nil
+}
+
+type jsonGetCommand struct {
+	Command
+	documents JSONDocuments
+}
+
+func NewJSONGetCommand(documents JSONDocuments) ExecutableCommand[string, Value] {
+	return &jsonGetCommand{
+		Command: NewCommand("JSON.GET").
+			WithArgument(KeyCommandArgument).
+			WithArgument(OptionalJSONPathArgument),
+		documents: documents,
+	}
+}
+
+// Run replies the value at the path encoded as JSON, nil when it does not
+// exist.
+func (c *jsonGetCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	path, err := jsonPathFromInput(c.GetName(), input, OptionalJSONPathArgument)
+	if err != nil {
+		return nil, err
+	}
+	data, found, err := c.documents.Get(key, path)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return &nilResult[Value]{}, nil
+	}
+	return &valueResult[Value]{value: StringValue(string(data))}, nil
+}
+
+type jsonDelCommand struct {
+	Command
+	documents JSONDocuments
+}
+
+func NewJSONDelCommand(documents JSONDocuments) ExecutableCommand[string, Value] {
+	return &jsonDelCommand{
+		Command: NewCommand("JSON.DEL").
+			WithArgument(KeyCommandArgument).
+			WithArgument(OptionalJSONPathArgument),
+		documents: documents,
+	}
+}
+
+// Run replies the number of values deleted, the key itself being deleted for
+// the root path.
+func (c *jsonDelCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	path, err := jsonPathFromInput(c.GetName(), input, OptionalJSONPathArgument)
+	if err != nil {
+		return nil, err
+	}
+	deleted, err := c.documents.Delete(key, path)
+	if err != nil {
+		return nil, err
+	}
+	if !deleted {
+		return &integerResult[Value]{value: 0}, nil
+	}
+	return &integerResult[Value]{value: 1}, nil
+}
+
+type jsonTypeCommand struct {
+	Command
+	documents JSONDocuments
+}
+
+func NewJSONTypeCommand(documents JSONDocuments) ExecutableCommand[string, Value] {
+	return &jsonTypeCommand{
+		Command: NewCommand("JSON.TYPE").
+			WithArgument(KeyCommandArgument).
+			WithArgument(OptionalJSONPathArgument),
+		documents: documents,
+	}
+}
+
+// Run replies the JSON type of the value at the path: object, array, string,
+// integer, number, boolean or null, and nil when it does not exist.
+func (c *jsonTypeCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	path, err := jsonPathFromInput(c.GetName(), input, OptionalJSONPathArgument)
+	if err != nil {
+		return nil, err
+	}
+	name, found, err := c.documents.Type(key, path)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return &nilResult[Value]{}, nil
+	}
+	return &textResult[Value]{text: name}, nil
+}
+
+type jsonNumIncrByCommand struct {
+	Command
+	documents JSONDocuments
+}
+
+func NewJSONNumIncrByCommand(documents JSONDocuments) ExecutableCommand[string, Value] {
+	return &jsonNumIncrByCommand{
+		Command: NewCommand("JSON.NUMINCRBY").
+			WithArgument(KeyCommandArgument).
+			WithArgument(JSONPathArgument).
+			WithArgument(NumberIncrementArgument),
+		documents: documents,
+	}
+}
+
+// Run replies the number at the path once incremented, nil when the path does
+// not exist. Integers stay integers while adding a float gives a float.
+func (c *jsonNumIncrByCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	path, err := jsonPathFromInput(c.GetName(), input, JSONPathArgument)
+	if err != nil {
+		return nil, err
+	}
+	increment, parseErr := ParseJSON([]byte(input.GetArgument(*NumberIncrementArgument).(string)))
+	delta, ok := increment.(json.Number)
+	if parseErr != nil || !ok {
+		return nil, &ValueTypeError{valueType: TypeFloat}
+	}
+	sum, found, err := c.documents.IncrBy(key, path, delta)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return &nilResult[Value]{}, nil
+	}
+	return &valueResult[Value]{value: StringValue(string(sum))}, nil
+}
+
+type jsonArrAppendCommand struct {
+	Command
+	documents JSONDocuments
+}
+
+func NewJSONArrAppendCommand(documents JSONDocuments) ExecutableCommand[string, Value] {
+	return &jsonArrAppendCommand{
+		Command: NewCommand("JSON.ARRAPPEND").
+			WithArgument(KeyCommandArgument).
+			WithArgument(JSONPathArgument).
+			WithArgument(JSONValuesArgument),
+		documents: documents,
+	}
+}
+
+// Run replies the length of the array once the values are appended, nil when
+// the path does not exist.
+func (c *jsonArrAppendCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	path, err := jsonPathFromInput(c.GetName(), input, JSONPathArgument)
+	if err != nil {
+		return nil, err
+	}
+	tokens := toStrings(input.GetArgument(*JSONValuesArgument).([]any))
+	values := make([]any, 0, len(tokens))
+	for _, token := range tokens {
+		value, err := parseJSONValue(c.GetName(), token)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	length, found, err := c.documents.Append(key, path, values)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return &nilResult[Value]{}, nil
+	}
+	return &integerResult[Value]{value: int64(length)}, nil
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"time"
+)
+
+type setCommand struct {
+	Command
+}
+
+func NewSetCommand() ExecutableCommand[string, Value] {
+	return &setCommand{
+		Command: NewCommand("SET").
+			WithArgument(KeyCommandArgument).
+			WithArgument(ValueArgument).
+			WithArgument(TTLArgument).
+			WithOption(ExpirationOption).
+			WithOption(ExpirationMsOption).
+			WithOption(IdleTimeoutOption).
+			WithOption(IfAbsentOption).
+			WithOption(IfExistsOption).
+			WithOption(GetPreviousOption).
+			WithOption(FrequentAccessOption),
+	}
+}
+
+func (c *setCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	value := input.GetArgument(*ValueArgument).(string)
+	expiresAt, err := expirationFromInput(c.GetName(), input)
+	if err != nil {
+		return nil, err
+	}
+	var condition WriteCondition[Value]
+	ifAbsent := input.GetOption(*IfAbsentOption) != nil
+	ifExists := input.GetOption(*IfExistsOption) != nil
+	switch {
+	case ifAbsent && ifExists:
+		return nil, &InvalidCommandUsageError{command: c.GetName()}
+	case ifAbsent:
+		condition = IfAbsent[Value]
+	case ifExists:
+		condition = IfExists[Value]
+	}
+
+	// The previous value replied must be a string, other values are then not
+	// replaced.
+	getPrevious := input.GetOption(*GetPreviousOption) != nil
+	wrongType := false
+	if getPrevious {
+		valueCondition := condition
+		condition = func(current CacheValue[Value]) bool {
+			if current != nil && current.Value().Type() != StringData {
+				wrongType = true
+				return false
+			}
+			return valueCondition == nil || valueCondition(current)
+		}
+	}
+
+	var previous CacheValue[Value]
+	var ok bool
+	// With an idle timeout, the expiration time becomes the maximum lifetime
+	// of the key.
+	if idleTimeout := input.GetOption(*IdleTimeoutOption); idleTimeout != nil {
+		if idleTimeout.(int) <= 0 {
+			return nil, &CommandError{message: "Invalid idle timeout in " + c.GetName()}
+		}
+		previous, ok = cache.SetIdleIf(key, StringValue(value), time.Duration(idleTimeout.(int))*time.Second, expiresAt, condition)
+	} else {
+		previous, ok = cache.SetIf(key, StringValue(value), expiresAt, condition)
+	}
+
+	if wrongType {
+		return nil, &WrongTypeError{}
+	}
+	if getPrevious {
+		if previous == nil {
+			return &nilResult[Value]{}, nil
+		}
+		return &valueResult[Value]{value: previous.Value()}, nil
+	}
+	if !ok {
+		return &nilResult[Value]{}, nil
+	}
+	return &okResult[Value]{}, nil
+}
+
+// setNXCommand sets a key only when it does not exist, replying whether it was
+// set.
+type setNXCommand struct {
+	Command
+}
+
+func NewSetNXCommand() ExecutableCommand[string, Value] {
+	return &setNXCommand{
+		Command: NewCommand("SETNX").
+			WithArgument(KeyCommandArgument).
+			WithArgument(ValueArgument).
+			WithArgument(TTLArgument).
+			WithOption(ExpirationOption).
+			WithOption(ExpirationMsOption).
+			WithOption(FrequentAccessOption),
+	}
+}
+
+func (c *setNXCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	value := input.GetArgument(*ValueArgument).(string)
+	expiresAt, err := expirationFromInput(c.GetName(), input)
+	if err != nil {
+		return nil, err
+	}
+	_, ok := cache.SetIf(key, StringValue(value), expiresAt, IfAbsent[Value])
+	return booleanResult[Value](ok), nil
+}
+
+// expirationFromInput returns the expiration time given either as the TTL
+// argument or one of the expiration options, or a zero time when there is
+// none.
+func expirationFromInput(commandName string, input CommandInput) (time.Time, Error) {
+	unit := time.Second
+	ttl := input.GetArgument(*TTLArgument)
+	if ttl == nil {
+		ttl = input.GetOption(*ExpirationOption)
+	}
+	if ttl == nil {
+		ttl = input.GetOption(*ExpirationMsOption)
+		unit = time.Millisecond
+	}
+	if ttl == nil {
+		return time.Time{}, nil
+	}
+	value := ttl.(int)
+	if value <= 0 {
+		return time.Time{}, &CommandError{message: "Invalid expire time in " + commandName}
+	}
+	return time.Now().Add(time.Duration(value) * unit), nil
+}
+
+// getCommand replies the value of a key holding a string.
+type getCommand struct {
+	Command
+}
+
+func NewGetCommand() ExecutableCommand[string, Value] {
+	return &getCommand{
+		Command: NewCommand("GET").
+			WithArgument(KeyCommandArgument).
+			WithOption(FrequentAccessOption),
+	}
+}
+
+func (c *getCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	value, ok := cache.Get(key)
+	if !ok {
+		return &nilResult[Value]{}, nil
+	}
+	if value.Type() != StringData {
+		return nil, &WrongTypeError{}
+	}
+	return &valueResult[Value]{value: *value}, nil
+}
+
+// typeCommand replies the data type of the value of a key, none when the key
+// does not exist.
+type typeCommand struct {
+	Command
+}
+
+func NewTypeCommand() ExecutableCommand[string, Value] {
+	return &typeCommand{
+		Command: NewCommand("TYPE").
+			WithArgument(KeyCommandArgument).
+			WithOption(FrequentAccessOption),
+	}
+}
+
+func (c *typeCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	cacheValue, ok := cache.get(key)
+	if !ok || isExpired(cacheValue, time.Now()) {
+		return &textResult[Value]{text: "none"}, nil
+	}
+	return &textResult[Value]{text: cacheValue.Value().Type().String()}, nil
+}
+
+// delCommand deletes one or more keys and replies the number of keys deleted.
+type delCommand[V any] struct {
+	Command
+}
+
+func NewDelCommand[V any]() ExecutableCommand[string, V] {
+	return &delCommand[V]{
+		Command: NewCommand("DEL").
+			WithArgument(KeysArgument).
+			WithOption(FrequentAccessOption),
+	}
+}
+
+func NewMDelCommand[V any]() ExecutableCommand[string, V] {
+	return &delCommand[V]{
+		Command: NewCommand("MDEL").
+			WithArgument(KeysArgument).
+			WithOption(FrequentAccessOption),
+	}
+}
+
+func (c *delCommand[V]) Run(input CommandInput, cache Cache[string, V]) (Result[V], Error) {
+	keys := toStrings(input.GetArgument(*KeysArgument).([]any))
+	if len(keys) == 1 {
+		return booleanResult[V](cache.Delete(keys[0])), nil
+	}
+	return &integerResult[V]{value: int64(cache.DeleteMany(keys))}, nil
+}
+
+type flushCommand[V any] struct {
+	Command
+}
+
+func NewFlushCommand[V any]() ExecutableCommand[string, V] {
+	return &flushCommand[V]{
+		Command: NewCommand("FLUSH").
+			WithOption(FrequentAccessOption),
+	}
+}
+
+func (c *flushCommand[V]) Run(input CommandInput, cache Cache[string, V]) (Result[V], Error) {
+	cache.Clear()
+	return &okResult[V]{}, nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import "sync"
+
+// keyWaiter is woken up every time one of the keys it waits for is written,
+// it then has to read it before others do.
+type keyWaiter struct {
+	keys []string
+	wake chan struct{}
+}
+
+// keyWaiters holds the clients blocked until one of the keys they wait for is
+// written, for the stores of the data types read by blocking commands.
+type keyWaiters struct {
+	mu      sync.Mutex
+	waiters map[string]map[*keyWaiter]struct{}
+}
+
+func newKeyWaiters() *keyWaiters {
+	return &keyWaiters{waiters: make(map[string]map[*keyWaiter]struct{})}
+}
+
+// Wait registers a waiter for the keys, it must be registered before looking
+// at the keys so that no write happening in between is missed.
+func (w *keyWaiters) Wait(keys []string) *keyWaiter {
+	waiter := &keyWaiter{keys: keys, wake: make(chan struct{}, 1)}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, key := range keys {
+		if w.waiters[key] == nil {
+			w.waiters[key] = make(map[*keyWaiter]struct{})
+		}
+		w.waiters[key][waiter] = struct{}{}
+	}
+	return waiter
+}
+
+func (w *keyWaiters) StopWaiting(waiter *keyWaiter) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, key := range waiter.keys {
+		delete(w.waiters[key], waiter)
+		if len(w.waiters[key]) == 0 {
+			delete(w.waiters, key)
+		}
+	}
+}
+
+// signal wakes up all the waiters of the key, the ones already woken up keep a
+// single pending wake up.
+func (w *keyWaiters) signal(key string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for waiter := range w.waiters[key] {
+		select {
+		case waiter.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// BlockingResult is implemented by the results of blocking commands, which
+// send their reply themselves once the keys they wait for are written or their
+// timeout is reached. They read the keys again through execute so that these
+// reads do not happen in the middle of a transaction, and return whether the
+// connection can be used again.
+type BlockingResult interface {
+	Block(connection Connection, execute func(func())) bool
+}
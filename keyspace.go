@@ -0,0 +1,248 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+const KeyspaceEventsBufferSize = 4096
+
+// Keyspace event types
+const (
+	SetEvent     = "set"
+	DelEvent     = "del"
+	ExpireEvent  = "expire"
+	PersistEvent = "persist"
+	ExpiredEvent = "expired"
+)
+
+// writeEventSuffix makes the event type of the writes of the values of the
+// other data types than strings, e.g. list.write for LPUSH or LSET.
+const writeEventSuffix = ".write"
+
+// KeyspaceEventClass is a set of event types that can be subscribed to.
+type KeyspaceEventClass uint16
+
+const (
+	// GenericEvents are the events of commands working on any key: del,
+	// expire and persist.
+	GenericEvents KeyspaceEventClass = 1 << iota
+	// StringEvents are the writes of strings: set.
+	StringEvents
+	// ListEvents are the writes of lists: list.write.
+	ListEvents
+	// HashEvents are the writes of hashes: hash.write.
+	HashEvents
+	// SetEvents are the writes of sets: set.write.
+	SetEvents
+	// SortedSetEvents are the writes of sorted sets: zset.write.
+	SortedSetEvents
+	// StreamEvents are the writes of streams: stream.write.
+	StreamEvents
+	// OtherTypeEvents are the writes of JSON documents, Bloom filters,
+	// HyperLogLogs, Count-Min Sketches and vector indexes: json.write,
+	// bloom.write, hyperloglog.write, cms.write and vectorindex.write.
+	OtherTypeEvents
+	// ExpiredEvents are sent when expired keys are deleted, which happens
+	// when they are accessed or cleared by the janitor rather than exactly
+	// when they expire.
+	ExpiredEvents
+
+	AllEvents = GenericEvents | StringEvents | ListEvents | HashEvents | SetEvents |
+		SortedSetEvents | StreamEvents | OtherTypeEvents | ExpiredEvents
+)
+
+var keyspaceEventClasses = map[string]KeyspaceEventClass{
+	SetEvent:                             StringEvents,
+	DelEvent:                             GenericEvents,
+	ExpireEvent:                          GenericEvents,
+	PersistEvent:                         GenericEvents,
+	ExpiredEvent:                         ExpiredEvents,
+	ListData.String() + writeEventSuffix: ListEvents,
+	HashData.String() + writeEventSuffix: HashEvents,
+	SetData.String() + writeEventSuffix:  SetEvents,
+	SortedSetData.String() + writeEventSuffix:      SortedSetEvents,
+	StreamData.String() + writeEventSuffix:         StreamEvents,
+	JSONData.String() + writeEventSuffix:           OtherTypeEvents,
+	BloomFilterData.String() + writeEventSuffix:    OtherTypeEvents,
+	HyperLogLogData.String() + writeEventSuffix:    OtherTypeEvents,
+	CountMinSketchData.String() + writeEventSuffix: OtherTypeEvents,
+	VectorIndexData.String() + writeEventSuffix:    OtherTypeEvents,
+}
+
+// writeEvent returns the event type of writing value: set for strings and the
+// values of caches not holding typed values, the write event of its data type
+// otherwise.
+func writeEvent[V any](value V) string {
+	typed, ok := any(value).(interface{ Type() DataType })
+	if !ok || typed.Type() == StringData {
+		return SetEvent
+	}
+	return typed.Type().String() + writeEventSuffix
+}
+
+type KeyspaceEvent[K comparable] struct {
+	Type string
+	Key  K
+}
+
+// KeyspaceEvents is the bus of the events happening to the keys of a cache.
+// Events are delivered without blocking the cache: they are dropped for the
+// subscribers whose buffer is full.
+type KeyspaceEvents[K comparable] interface {
+	Subscribe(KeyspaceEventClass) (int64, <-chan KeyspaceEvent[K])
+	Unsubscribe(int64)
+	Emit(eventType string, keys ...K)
+}
+
+type keyspaceSubscriber[K comparable] struct {
+	classes KeyspaceEventClass
+	events  chan KeyspaceEvent[K]
+	dropped atomic.Int64
+}
+
+type keyspaceEvents[K comparable] struct {
+	mu          sync.RWMutex
+	subscribers map[int64]*keyspaceSubscriber[K]
+	// classes is the union of the classes subscribed to, so that emitting an
+	// event nobody listens to costs no locking
+	classes atomic.Uint32
+	nextID  int64
+	logger  Logger
+}
+
+func NewKeyspaceEvents[K comparable](logger Logger) KeyspaceEvents[K] {
+	return &keyspaceEvents[K]{
+		subscribers: make(map[int64]*keyspaceSubscriber[K]),
+		logger:      logger,
+	}
+}
+
+func (b *keyspaceEvents[K]) Subscribe(classes KeyspaceEventClass) (int64, <-chan KeyspaceEvent[K]) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.nextID++
+	subscriber := &keyspaceSubscriber[K]{
+		classes: classes,
+		events:  make(chan KeyspaceEvent[K], KeyspaceEventsBufferSize),
+	}
+	b.subscribers[b.nextID] = subscriber
+	b.updateClasses()
+	return b.nextID, subscriber.events
+}
+
+func (b *keyspaceEvents[K]) Unsubscribe(id int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	subscriber, ok := b.subscribers[id]
+	if !ok {
+		return
+	}
+	delete(b.subscribers, id)
+	close(subscriber.events)
+	b.updateClasses()
+	if dropped := subscriber.dropped.Load(); dropped > 0 {
+		b.logger.Info(fmt.Sprintf("[KEYSPACE_EVENT] Subscriber %d unsubscribed, %d events dropped", id, dropped))
+	}
+}
+
+// updateClasses must be called with the lock held.
+func (b *keyspaceEvents[K]) updateClasses() {
+	var classes KeyspaceEventClass
+	for _, subscriber := range b.subscribers {
+		classes |= subscriber.classes
+	}
+	b.classes.Store(uint32(classes))
+}
+
+// Emit sends an event for each key to the subscribers of its class. It may be
+// called with the lock of the cache held as it never blocks.
+func (b *keyspaceEvents[K]) Emit(eventType string, keys ...K) {
+	class := keyspaceEventClasses[eventType]
+	if KeyspaceEventClass(b.classes.Load())&class == 0 {
+		return
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, subscriber := range b.subscribers {
+		if subscriber.classes&class == 0 {
+			continue
+		}
+		for _, key := range keys {
+			select {
+			case subscriber.events <- KeyspaceEvent[K]{Type: eventType, Key: key}:
+			default:
+				subscriber.dropped.Add(1)
+			}
+		}
+	}
+}
+
+// KeyspaceNotifications selects the keyspace events published over pub/sub.
+// Events of the cache number n are published to `__keyspace@n__:<key>` with
+// the event type as message when Keyspace is set, and to
+// `__keyevent@n__:<event>` with the key as message when Keyevent is set.
+type KeyspaceNotifications struct {
+	Classes  KeyspaceEventClass
+	Keyspace bool
+	Keyevent bool
+}
+
+func (n KeyspaceNotifications) Enabled() bool {
+	return n.Classes != 0 && (n.Keyspace || n.Keyevent)
+}
+
+// ParseKeyspaceNotifications reads the notifications from flags: K for
+// keyspace channels, E for keyevent channels, g for generic events, $ for
+// string events, l, h, s, z and t for list, hash, set, sorted set and stream
+// events, d for the events of the other data types, x for expired events and A
+// for all the event classes. There is no eviction, so no evicted events.
+func ParseKeyspaceNotifications(flags string) (KeyspaceNotifications, error) {
+	var notifications KeyspaceNotifications
+	for _, flag := range flags {
+		switch flag {
+		case 'K':
+			notifications.Keyspace = true
+		case 'E':
+			notifications.Keyevent = true
+		case 'g':
+			notifications.Classes |= GenericEvents
+		case '$':
+			notifications.Classes |= StringEvents
+		case 'l':
+			notifications.Classes |= ListEvents
+		case 'h':
+			notifications.Classes |= HashEvents
+		case 's':
+			notifications.Classes |= SetEvents
+		case 'z':
+			notifications.Classes |= SortedSetEvents
+		case 't':
+			notifications.Classes |= StreamEvents
+		case 'd':
+			notifications.Classes |= OtherTypeEvents
+		case 'x':
+			notifications.Classes |= ExpiredEvents
+		case 'A':
+			notifications.Classes |= AllEvents
+		default:
+			return KeyspaceNotifications{}, fmt.Errorf("unknown keyspace notification flag: %c", flag)
+		}
+	}
+	return notifications, nil
+}
+
+// publishKeyspaceEvents forwards the events of a cache to pub/sub until the
+// subscription is closed.
+func publishKeyspaceEvents[K comparable](events <-chan KeyspaceEvent[K], pubSub PubSub, notifications KeyspaceNotifications, cacheNumber int) {
+	for event := range events {
+		key := fmt.Sprint(event.Key)
+		if notifications.Keyspace {
+			pubSub.Publish(fmt.Sprintf("__keyspace@%d__:%s", cacheNumber, key), event.Type)
+		}
+		if notifications.Keyevent {
+			pubSub.Publish(fmt.Sprintf("__keyevent@%d__:%s", cacheNumber, event.Type), key)
+		}
+	}
+}
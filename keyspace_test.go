@@ -0,0 +1,167 @@
+package main
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// expectEvents reads the next events of a subscription, failing on the ones
+// missing.
+func expectEvents(t *testing.T, name string, events <-chan KeyspaceEvent[string], expected ...KeyspaceEvent[string]) {
+	t.Helper()
+	for _, want := range expected {
+		select {
+		case event := <-events:
+			if event != want {
+				t.Errorf("%s: got event %+v, want %+v", name, event, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s: event %+v not sent", name, want)
+		}
+	}
+}
+
+func expectNoEvent(t *testing.T, name string, events <-chan KeyspaceEvent[string]) {
+	t.Helper()
+	select {
+	case event := <-events:
+		t.Errorf("%s: got unexpected event %+v", name, event)
+	default:
+	}
+}
+
+func TestKeyspaceEventsOfGenericCommands(t *testing.T) {
+	for name, cache := range newTestCaches(t) {
+		_, events := cache.Events().Subscribe(AllEvents)
+		cache.Set("k", "v", time.Time{})
+		cache.Expire("k", time.Now().Add(time.Hour))
+		cache.Persist("k")
+		cache.Delete("k")
+		// Failed commands send nothing
+		cache.Delete("k")
+		cache.Expire("k", time.Now().Add(time.Hour))
+		expectEvents(t, name, events,
+			KeyspaceEvent[string]{Type: SetEvent, Key: "k"},
+			KeyspaceEvent[string]{Type: ExpireEvent, Key: "k"},
+			KeyspaceEvent[string]{Type: PersistEvent, Key: "k"},
+			KeyspaceEvent[string]{Type: DelEvent, Key: "k"},
+		)
+		expectNoEvent(t, name, events)
+	}
+}
+
+func TestKeyspaceEventsOfClearExpired(t *testing.T) {
+	logger := NewLogger(io.Discard, "", 0)
+	config := CacheConfig{Precision: time.Millisecond, Records: TimingWheelRecords}
+	mainCache, err := NewCache[string, string](config, logger)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	syncCache, err := NewSyncCache[string, string](config, logger)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	for name, cache := range map[string]Cache[string, string]{"cache": mainCache, "syncCache": syncCache} {
+		_, events := cache.Events().Subscribe(ExpiredEvents)
+		cache.Set("k", "v", time.Now().Add(5*time.Millisecond))
+		time.Sleep(10 * time.Millisecond)
+		if cleared := cache.ClearExpired(); cleared != 1 {
+			t.Fatalf("%s: got %d keys cleared, want 1", name, cleared)
+		}
+		expectEvents(t, name, events, KeyspaceEvent[string]{Type: ExpiredEvent, Key: "k"})
+		expectNoEvent(t, name, events)
+	}
+}
+
+func TestKeyspaceEventsFilteredByClass(t *testing.T) {
+	for name, cache := range newTestCaches(t) {
+		_, genericEvents := cache.Events().Subscribe(GenericEvents)
+		_, stringEvents := cache.Events().Subscribe(StringEvents)
+		cache.Set("k", "v", time.Time{})
+		cache.Delete("k")
+		expectEvents(t, name, genericEvents, KeyspaceEvent[string]{Type: DelEvent, Key: "k"})
+		expectNoEvent(t, name, genericEvents)
+		expectEvents(t, name, stringEvents, KeyspaceEvent[string]{Type: SetEvent, Key: "k"})
+		expectNoEvent(t, name, stringEvents)
+	}
+}
+
+// TestPushEmitsListEvents checks that list writes are not sent to the
+// subscribers of string events.
+func TestPushEmitsListEvents(t *testing.T) {
+	cache := newTestCache(t)
+	_, events := cache.Events().Subscribe(StringEvents | ListEvents)
+	_, stringEvents := cache.Events().Subscribe(StringEvents)
+	lists := NewLists(cache)
+	for range 2 {
+		if _, err := lists.Push("eventq", []string{"x"}, true); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+	cache.Set("eventk", StringValue("x"), time.Time{})
+
+	expectEvents(t, "cache", events,
+		KeyspaceEvent[string]{Type: "list.write", Key: "eventq"},
+		KeyspaceEvent[string]{Type: "list.write", Key: "eventq"},
+		KeyspaceEvent[string]{Type: SetEvent, Key: "eventk"},
+	)
+	expectEvents(t, "cache", stringEvents, KeyspaceEvent[string]{Type: SetEvent, Key: "eventk"})
+	expectNoEvent(t, "cache", stringEvents)
+}
+
+func TestPublishKeyspaceEvents(t *testing.T) {
+	pubSub, err := NewPubSub(16, DropForSlowConsumers, NewLogger(io.Discard, "", 0))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	subscriber := pubSub.Register(newTestConnection())
+	subscriber.PSubscribe("__key*")
+
+	events := make(chan KeyspaceEvent[string], 1)
+	events <- KeyspaceEvent[string]{Type: ExpiredEvent, Key: "k"}
+	close(events)
+	notifications := KeyspaceNotifications{Classes: AllEvents, Keyspace: true, Keyevent: true}
+	publishKeyspaceEvents(events, pubSub, notifications, 1)
+
+	for _, want := range []PubSubMessage{
+		{Pattern: "__key*", Channel: "__keyspace@1__:k", Message: ExpiredEvent},
+		{Pattern: "__key*", Channel: "__keyevent@1__:expired", Message: "k"},
+	} {
+		if message := <-subscriber.Messages(); message != want {
+			t.Errorf("got message %+v, want %+v", message, want)
+		}
+	}
+}
+
+func TestParseKeyspaceNotifications(t *testing.T) {
+	for _, test := range []struct {
+		flags string
+		want  KeyspaceNotifications
+	}{
+		{"", KeyspaceNotifications{}},
+		{"KEA", KeyspaceNotifications{Classes: AllEvents, Keyspace: true, Keyevent: true}},
+		{"Kg$", KeyspaceNotifications{Classes: GenericEvents | StringEvents, Keyspace: true}},
+		{"Elhszt", KeyspaceNotifications{Classes: ListEvents | HashEvents | SetEvents | SortedSetEvents | StreamEvents, Keyevent: true}},
+		{"Exd", KeyspaceNotifications{Classes: ExpiredEvents | OtherTypeEvents, Keyevent: true}},
+	} {
+		notifications, err := ParseKeyspaceNotifications(test.flags)
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", test.flags, err)
+		} else if notifications != test.want {
+			t.Errorf("%q: got %+v, want %+v", test.flags, notifications, test.want)
+		}
+	}
+	for _, flags := range []string{"g", "K"} {
+		if notifications, _ := ParseKeyspaceNotifications(flags); notifications.Enabled() {
+			t.Errorf("%q: notifications enabled without channels or events", flags)
+		}
+	}
+	// There is no eviction, so no evicted events to select
+	for _, flags := range []string{"Ke", "K?"} {
+		if _, err := ParseKeyspaceNotifications(flags); err == nil || !strings.Contains(err.Error(), "flag") {
+			t.Errorf("%q: got error %v, want an unknown flag", flags, err)
+		}
+	}
+}
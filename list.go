@@ -0,0 +1,131 @@
+package main
+
+const minListCapacity = 8
+
+// List is a double-ended queue kept in a ring buffer, so that elements are
+// pushed and popped at both ends in constant time.
+type List[V any] struct {
+	items []V
+	head  int
+	size  int
+}
+
+func NewList[V any]() *List[V] {
+	return &List[V]{items: make([]V, minListCapacity)}
+}
+
+func (l *List[V]) Len() int {
+	return l.size
+}
+
+// index returns the position in the buffer of the i-th element.
+func (l *List[V]) index(i int) int {
+	return (l.head + i) % len(l.items)
+}
+
+func (l *List[V]) Get(i int) V {
+	return l.items[l.index(i)]
+}
+
+func (l *List[V]) resize(capacity int) {
+	items := make([]V, capacity)
+	for i := 0; i < l.size; i++ {
+		items[i] = l.Get(i)
+	}
+	l.items = items
+	l.head = 0
+}
+
+func (l *List[V]) PushFront(value V) {
+	if l.size == len(l.items) {
+		l.resize(2 * len(l.items))
+	}
+	l.head = (l.head - 1 + len(l.items)) % len(l.items)
+	l.items[l.head] = value
+	l.size++
+}
+
+func (l *List[V]) PushBack(value V) {
+	if l.size == len(l.items) {
+		l.resize(2 * len(l.items))
+	}
+	l.items[l.index(l.size)] = value
+	l.size++
+}
+
+func (l *List[V]) PopFront() (V, bool) {
+	var zero V
+	if l.size == 0 {
+		return zero, false
+	}
+	value := l.items[l.head]
+	l.items[l.head] = zero
+	l.head = l.index(1)
+	l.size--
+	l.shrink()
+	return value, true
+}
+
+func (l *List[V]) PopBack() (V, bool) {
+	var zero V
+	if l.size == 0 {
+		return zero, false
+	}
+	last := l.index(l.size - 1)
+	value := l.items[last]
+	l.items[last] = zero
+	l.size--
+	l.shrink()
+	return value, true
+}
+
+// shrink gives memory back once the list uses a quarter of its buffer.
+func (l *List[V]) shrink() {
+	if len(l.items) > minListCapacity && l.size <= len(l.items)/4 {
+		l.resize(max(len(l.items)/2, minListCapacity))
+	}
+}
+
+// bounds converts start and stop, which count from the end when negative, to
+// the range [start, stop) of the elements they include. The range is empty
+// when they do not overlap the list.
+func (l *List[V]) bounds(start, stop int) (int, int) {
+	if start < 0 {
+		start = max(l.size+start, 0)
+	}
+	if stop < 0 {
+		stop = l.size + stop
+	}
+	stop = min(stop+1, l.size)
+	if start >= stop {
+		return 0, 0
+	}
+	return start, stop
+}
+
+// Range returns the elements from start to stop included, negative indexes
+// counting from the end of the list.
+func (l *List[V]) Range(start, stop int) []V {
+	start, stop = l.bounds(start, stop)
+	values := make([]V, 0, stop-start)
+	for i := start; i < stop; i++ {
+		values = append(values, l.Get(i))
+	}
+	return values
+}
+
+// Trim only keeps the elements from start to stop included, negative indexes
+// counting from the end of the list.
+func (l *List[V]) Trim(start, stop int) {
+	start, stop = l.bounds(start, stop)
+	var zero V
+	for i := stop; i < l.size; i++ {
+		l.items[l.index(i)] = zero
+	}
+	for i := 0; i < start; i++ {
+		l.items[l.index(i)] = zero
+	}
+	l.head = l.index(start)
+	l.size = stop - start
+	l.shrink()
+}
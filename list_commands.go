@@ -0,0 +1,419 @@
+package main
+
+import (
+	"math"
+	"strconv"
+	"time"
+)
+
+// Lists holds the lists of the cache, along with the clients blocked until an
+// element is pushed to one of them.
+type Lists interface {
+	Push(key string, elements []string, front bool) (int, Error)
+	Pop(key string, count int, front bool) ([]string, bool, Error)
+	Range(key string, start, stop int) ([]string, Error)
+	Len(key string) (int, Error)
+	Trim(key string, start, stop int) Error
+	Wait(keys []string) *keyWaiter
+	StopWaiting(*keyWaiter)
+}
+
+type lists struct {
+	*keyWaiters
+	cache Cache[string, Value]
+}
+
+func NewLists(cache Cache[string, Value]) Lists {
+	return &lists{
+		keyWaiters: newKeyWaiters(),
+		cache:      cache,
+	}
+}
+
+// Push adds the elements one after the other at the front or the back of the
+// list, which is created when needed, and returns its new length.
+func (l *lists) Push(key string, elements []string, front bool) (int, Error) {
+	list, err := computeAs(l.cache, key, ListData, func(current **List[string]) (*List[string], ComputeAction, Error) {
+		list := NewList[string]()
+		if current != nil {
+			list = *current
+		}
+		for _, element := range elements {
+			if front {
+				list.PushFront(element)
+			} else {
+				list.PushBack(element)
+			}
+		}
+		return list, StoreValue, nil
+	}, time.Time{})
+	if err != nil {
+		return 0, err
+	}
+	length := list.Len()
+	l.signal(key)
+	return length, nil
+}
+
+// Pop removes up to count elements from the front or the back of the list,
+// which is deleted once empty. It returns false when the list does not exist.
+func (l *lists) Pop(key string, count int, front bool) ([]string, bool, Error) {
+	var elements []string
+	exists := false
+	_, err := computeAs(l.cache, key, ListData, func(current **List[string]) (*List[string], ComputeAction, Error) {
+		if current == nil {
+			return nil, KeepValue, nil
+		}
+		exists = true
+		list := *current
+		for len(elements) < count {
+			var element string
+			var ok bool
+			if front {
+				element, ok = list.PopFront()
+			} else {
+				element, ok = list.PopBack()
+			}
+			if !ok {
+				break
+			}
+			elements = append(elements, element)
+		}
+		if len(elements) == 0 {
+			return list, KeepValue, nil
+		}
+		if list.Len() == 0 {
+			return list, DeleteValue, nil
+		}
+		return list, StoreValue, nil
+	}, time.Time{})
+	return elements, exists, err
+}
+
+func (l *lists) Range(key string, start, stop int) ([]string, Error) {
+	var elements []string
+	_, err := computeAs(l.cache, key, ListData, func(current **List[string]) (*List[string], ComputeAction, Error) {
+		if current != nil {
+			elements = (*current).Range(start, stop)
+		}
+		return nil, KeepValue, nil
+	}, time.Time{})
+	return elements, err
+}
+
+func (l *lists) Len(key string) (int, Error) {
+	length := 0
+	_, err := computeAs(l.cache, key, ListData, func(current **List[string]) (*List[string], ComputeAction, Error) {
+		if current != nil {
+			length = (*current).Len()
+		}
+		return nil, KeepValue, nil
+	}, time.Time{})
+	return length, err
+}
+
+// Trim only keeps the elements from start to stop included, the list is
+// deleted when none is left.
+func (l *lists) Trim(key string, start, stop int) Error {
+	_, err := computeAs(l.cache, key, ListData, func(current **List[string]) (*List[string], ComputeAction, Error) {
+		if current == nil {
+			return nil, KeepValue, nil
+		}
+		list := *current
+		list.Trim(start, stop)
+		if list.Len() == 0 {
+			return list, DeleteValue, nil
+		}
+		return list, StoreValue, nil
+	}, time.Time{})
+	return err
+}
+
+func elementsResult(elements []string) Result[Value] {
+	items := make([]Result[Value], len(elements))
+	for i, element := range elements {
+		items[i] = &valueResult[Value]{value: StringValue(element)}
+	}
+	return &arrayResult[Value]{items: items}
+}
+
+type pushCommand struct {
+	Command
+	lists Lists
+	front bool
+}
+
+func NewLPushCommand(lists Lists) ExecutableCommand[string, Value] {
+	return &pushCommand{
+		Command: NewCommand("LPUSH").
+			WithArgument(KeyCommandArgument).
+			WithArgument(ElementsArgument),
+		lists: lists,
+		front: true,
+	}
+}
+
+func NewRPushCommand(lists Lists) ExecutableCommand[string, Value] {
+	return &pushCommand{
+		Command: NewCommand("RPUSH").
+			WithArgument(KeyCommandArgument).
+			WithArgument(ElementsArgument),
+		lists: lists,
+	}
+}
+
+// Run pushes the elements in order, LPUSH a b c leaves c at the front.
+func (c *pushCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	elements := toStrings(input.GetArgument(*ElementsArgument).([]any))
+	length, err := c.lists.Push(key, elements, c.front)
+	if err != nil {
+		return nil, err
+	}
+	return &integerResult[Value]{value: int64(length)}, nil
+}
+
+type popCommand struct {
+	Command
+	lists Lists
+	front bool
+}
+
+func NewLPopCommand(lists Lists) ExecutableCommand[string, Value] {
+	return &popCommand{
+		Command: NewCommand("LPOP").
+			WithArgument(KeyCommandArgument).
+			WithArgument(CountArgument),
+		lists: lists,
+		front: true,
+	}
+}
+
+func NewRPopCommand(lists Lists) ExecutableCommand[string, Value] {
+	return &popCommand{
+		Command: NewCommand("RPOP").
+			WithArgument(KeyCommandArgument).
+			WithArgument(CountArgument),
+		lists: lists,
+	}
+}
+
+// Run replies the element popped, or an array of up to count elements when a
+// count is given.
+func (c *popCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	count, hasCount := input.GetArgument(*CountArgument).(int)
+	if !hasCount {
+		count = 1
+	}
+	if count < 0 {
+		return nil, &InvalidCommandUsageError{command: c.GetName()}
+	}
+	elements, ok, err := c.lists.Pop(key, count, c.front)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return &nilResult[Value]{}, nil
+	}
+	if hasCount {
+		return elementsResult(elements), nil
+	}
+	return &valueResult[Value]{value: StringValue(elements[0])}, nil
+}
+
+type lrangeCommand struct {
+	Command
+	lists Lists
+}
+
+func NewLRangeCommand(lists Lists) ExecutableCommand[string, Value] {
+	return &lrangeCommand{
+		Command: NewCommand("LRANGE").
+			WithArgument(KeyCommandArgument).
+			WithArgument(StartArgument).
+			WithArgument(StopArgument),
+		lists: lists,
+	}
+}
+
+func (c *lrangeCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	start := input.GetArgument(*StartArgument).(int)
+	stop := input.GetArgument(*StopArgument).(int)
+	elements, err := c.lists.Range(key, start, stop)
+	if err != nil {
+		return nil, err
+	}
+	return elementsResult(elements), nil
+}
+
+type llenCommand struct {
+	Command
+	lists Lists
+}
+
+func NewLLenCommand(lists Lists) ExecutableCommand[string, Value] {
+	return &llenCommand{
+		Command: NewCommand("LLEN").
+			WithArgument(KeyCommandArgument),
+		lists: lists,
+	}
+}
+
+func (c *llenCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	length, err := c.lists.Len(key)
+	if err != nil {
+		return nil, err
+	}
+	return &integerResult[Value]{value: int64(length)}, nil
+}
+
+type ltrimCommand struct {
+	Command
+	lists Lists
+}
+
+func NewLTrimCommand(lists Lists) ExecutableCommand[string, Value] {
+	return &ltrimCommand{
+		Command: NewCommand("LTRIM").
+			WithArgument(KeyCommandArgument).
+			WithArgument(StartArgument).
+			WithArgument(StopArgument),
+		lists: lists,
+	}
+}
+
+func (c *ltrimCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	start := input.GetArgument(*StartArgument).(int)
+	stop := input.GetArgument(*StopArgument).(int)
+	if err := c.lists.Trim(key, start, stop); err != nil {
+		return nil, err
+	}
+	return &okResult[Value]{}, nil
+}
+
+// blockingPopCommand pops from the first of the keys having an element, or
+// waits for one to be pushed. Waiting happens once the command has given its
+// execution slot back, so blocked clients do not hold up the workers.
+type blockingPopCommand struct {
+	Command
+	lists Lists
+	front bool
+}
+
+func NewBLPopCommand(lists Lists) ExecutableCommand[string, Value] {
+	return &blockingPopCommand{
+		Command: NewCommand("BLPOP").
+			WithArgument(KeysTimeoutArgument),
+		lists: lists,
+		front: true,
+	}
+}
+
+func NewBRPopCommand(lists Lists) ExecutableCommand[string, Value] {
+	return &blockingPopCommand{
+		Command: NewCommand("BRPOP").
+			WithArgument(KeysTimeoutArgument),
+		lists: lists,
+	}
+}
+
+func (c *blockingPopCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	tokens := toStrings(input.GetArgument(*KeysTimeoutArgument).([]any))
+	if len(tokens) < 2 {
+		return nil, &InvalidCommandUsageError{command: c.GetName()}
+	}
+	keys := tokens[:len(tokens)-1]
+	seconds, err := strconv.ParseFloat(tokens[len(tokens)-1], 64)
+	if err != nil || seconds < 0 || math.IsInf(seconds, 0) || seconds > math.MaxInt64/float64(time.Second) {
+		return nil, &InvalidCommandUsageError{command: c.GetName()}
+	}
+	result := &blockingPopResult{
+		lists:   c.lists,
+		keys:    keys,
+		timeout: time.Duration(seconds * float64(time.Second)),
+		front:   c.front,
+	}
+	if _, err := result.pop(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// blockingPopResult replies the key and the element popped. When the lists
+// were empty at execution it sends the reply once an element arrives, or nil
+// after the timeout. Inside a transaction it does not block and replies nil.
+// A key holding another type meanwhile ends the wait with its error.
+type blockingPopResult struct {
+	lists   Lists
+	keys    []string
+	timeout time.Duration
+	front   bool
+	key     string
+	element string
+	popped  bool
+}
+
+func (r *blockingPopResult) pop() (bool, Error) {
+	for _, key := range r.keys {
+		elements, _, err := r.lists.Pop(key, 1, r.front)
+		if err != nil {
+			return false, err
+		}
+		if len(elements) > 0 {
+			r.key, r.element, r.popped = key, elements[0], true
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *blockingPopResult) String() string {
+	if !r.popped {
+		return (&nilResult[Value]{}).String()
+	}
+	return (&arrayResult[Value]{items: []Result[Value]{
+		&textResult[Value]{text: strconv.Quote(r.key)},
+		&valueResult[Value]{value: StringValue(r.element)},
+	}}).String()
+}
+
+func (r *blockingPopResult) Block(connection Connection, execute func(func())) bool {
+	if r.popped {
+		return reply(connection, r.String()) == nil
+	}
+	waiter := r.lists.Wait(r.keys)
+	defer r.lists.StopWaiting(waiter)
+	closed, stopWatching := connection.WatchClosed()
+	defer stopWatching()
+	var timeout <-chan time.Time
+	if r.timeout > 0 {
+		timer := time.NewTimer(r.timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	for {
+		var popped bool
+		var err Error
+		execute(func() {
+			popped, err = r.pop()
+		})
+		if err != nil {
+			return reply(connection, err.Display()) == nil
+		}
+		if popped {
+			break
+		}
+		select {
+		case <-waiter.wake:
+		case <-timeout:
+			return reply(connection, r.String()) == nil
+		case <-closed:
+			return false
+		}
+	}
+	return reply(connection, r.String()) == nil
+}
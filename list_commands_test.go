@@ -0,0 +1,89 @@
+package main
+
+import (
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testConnection is a connection whose replies are sent to a channel.
+type testConnection struct {
+	replies chan string
+	closed  chan struct{}
+}
+
+func newTestConnection() *testConnection {
+	return &testConnection{replies: make(chan string, 16), closed: make(chan struct{})}
+}
+
+func (c *testConnection) Read() (string, Error) {
+	<-c.closed
+	return "", nil
+}
+
+func (c *testConnection) Send(output string) Error {
+	c.replies <- output
+	return nil
+}
+
+func (c *testConnection) Close() Error {
+	close(c.closed)
+	return nil
+}
+
+func (c *testConnection) RemoteAddr() net.Addr {
+	return &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)}
+}
+
+func (c *testConnection) ID() int64                                  { return 1 }
+func (c *testConnection) Name() string                               { return "" }
+func (c *testConnection) SetName(string)                             {}
+func (c *testConnection) SetLastCommand(string)                      {}
+func (c *testConnection) SetIdleTimeout(time.Duration) time.Duration { return 0 }
+func (c *testConnection) WatchClosed() (<-chan struct{}, func())     { return c.closed, func() {} }
+func (c *testConnection) Info() ConnectionInfo                       { return ConnectionInfo{} }
+
+func newTestCache(t testing.TB) Cache[string, Value] {
+	cache, err := NewCache[string, Value](CacheConfig{Precision: time.Minute}, NewLogger(io.Discard, "", 0))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	return cache
+}
+
+// TestBlockingPopWaitsForTransactions checks that a blocked pop woken up by a
+// push does not pop in the middle of the transaction that pushed.
+func TestBlockingPopWaitsForTransactions(t *testing.T) {
+	lists := NewLists(newTestCache(t))
+	executor := &executor[string, Value]{}
+	result := &blockingPopResult{lists: lists, keys: []string{"raceq"}, front: true}
+	connection := newTestConnection()
+	done := make(chan bool)
+	go func() {
+		done <- result.Block(connection, executor.ExecuteFunc)
+	}()
+
+	executor.exclusive.Lock()
+	if _, err := lists.Push("raceq", []string{"x"}, false); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	for range 10 {
+		time.Sleep(5 * time.Millisecond)
+		if n, _ := lists.Len("raceq"); n != 1 {
+			t.Fatalf("list length changed to %d during the transaction", n)
+		}
+	}
+	executor.exclusive.Unlock()
+
+	if !<-done {
+		t.Fatalf("connection not usable after the pop")
+	}
+	if n, _ := lists.Len("raceq"); n != 0 {
+		t.Errorf("got list length %d after the pop, want 0", n)
+	}
+	if reply := <-connection.replies; !strings.HasPrefix(reply, result.String()) || result.element != "x" {
+		t.Errorf("unexpected reply %q", reply)
+	}
+}
@@ -24,6 +24,96 @@ func main() {
 		log.Fatal("Error during reading CACHER_USE_SYNC_CACHE variable from env: ", err)
 	}
 
+	slowLogThreshold, err := getEnvInt("CACHER_SLOWLOG_THRESHOLD", 10000)
+	if err != nil {
+		log.Fatal("Error during reading CACHER_SLOWLOG_THRESHOLD variable from env: ", err)
+	}
+
+	slowLogMaxLen, err := getEnvInt("CACHER_SLOWLOG_MAX_LEN", 128)
+	if err != nil {
+		log.Fatal("Error during reading CACHER_SLOWLOG_MAX_LEN variable from env: ", err)
+	}
+
+	readTimeout, err := getEnvDuration("CACHER_READ_TIMEOUT", 30*time.Second)
+	if err != nil {
+		log.Fatal("Error during reading CACHER_READ_TIMEOUT variable from env: ", err)
+	}
+
+	writeTimeout, err := getEnvDuration("CACHER_WRITE_TIMEOUT", 30*time.Second)
+	if err != nil {
+		log.Fatal("Error during reading CACHER_WRITE_TIMEOUT variable from env: ", err)
+	}
+
+	idleTimeout, err := getEnvDuration("CACHER_IDLE_TIMEOUT", 5*time.Minute)
+	if err != nil {
+		log.Fatal("Error during reading CACHER_IDLE_TIMEOUT variable from env: ", err)
+	}
+
+	maxConnections, err := getEnvInt("CACHER_MAX_CONNECTIONS", 10000)
+	if err != nil {
+		log.Fatal("Error during reading CACHER_MAX_CONNECTIONS variable from env: ", err)
+	}
+
+	maxConnectionsPerIP, err := getEnvInt("CACHER_MAX_CONNECTIONS_PER_IP", 0)
+	if err != nil {
+		log.Fatal("Error during reading CACHER_MAX_CONNECTIONS_PER_IP variable from env: ", err)
+	}
+
+	pubSubBufferSize, err := getEnvInt("CACHER_PUBSUB_BUFFER_SIZE", 1024)
+	if err != nil {
+		log.Fatal("Error during reading CACHER_PUBSUB_BUFFER_SIZE variable from env: ", err)
+	}
+
+	pubSubSlowConsumerPolicy, err := ParseSlowConsumerPolicy(getEnvString("CACHER_PUBSUB_SLOW_CONSUMER_POLICY", "disconnect"))
+	if err != nil {
+		log.Fatal("Error during reading CACHER_PUBSUB_SLOW_CONSUMER_POLICY variable from env: ", err)
+	}
+
+	keyspaceNotifications, err := ParseKeyspaceNotifications(getEnvString("CACHER_KEYSPACE_NOTIFICATIONS", ""))
+	if err != nil {
+		log.Fatal("Error during reading CACHER_KEYSPACE_NOTIFICATIONS variable from env: ", err)
+	}
+
+	mainCachePrecision, err := getEnvDuration("CACHER_MAIN_CACHE_PRECISION", time.Minute)
+	if err != nil {
+		log.Fatal("Error during reading CACHER_MAIN_CACHE_PRECISION variable from env: ", err)
+	}
+
+	mainCacheRecords, err := ParseRecordsKind(getEnvString("CACHER_MAIN_CACHE_RECORDS", "ordered"))
+	if err != nil {
+		log.Fatal("Error during reading CACHER_MAIN_CACHE_RECORDS variable from env: ", err)
+	}
+
+	mainCacheIdleTimeout, err := getEnvDuration("CACHER_MAIN_CACHE_IDLE_TIMEOUT", 0)
+	if err != nil {
+		log.Fatal("Error during reading CACHER_MAIN_CACHE_IDLE_TIMEOUT variable from env: ", err)
+	}
+
+	mainCacheMaxLifetime, err := getEnvDuration("CACHER_MAIN_CACHE_MAX_LIFETIME", 0)
+	if err != nil {
+		log.Fatal("Error during reading CACHER_MAIN_CACHE_MAX_LIFETIME variable from env: ", err)
+	}
+
+	syncCachePrecision, err := getEnvDuration("CACHER_SYNC_CACHE_PRECISION", time.Minute*5)
+	if err != nil {
+		log.Fatal("Error during reading CACHER_SYNC_CACHE_PRECISION variable from env: ", err)
+	}
+
+	syncCacheRecords, err := ParseRecordsKind(getEnvString("CACHER_SYNC_CACHE_RECORDS", "ordered"))
+	if err != nil {
+		log.Fatal("Error during reading CACHER_SYNC_CACHE_RECORDS variable from env: ", err)
+	}
+
+	syncCacheIdleTimeout, err := getEnvDuration("CACHER_SYNC_CACHE_IDLE_TIMEOUT", 0)
+	if err != nil {
+		log.Fatal("Error during reading CACHER_SYNC_CACHE_IDLE_TIMEOUT variable from env: ", err)
+	}
+
+	syncCacheMaxLifetime, err := getEnvDuration("CACHER_SYNC_CACHE_MAX_LIFETIME", 0)
+	if err != nil {
+		log.Fatal("Error during reading CACHER_SYNC_CACHE_MAX_LIFETIME variable from env: ", err)
+	}
+
 	logFilePath := "server.log"
 	logPrefix := "- "
 	logFile, err := os.OpenFile(logFilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
@@ -32,10 +122,16 @@ func main() {
 	}
 	logger := NewLogger(logFile, logPrefix, log.Ldate|log.Ltime)
 
-	commandManager := NewCommandManager()
-	cacheManager := NewCacheManager[string, string](logger)
+	cacheManager := NewCacheManager[string, Value](logger)
 
-	err = cacheManager.SetupMainCache(time.Minute)
+	err = cacheManager.SetupMainCache(CacheConfig{
+		Precision: mainCachePrecision,
+		Records:   mainCacheRecords,
+		IdlePolicy: IdlePolicy{
+			IdleTimeout: mainCacheIdleTimeout,
+			MaxLifetime: mainCacheMaxLifetime,
+		},
+	})
 	if err != nil {
 		log.Fatal("Error setting up main cache: ", err)
 	}
@@ -46,7 +142,14 @@ func main() {
 	}
 
 	if useSyncCache {
-		err = cacheManager.SetupSyncCache(time.Minute * 5)
+		err = cacheManager.SetupSyncCache(CacheConfig{
+			Precision: syncCachePrecision,
+			Records:   syncCacheRecords,
+			IdlePolicy: IdlePolicy{
+				IdleTimeout: syncCacheIdleTimeout,
+				MaxLifetime: syncCacheMaxLifetime,
+			},
+		})
 		if err != nil {
 			log.Fatal("Error setting up sync cache: ", err)
 		}
@@ -57,10 +160,138 @@ func main() {
 		}
 	}
 
-	server, err := NewServer(port, nbrWorkers, logger, commandManager, cacheManager)
+	// Every data type is stored in the main cache, where keys are tagged with
+	// the type of their value
+	mainCache := cacheManager.Get(false)
+	lists := NewLists(mainCache)
+	hashes := NewHashes(mainCache)
+	sets := NewSets(mainCache)
+	sortedSets := NewSortedSets(mainCache)
+	jsonDocuments := NewJSONDocuments(mainCache)
+	bloomFilters := NewBloomFilters(mainCache)
+	hyperLogLogs := NewHyperLogLogs(mainCache)
+	countMinSketches := NewCountMinSketches(mainCache)
+	streams := NewStreams(mainCache)
+	geoSets := NewGeoSets(mainCache)
+	vectorIndexes := NewVectorIndexes(mainCache)
+
+	commandManager := NewCommandManager().
+		AddCommand("SET", NewSetCommand()).
+		AddCommand("SETNX", NewSetNXCommand()).
+		AddCommand("GET", NewGetCommand()).
+		AddCommand("GETS", NewGetsCommand()).
+		AddCommand("CAS", NewCasCommand()).
+		AddCommand("MGET", NewMGetCommand()).
+		AddCommand("MSET", NewMSetCommand()).
+		AddCommand("DEL", NewDelCommand[Value]()).
+		AddCommand("MDEL", NewMDelCommand[Value]()).
+		AddCommand("FLUSH", NewFlushCommand[Value]()).
+		AddCommand("KEYS", NewKeysCommand[Value]()).
+		AddCommand("SCAN", NewScanCommand[Value]()).
+		AddCommand("WATCH", NewWatchCommand[Value]()).
+		AddCommand("EXPIRE", NewExpireCommand[Value]()).
+		AddCommand("PEXPIRE", NewPExpireCommand[Value]()).
+		AddCommand("EXPIREAT", NewExpireAtCommand[Value]()).
+		AddCommand("TTL", NewTTLCommand[Value]()).
+		AddCommand("PTTL", NewPTTLCommand[Value]()).
+		AddCommand("PERSIST", NewPersistCommand[Value]()).
+		AddCommand("TYPE", NewTypeCommand()).
+		AddCommand("INCR", NewIncrCommand()).
+		AddCommand("DECR", NewDecrCommand()).
+		AddCommand("INCRBY", NewIncrByCommand()).
+		AddCommand("DECRBY", NewDecrByCommand()).
+		AddCommand("INCRBYFLOAT", NewIncrByFloatCommand()).
+		AddCommand("LPUSH", NewLPushCommand(lists)).
+		AddCommand("RPUSH", NewRPushCommand(lists)).
+		AddCommand("LPOP", NewLPopCommand(lists)).
+		AddCommand("RPOP", NewRPopCommand(lists)).
+		AddCommand("LRANGE", NewLRangeCommand(lists)).
+		AddCommand("LLEN", NewLLenCommand(lists)).
+		AddCommand("LTRIM", NewLTrimCommand(lists)).
+		AddCommand("BLPOP", NewBLPopCommand(lists)).
+		AddCommand("BRPOP", NewBRPopCommand(lists)).
+		AddCommand("HSET", NewHSetCommand(hashes)).
+		AddCommand("HGET", NewHGetCommand(hashes)).
+		AddCommand("HMGET", NewHMGetCommand(hashes)).
+		AddCommand("HDEL", NewHDelCommand(hashes)).
+		AddCommand("HGETALL", NewHGetAllCommand(hashes)).
+		AddCommand("HINCRBY", NewHIncrByCommand(hashes)).
+		AddCommand("HLEN", NewHLenCommand(hashes)).
+		AddCommand("HEXPIRE", NewHExpireCommand(hashes)).
+		AddCommand("HPEXPIRE", NewHPExpireCommand(hashes)).
+		AddCommand("HTTL", NewHTTLCommand(hashes)).
+		AddCommand("HPTTL", NewHPTTLCommand(hashes)).
+		AddCommand("SADD", NewSAddCommand(sets)).
+		AddCommand("SREM", NewSRemCommand(sets)).
+		AddCommand("SMEMBERS", NewSMembersCommand(sets)).
+		AddCommand("SISMEMBER", NewSIsMemberCommand(sets)).
+		AddCommand("SINTER", NewSInterCommand(sets)).
+		AddCommand("SUNION", NewSUnionCommand(sets)).
+		AddCommand("SDIFF", NewSDiffCommand(sets)).
+		AddCommand("ZADD", NewZAddCommand(sortedSets)).
+		AddCommand("ZINCRBY", NewZIncrByCommand(sortedSets)).
+		AddCommand("ZREM", NewZRemCommand(sortedSets)).
+		AddCommand("ZRANGE", NewZRangeCommand(sortedSets)).
+		AddCommand("ZRANGEBYSCORE", NewZRangeByScoreCommand(sortedSets)).
+		AddCommand("ZRANK", NewZRankCommand(sortedSets)).
+		AddCommand("JSON.SET", NewJSONSetCommand(jsonDocuments)).
+		AddCommand("JSON.GET", NewJSONGetCommand(jsonDocuments)).
+		AddCommand("JSON.DEL", NewJSONDelCommand(jsonDocuments)).
+		AddCommand("JSON.TYPE", NewJSONTypeCommand(jsonDocuments)).
+		AddCommand("JSON.NUMINCRBY", NewJSONNumIncrByCommand(jsonDocuments)).
+		AddCommand("JSON.ARRAPPEND", NewJSONArrAppendCommand(jsonDocuments)).
+		AddCommand("BF.RESERVE", NewBFReserveCommand(bloomFilters)).
+		AddCommand("BF.ADD", NewBFAddCommand(bloomFilters)).
+		AddCommand("BF.EXISTS", NewBFExistsCommand(bloomFilters)).
+		AddCommand("PFADD", NewPFAddCommand(hyperLogLogs)).
+		AddCommand("PFCOUNT", NewPFCountCommand(hyperLogLogs)).
+		AddCommand("PFMERGE", NewPFMergeCommand(hyperLogLogs)).
+		AddCommand("CMS.INITBYDIM", NewCMSInitByDimCommand(countMinSketches)).
+		AddCommand("CMS.INITBYPROB", NewCMSInitByProbCommand(countMinSketches)).
+		AddCommand("CMS.INCRBY", NewCMSIncrByCommand(countMinSketches)).
+		AddCommand("CMS.QUERY", NewCMSQueryCommand(countMinSketches)).
+		AddCommand("XADD", NewXAddCommand(streams)).
+		AddCommand("XLEN", NewXLenCommand(streams)).
+		AddCommand("XRANGE", NewXRangeCommand(streams)).
+		AddCommand("XTRIM", NewXTrimCommand(streams)).
+		AddCommand("XREAD", NewXReadCommand(streams)).
+		AddCommand("XREADGROUP", NewXReadGroupCommand(streams)).
+		AddCommand("XACK", NewXAckCommand(streams)).
+		AddCommand("XPENDING", NewXPendingCommand(streams)).
+		AddCommand("XCLAIM", NewXClaimCommand(streams)).
+		AddCommand("XAUTOCLAIM", NewXAutoClaimCommand(streams)).
+		AddCommand("XGROUP", NewXGroupCommand(streams)).
+		AddCommand("GEOADD", NewGeoAddCommand(geoSets)).
+		AddCommand("GEOPOS", NewGeoPosCommand(geoSets)).
+		AddCommand("GEODIST", NewGeoDistCommand(geoSets)).
+		AddCommand("GEOSEARCH", NewGeoSearchCommand(geoSets)).
+		AddCommand("VEC.CREATE", NewVecCreateCommand(vectorIndexes)).
+		AddCommand("VEC.ADD", NewVecAddCommand(vectorIndexes)).
+		AddCommand("VEC.REM", NewVecRemCommand(vectorIndexes)).
+		AddCommand("VEC.SEARCH", NewVecSearchCommand(vectorIndexes))
+
+	config := &ServerConfig{
+		port:             port,
+		nbrWorkers:       nbrWorkers,
+		slowLogThreshold: time.Duration(slowLogThreshold) * time.Microsecond,
+		slowLogMaxLen:    slowLogMaxLen,
+		timeouts: ConnectionTimeouts{
+			Read:  readTimeout,
+			Write: writeTimeout,
+			Idle:  idleTimeout,
+		},
+		maxConnections:           maxConnections,
+		maxConnectionsPerIP:      maxConnectionsPerIP,
+		pubSubBufferSize:         pubSubBufferSize,
+		pubSubSlowConsumerPolicy: pubSubSlowConsumerPolicy,
+		keyspaceNotifications:    keyspaceNotifications,
+	}
+	server, err := NewServer(config, logger, commandManager, cacheManager)
 	if err != nil {
 		log.Fatal("Error during init server: ", err)
 		os.Exit(1)
 	}
+	cacheManager.StartJanitors()
 	server.Start(5 * time.Second)
+	cacheManager.StopJanitors()
 }
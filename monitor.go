@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const MonitorBufferSize = 256
+
+type MonitorEvent struct {
+	Timestamp time.Time
+	Client    string
+	Command   string
+	Args      []string
+}
+
+func (e MonitorEvent) String() string {
+	parts := make([]string, 0, len(e.Args)+1)
+	parts = append(parts, fmt.Sprintf("%q", e.Command))
+	for _, arg := range e.Args {
+		parts = append(parts, fmt.Sprintf("%q", arg))
+	}
+	return fmt.Sprintf("%d.%06d [%s] %s", e.Timestamp.Unix(), e.Timestamp.Nanosecond()/1000, e.Client, strings.Join(parts, " "))
+}
+
+type Monitor interface {
+	Subscribe() (int64, <-chan MonitorEvent)
+	Unsubscribe(int64)
+	Publish(MonitorEvent)
+	Len() int
+	Close()
+}
+
+type monitorSubscriber struct {
+	events  chan MonitorEvent
+	dropped atomic.Int64
+}
+
+type monitor struct {
+	mu          sync.RWMutex
+	subscribers map[int64]*monitorSubscriber
+	nextID      int64
+	logger      Logger
+}
+
+func NewMonitor(logger Logger) Monitor {
+	return &monitor{
+		subscribers: make(map[int64]*monitorSubscriber),
+		logger:      logger,
+	}
+}
+
+func (m *monitor) Subscribe() (int64, <-chan MonitorEvent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id := m.nextID
+	m.nextID++
+	subscriber := &monitorSubscriber{events: make(chan MonitorEvent, MonitorBufferSize)}
+	m.subscribers[id] = subscriber
+	m.logger.Info(fmt.Sprintf("[MONITOR_EVENT] Monitor %d subscribed", id))
+	return id, subscriber.events
+}
+
+func (m *monitor) Unsubscribe(id int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	subscriber, ok := m.subscribers[id]
+	if !ok {
+		return
+	}
+	delete(m.subscribers, id)
+	close(subscriber.events)
+	m.logger.Info(fmt.Sprintf("[MONITOR_EVENT] Monitor %d unsubscribed, %d events dropped", id, subscriber.dropped.Load()))
+}
+
+// Publish fans the event out to every monitor without blocking: events are
+// dropped for monitors whose buffer is full.
+func (m *monitor) Publish(event MonitorEvent) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, subscriber := range m.subscribers {
+		select {
+		case subscriber.events <- event:
+		default:
+			subscriber.dropped.Add(1)
+		}
+	}
+}
+
+func (m *monitor) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.subscribers)
+}
+
+func (m *monitor) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, subscriber := range m.subscribers {
+		delete(m.subscribers, id)
+		close(subscriber.events)
+	}
+}
+
+// StreamingResult is implemented by results that take over the connection to
+// turn it into a feed: they send their reply themselves and return whether
+// the connection can go back to normal once they are done.
+type StreamingResult interface {
+	Stream(Connection) bool
+}
+
+type monitorResult[V any] struct {
+	monitor Monitor
+}
+
+func (r *monitorResult[V]) String() string {
+	return "OK"
+}
+
+func (r *monitorResult[V]) Stream(connection Connection) bool {
+	if reply(connection, r.String()) != nil {
+		return false
+	}
+	id, events := r.monitor.Subscribe()
+	defer r.monitor.Unsubscribe(id)
+	connection.SetIdleTimeout(0)
+
+	// The client can only leave the feed by sending QUIT or disconnecting
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			input, err := connection.Read()
+			if err != nil || strings.EqualFold(strings.TrimSpace(input), "QUIT") {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return false
+			}
+			if err := reply(connection, event.String()); err != nil {
+				return false
+			}
+		case <-closed:
+			return false
+		}
+	}
+}
+
+type monitorCommand[K comparable, V any] struct {
+	Command
+	monitor Monitor
+}
+
+func NewMonitorCommand[K comparable, V any](monitor Monitor) ExecutableCommand[K, V] {
+	return &monitorCommand[K, V]{
+		Command: NewCommand("MONITOR"),
+		monitor: monitor,
+	}
+}
+
+func (c *monitorCommand[K, V]) Run(input CommandInput, cache Cache[K, V]) (Result[V], Error) {
+	return &monitorResult[V]{monitor: c.monitor}, nil
+}
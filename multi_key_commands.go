@@ -0,0 +1,62 @@
+package main
+
+// mgetCommand replies the values of several keys in order, with nil for the
+// missing ones and the ones not holding a string.
+type mgetCommand struct {
+	Command
+}
+
+func NewMGetCommand() ExecutableCommand[string, Value] {
+	return &mgetCommand{
+		Command: NewCommand("MGET").
+			WithArgument(KeysArgument).
+			WithOption(FrequentAccessOption),
+	}
+}
+
+func (c *mgetCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	keys := toStrings(input.GetArgument(*KeysArgument).([]any))
+	values := cache.GetMany(keys)
+	items := make([]Result[Value], len(values))
+	for i, value := range values {
+		if value == nil || value.Type() != StringData {
+			items[i] = &nilResult[Value]{}
+			continue
+		}
+		items[i] = &valueResult[Value]{value: *value}
+	}
+	return &arrayResult[Value]{items: items}, nil
+}
+
+// msetCommand sets several keys at once, all of them with the expiration
+// given as option.
+type msetCommand struct {
+	Command
+}
+
+func NewMSetCommand() ExecutableCommand[string, Value] {
+	return &msetCommand{
+		Command: NewCommand("MSET").
+			WithArgument(KeyValuesArgument).
+			WithOption(ExpirationOption).
+			WithOption(ExpirationMsOption).
+			WithOption(FrequentAccessOption),
+	}
+}
+
+func (c *msetCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	tokens := toStrings(input.GetArgument(*KeyValuesArgument).([]any))
+	if len(tokens)%2 != 0 {
+		return nil, &InvalidCommandUsageError{command: c.GetName()}
+	}
+	expiresAt, err := expirationFromInput(c.GetName(), input)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]KeyValue[string, Value], 0, len(tokens)/2)
+	for i := 0; i < len(tokens); i += 2 {
+		entries = append(entries, KeyValue[string, Value]{Key: tokens[i], Value: StringValue(tokens[i+1])})
+	}
+	cache.SetMany(entries, expiresAt)
+	return &okResult[Value]{}, nil
+}
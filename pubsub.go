@@ -0,0 +1,495 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// SlowConsumerPolicy decides what happens to the messages published to a
+// subscriber whose buffer is full.
+type SlowConsumerPolicy int
+
+const (
+	// DisconnectSlowConsumers closes the connection of the subscriber so that
+	// it knows it missed messages.
+	DisconnectSlowConsumers SlowConsumerPolicy = iota
+	// DropForSlowConsumers drops the messages the subscriber has no room for.
+	DropForSlowConsumers
+)
+
+// ParseSlowConsumerPolicy reads a policy from its name: "disconnect" or
+// "drop".
+func ParseSlowConsumerPolicy(name string) (SlowConsumerPolicy, error) {
+	switch name {
+	case "disconnect":
+		return DisconnectSlowConsumers, nil
+	case "drop":
+		return DropForSlowConsumers, nil
+	default:
+		return DisconnectSlowConsumers, fmt.Errorf("unknown slow consumer policy: %s", name)
+	}
+}
+
+// PubSubMessage is a message published to a channel, Pattern is the pattern
+// it was received through when it was not received by channel.
+type PubSubMessage struct {
+	Pattern string
+	Channel string
+	Message string
+}
+
+type PubSub interface {
+	Register(Connection) Subscriber
+	Publish(channel string, message string) int
+	Close()
+}
+
+// Subscriber holds the subscriptions of a connection. The subscription methods
+// return the subscription count after each channel or pattern, unsubscribing
+// from nothing unsubscribes from everything.
+type Subscriber interface {
+	Subscribe(channels ...string) []int
+	PSubscribe(patterns ...string) []int
+	Unsubscribe(channels ...string) ([]string, []int)
+	PUnsubscribe(patterns ...string) ([]string, []int)
+	Count() int
+	Messages() <-chan PubSubMessage
+	Close()
+}
+
+type pubSub struct {
+	mu         sync.RWMutex
+	channels   map[string]map[int64]*pubSubSubscriber
+	patterns   map[string]map[int64]*pubSubSubscriber
+	nextID     int64
+	bufferSize int
+	policy     SlowConsumerPolicy
+	logger     Logger
+}
+
+func NewPubSub(bufferSize int, policy SlowConsumerPolicy, logger Logger) (PubSub, Error) {
+	if bufferSize <= 0 {
+		err := &SetupError{message: "Invalid pub/sub buffer size: must be positive"}
+		logger.Error(fmt.Sprintf("[PUBSUB_EVENT] %s", err.Error()))
+		return nil, err
+	}
+	return &pubSub{
+		channels:   make(map[string]map[int64]*pubSubSubscriber),
+		patterns:   make(map[string]map[int64]*pubSubSubscriber),
+		bufferSize: bufferSize,
+		policy:     policy,
+		logger:     logger,
+	}, nil
+}
+
+func (p *pubSub) Register(connection Connection) Subscriber {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.nextID++
+	return &pubSubSubscriber{
+		id:         p.nextID,
+		pubSub:     p,
+		connection: connection,
+		messages:   make(chan PubSubMessage, p.bufferSize),
+		channels:   make(map[string]bool),
+		patterns:   make(map[string]bool),
+	}
+}
+
+// Publish delivers the message to the subscribers of the channel and of the
+// patterns matching it without blocking, and returns the number of deliveries.
+// Subscribers whose buffer is full are handled according to the policy.
+func (p *pubSub) Publish(channel string, message string) int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	receivers := 0
+	for _, subscriber := range p.channels[channel] {
+		if subscriber.deliver(PubSubMessage{Channel: channel, Message: message}) {
+			receivers++
+		}
+	}
+	for pattern, subscribers := range p.patterns {
+		if !globMatch(pattern, channel) {
+			continue
+		}
+		for _, subscriber := range subscribers {
+			if subscriber.deliver(PubSubMessage{Pattern: pattern, Channel: channel, Message: message}) {
+				receivers++
+			}
+		}
+	}
+	return receivers
+}
+
+// Close closes the message feed of every subscriber.
+func (p *pubSub) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	closed := make(map[int64]bool)
+	for _, subscriptions := range []map[string]map[int64]*pubSubSubscriber{p.channels, p.patterns} {
+		for _, subscribers := range subscriptions {
+			for id, subscriber := range subscribers {
+				if !closed[id] {
+					closed[id] = true
+					subscriber.closed = true
+					close(subscriber.messages)
+				}
+			}
+		}
+	}
+	clear(p.channels)
+	clear(p.patterns)
+}
+
+type pubSubSubscriber struct {
+	id         int64
+	pubSub     *pubSub
+	connection Connection
+	messages   chan PubSubMessage
+	// channels, patterns and closed are guarded by the lock of the pub/sub
+	channels map[string]bool
+	patterns map[string]bool
+	closed   bool
+	dropped  atomic.Int64
+	overflow atomic.Bool
+}
+
+// deliver sends the message without blocking, it must be called with the
+// pub/sub lock held for reading.
+func (s *pubSubSubscriber) deliver(message PubSubMessage) bool {
+	select {
+	case s.messages <- message:
+		return true
+	default:
+	}
+	s.dropped.Add(1)
+	if s.pubSub.policy == DisconnectSlowConsumers && s.overflow.CompareAndSwap(false, true) {
+		s.pubSub.logger.Warning(fmt.Sprintf("[PUBSUB_EVENT] Disconnecting subscriber %d (%s) which cannot keep up", s.id, s.connection.RemoteAddr()))
+		s.connection.Close()
+	}
+	return false
+}
+
+func (s *pubSubSubscriber) Subscribe(channels ...string) []int {
+	return s.subscribe(s.channels, s.pubSub.channels, channels)
+}
+
+func (s *pubSubSubscriber) PSubscribe(patterns ...string) []int {
+	return s.subscribe(s.patterns, s.pubSub.patterns, patterns)
+}
+
+func (s *pubSubSubscriber) subscribe(own map[string]bool, all map[string]map[int64]*pubSubSubscriber, names []string) []int {
+	p := s.pubSub
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	counts := make([]int, len(names))
+	for i, name := range names {
+		if !s.closed && !own[name] {
+			own[name] = true
+			if all[name] == nil {
+				all[name] = make(map[int64]*pubSubSubscriber)
+			}
+			all[name][s.id] = s
+		}
+		counts[i] = len(s.channels) + len(s.patterns)
+	}
+	return counts
+}
+
+func (s *pubSubSubscriber) Unsubscribe(channels ...string) ([]string, []int) {
+	return s.unsubscribe(s.channels, s.pubSub.channels, channels)
+}
+
+func (s *pubSubSubscriber) PUnsubscribe(patterns ...string) ([]string, []int) {
+	return s.unsubscribe(s.patterns, s.pubSub.patterns, patterns)
+}
+
+func (s *pubSubSubscriber) unsubscribe(own map[string]bool, all map[string]map[int64]*pubSubSubscriber, names []string) ([]string, []int) {
+	p := s.pubSub
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(names) == 0 {
+		for name := range own {
+			names = append(names, name)
+		}
+	}
+	counts := make([]int, len(names))
+	for i, name := range names {
+		if own[name] {
+			delete(own, name)
+			delete(all[name], s.id)
+			if len(all[name]) == 0 {
+				delete(all, name)
+			}
+		}
+		counts[i] = len(s.channels) + len(s.patterns)
+	}
+	return names, counts
+}
+
+func (s *pubSubSubscriber) Count() int {
+	s.pubSub.mu.RLock()
+	defer s.pubSub.mu.RUnlock()
+	return len(s.channels) + len(s.patterns)
+}
+
+func (s *pubSubSubscriber) Messages() <-chan PubSubMessage {
+	return s.messages
+}
+
+// Close drops all the subscriptions and closes the message feed.
+func (s *pubSubSubscriber) Close() {
+	s.Unsubscribe()
+	s.PUnsubscribe()
+	p := s.pubSub
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.messages)
+	}
+	if dropped := s.dropped.Load(); dropped > 0 {
+		p.logger.Info(fmt.Sprintf("[PUBSUB_EVENT] Subscriber %d closed, %d messages dropped", s.id, dropped))
+	}
+}
+
+// subscriptionResult turns the connection into push mode: messages are sent
+// as they are published while the client may only change its subscriptions.
+// The connection goes back to normal once it has no subscriptions left.
+type subscriptionResult[V any] struct {
+	subscriber Subscriber
+	command    string
+	names      []string
+}
+
+func (r *subscriptionResult[V]) String() string {
+	return ""
+}
+
+func (r *subscriptionResult[V]) Stream(connection Connection) bool {
+	defer r.subscriber.Close()
+	if !r.run(connection, r.command, r.names) {
+		return false
+	}
+	idleTimeout := connection.SetIdleTimeout(0)
+	defer connection.SetIdleTimeout(idleTimeout)
+
+	// Commands are read one at a time so that nothing is read past the one
+	// leaving push mode
+	commands := make(chan []string)
+	proceed := make(chan bool)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		defer close(commands)
+		for {
+			input, err := connection.Read()
+			if err != nil {
+				return
+			}
+			select {
+			case commands <- strings.Fields(input):
+			case <-done:
+				return
+			}
+			select {
+			case ok := <-proceed:
+				if !ok {
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	messages := r.subscriber.Messages()
+	for {
+		select {
+		case message, ok := <-messages:
+			if !ok {
+				return false
+			}
+			if reply(connection, formatPubSubMessage[V](message).String()) != nil {
+				return false
+			}
+		case in, ok := <-commands:
+			if !ok {
+				return false
+			}
+			if len(in) == 0 {
+				proceed <- true
+				continue
+			}
+			if !r.run(connection, strings.ToUpper(in[0]), in[1:]) {
+				return false
+			}
+			if r.subscriber.Count() == 0 {
+				proceed <- false
+				return true
+			}
+			proceed <- true
+		}
+	}
+}
+
+// run handles a command received in push mode, it returns false once the
+// connection should be closed.
+func (r *subscriptionResult[V]) run(connection Connection, command string, names []string) bool {
+	var replies []Result[V]
+	switch command {
+	case "SUBSCRIBE", "PSUBSCRIBE":
+		if len(names) == 0 {
+			return reply(connection, (&InvalidCommandUsageError{command: command}).Display()) == nil
+		}
+		var counts []int
+		if command == "SUBSCRIBE" {
+			counts = r.subscriber.Subscribe(names...)
+		} else {
+			counts = r.subscriber.PSubscribe(names...)
+		}
+		replies = subscriptionReplies[V](command, names, counts)
+	case "UNSUBSCRIBE", "PUNSUBSCRIBE":
+		var counts []int
+		if command == "UNSUBSCRIBE" {
+			names, counts = r.subscriber.Unsubscribe(names...)
+		} else {
+			names, counts = r.subscriber.PUnsubscribe(names...)
+		}
+		replies = subscriptionReplies[V](command, names, counts)
+		if len(replies) == 0 {
+			replies = subscriptionReplies[V](command, []string{""}, []int{r.subscriber.Count()})
+		}
+	case "PING":
+		replies = []Result[V]{&textResult[V]{text: "PONG"}}
+	case "QUIT":
+		reply(connection, "OK")
+		return false
+	default:
+		err := &CommandError{message: fmt.Sprintf("%s is not allowed while subscribed, only (P)SUBSCRIBE, (P)UNSUBSCRIBE, PING and QUIT are", command)}
+		return reply(connection, err.Display()) == nil
+	}
+	for _, result := range replies {
+		if reply(connection, result.String()) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func subscriptionReplies[V any](command string, names []string, counts []int) []Result[V] {
+	replies := make([]Result[V], len(names))
+	for i, name := range names {
+		var nameResult Result[V] = &textResult[V]{text: fmt.Sprintf("%q", name)}
+		if name == "" {
+			nameResult = &nilResult[V]{}
+		}
+		replies[i] = &arrayResult[V]{items: []Result[V]{
+			&textResult[V]{text: fmt.Sprintf("%q", strings.ToLower(command))},
+			nameResult,
+			&integerResult[V]{value: int64(counts[i])},
+		}}
+	}
+	return replies
+}
+
+func formatPubSubMessage[V any](message PubSubMessage) Result[V] {
+	items := []Result[V]{&textResult[V]{text: `"message"`}}
+	if message.Pattern != "" {
+		items = []Result[V]{
+			&textResult[V]{text: `"pmessage"`},
+			&textResult[V]{text: fmt.Sprintf("%q", message.Pattern)},
+		}
+	}
+	items = append(items,
+		&textResult[V]{text: fmt.Sprintf("%q", message.Channel)},
+		&textResult[V]{text: fmt.Sprintf("%q", message.Message)},
+	)
+	return &arrayResult[V]{items: items}
+}
+
+// subscribeCommand subscribes the connection to channels or patterns and
+// switches it into push mode.
+type subscribeCommand[K comparable, V any] struct {
+	connectionCommand[K, V]
+	pubSub PubSub
+}
+
+func NewSubscribeCommand[K comparable, V any](pubSub PubSub) ExecutableCommand[K, V] {
+	return &subscribeCommand[K, V]{
+		connectionCommand: connectionCommand[K, V]{
+			Command: NewCommand("SUBSCRIBE").WithArgument(ChannelsArgument),
+		},
+		pubSub: pubSub,
+	}
+}
+
+func NewPSubscribeCommand[K comparable, V any](pubSub PubSub) ExecutableCommand[K, V] {
+	return &subscribeCommand[K, V]{
+		connectionCommand: connectionCommand[K, V]{
+			Command: NewCommand("PSUBSCRIBE").WithArgument(ChannelsArgument),
+		},
+		pubSub: pubSub,
+	}
+}
+
+func (c *subscribeCommand[K, V]) RunOnConnection(connection Connection, input CommandInput, cache Cache[K, V]) (Result[V], Error) {
+	return &subscriptionResult[V]{
+		subscriber: c.pubSub.Register(connection),
+		command:    c.GetName(),
+		names:      toStrings(input.GetArgument(*ChannelsArgument).([]any)),
+	}, nil
+}
+
+// unsubscribeCommand replies like in push mode for a connection which is not
+// subscribed to anything.
+type unsubscribeCommand[K comparable, V any] struct {
+	Command
+}
+
+func NewUnsubscribeCommand[K comparable, V any]() ExecutableCommand[K, V] {
+	return &unsubscribeCommand[K, V]{
+		Command: NewCommand("UNSUBSCRIBE").WithArgument(OptionalChannelsArgument),
+	}
+}
+
+func NewPUnsubscribeCommand[K comparable, V any]() ExecutableCommand[K, V] {
+	return &unsubscribeCommand[K, V]{
+		Command: NewCommand("PUNSUBSCRIBE").WithArgument(OptionalChannelsArgument),
+	}
+}
+
+func (c *unsubscribeCommand[K, V]) Run(input CommandInput, cache Cache[K, V]) (Result[V], Error) {
+	names := []string{""}
+	if channels, ok := input.GetArgument(*OptionalChannelsArgument).([]any); ok {
+		names = toStrings(channels)
+	}
+	// Like in push mode, every channel gets its own reply
+	lines := make([]string, 0, len(names))
+	for _, result := range subscriptionReplies[V](c.GetName(), names, make([]int, len(names))) {
+		lines = append(lines, result.String())
+	}
+	return &textResult[V]{text: strings.Join(lines, "\n")}, nil
+}
+
+type publishCommand[K comparable, V any] struct {
+	Command
+	pubSub PubSub
+}
+
+func NewPublishCommand[K comparable, V any](pubSub PubSub) ExecutableCommand[K, V] {
+	return &publishCommand[K, V]{
+		Command: NewCommand("PUBLISH").
+			WithArgument(ChannelArgument).
+			WithArgument(MessageArgument),
+		pubSub: pubSub,
+	}
+}
+
+func (c *publishCommand[K, V]) Run(input CommandInput, cache Cache[K, V]) (Result[V], Error) {
+	channel := input.GetArgument(*ChannelArgument).(string)
+	message := input.GetArgument(*MessageArgument).(string)
+	return &integerResult[V]{value: int64(c.pubSub.Publish(channel, message))}, nil
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// pushTestConnection is a test connection reading the commands sent to
+// inputs.
+type pushTestConnection struct {
+	*testConnection
+	inputs chan string
+}
+
+func (c *pushTestConnection) Read() (string, Error) {
+	select {
+	case input := <-c.inputs:
+		return input, nil
+	case <-c.closed:
+		return "", &UnexpectedError{message: "Connection closed"}
+	}
+}
+
+func newTestPubSub(t *testing.T, bufferSize int, policy SlowConsumerPolicy) PubSub {
+	pubSub, err := NewPubSub(bufferSize, policy, NewLogger(io.Discard, "", 0))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	return pubSub
+}
+
+func TestPublishDeliversToPatterns(t *testing.T) {
+	pubSub := newTestPubSub(t, 4, DropForSlowConsumers)
+	byPattern := pubSub.Register(newTestConnection())
+	byPattern.PSubscribe("news.*")
+	byChannel := pubSub.Register(newTestConnection())
+	byChannel.Subscribe("news.sport")
+
+	if receivers := pubSub.Publish("news.sport", "goal"); receivers != 2 {
+		t.Errorf("got %d receivers, want 2", receivers)
+	}
+	if receivers := pubSub.Publish("weather", "rain"); receivers != 0 {
+		t.Errorf("got %d receivers for an unmatched channel, want 0", receivers)
+	}
+	want := PubSubMessage{Pattern: "news.*", Channel: "news.sport", Message: "goal"}
+	if message := <-byPattern.Messages(); message != want {
+		t.Errorf("got %+v through the pattern, want %+v", message, want)
+	}
+	want = PubSubMessage{Channel: "news.sport", Message: "goal"}
+	if message := <-byChannel.Messages(); message != want {
+		t.Errorf("got %+v through the channel, want %+v", message, want)
+	}
+}
+
+func TestPublishDropsForSlowConsumers(t *testing.T) {
+	pubSub := newTestPubSub(t, 2, DropForSlowConsumers)
+	connection := newTestConnection()
+	subscriber := pubSub.Register(connection)
+	subscriber.Subscribe("c")
+
+	for i, want := range []int{1, 1, 0} {
+		if receivers := pubSub.Publish("c", string(rune('a'+i))); receivers != want {
+			t.Errorf("message %d: got %d receivers, want %d", i, receivers, want)
+		}
+	}
+	select {
+	case <-connection.closed:
+		t.Fatalf("slow consumer disconnected by the drop policy")
+	default:
+	}
+	for _, want := range []string{"a", "b"} {
+		if message := <-subscriber.Messages(); message.Message != want {
+			t.Errorf("got message %q, want %q", message.Message, want)
+		}
+	}
+	// Room was made, so that the next messages are delivered again
+	if receivers := pubSub.Publish("c", "d"); receivers != 1 {
+		t.Errorf("got %d receivers once the buffer was read, want 1", receivers)
+	}
+}
+
+func TestPublishDisconnectsSlowConsumers(t *testing.T) {
+	pubSub := newTestPubSub(t, 1, DisconnectSlowConsumers)
+	connection := newTestConnection()
+	pubSub.Register(connection).Subscribe("c")
+
+	pubSub.Publish("c", "a")
+	select {
+	case <-connection.closed:
+		t.Fatalf("subscriber disconnected before its buffer was full")
+	default:
+	}
+	// Later messages do not close the connection again
+	for range 2 {
+		if receivers := pubSub.Publish("c", "b"); receivers != 0 {
+			t.Errorf("got %d receivers with a full buffer, want 0", receivers)
+		}
+	}
+	select {
+	case <-connection.closed:
+	case <-time.After(time.Second):
+		t.Fatalf("slow consumer not disconnected")
+	}
+}
+
+func TestStreamLeavesPushModeOnceUnsubscribed(t *testing.T) {
+	pubSub := newTestPubSub(t, 4, DropForSlowConsumers)
+	connection := &pushTestConnection{testConnection: newTestConnection(), inputs: make(chan string, 4)}
+	result := &subscriptionResult[Value]{
+		subscriber: pubSub.Register(connection),
+		command:    "SUBSCRIBE",
+		names:      []string{"a", "b"},
+	}
+	done := make(chan bool)
+	go func() {
+		done <- result.Stream(connection)
+	}()
+	expectReply := func(want string) {
+		t.Helper()
+		if got := <-connection.replies; !strings.Contains(got, want) {
+			t.Fatalf("got reply %q, want it to contain %q", got, want)
+		}
+	}
+	expectReply(`"a"`)
+	expectReply(`"b"`)
+
+	pubSub.Publish("a", "hello")
+	expectReply(`"hello"`)
+	connection.inputs <- "GET k"
+	expectReply("not allowed while subscribed")
+	connection.inputs <- "UNSUBSCRIBE a"
+	expectReply("(integer) 1")
+	select {
+	case <-done:
+		t.Fatalf("push mode left with a subscription left")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	connection.inputs <- "UNSUBSCRIBE b"
+	expectReply("(integer) 0")
+	select {
+	case ok := <-done:
+		if !ok {
+			t.Fatalf("connection closed when leaving push mode")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("push mode not left once unsubscribed from everything")
+	}
+	// The command coming next is left to the server
+	connection.inputs <- "GET k"
+	time.Sleep(10 * time.Millisecond)
+	if len(connection.inputs) != 1 {
+		t.Errorf("command read past the end of push mode")
+	}
+	if receivers := pubSub.Publish("a", "hello"); receivers != 0 {
+		t.Errorf("got %d receivers after leaving push mode, want 0", receivers)
+	}
+}
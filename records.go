@@ -9,17 +9,52 @@ import (
 
 const MinPrecision = time.Second
 
+// RecordsKind selects the data structure used to track expiration times.
+type RecordsKind int
+
+const (
+	// OrderedRecords groups keys in an ordered map of time buckets, it only
+	// supports a precision above one second.
+	OrderedRecords RecordsKind = iota
+	// TimingWheelRecords uses a hierarchical timing wheel supporting a
+	// precision down to one millisecond.
+	TimingWheelRecords
+)
+
+// ParseRecordsKind reads a records kind from its name: "ordered" or "wheel".
+func ParseRecordsKind(name string) (RecordsKind, error) {
+	switch name {
+	case "ordered":
+		return OrderedRecords, nil
+	case "wheel":
+		return TimingWheelRecords, nil
+	default:
+		return OrderedRecords, fmt.Errorf("unknown records kind: %s", name)
+	}
+}
+
+// NewRecordsOfKind creates the records of the given kind.
+func NewRecordsOfKind[K comparable](kind RecordsKind, precision time.Duration, logger Logger) (Records[K], Error) {
+	if kind == TimingWheelRecords {
+		return NewTimingWheel[K](precision, logger)
+	}
+	return NewRecords[K](precision, logger)
+}
+
 type Records[T any] interface {
 	truncateTime(time.Time) int64
 	Get(time.Time) []T
 	Add(T, time.Time)
 	AddMany([]T, time.Time)
 	Delete(T, time.Time)
+	Move(T, time.Time, time.Time)
 	DeleteBefore(time.Time, func([]T)) int
 	DeleteAfter(time.Time, func([]T)) int
 	Clear()
 }
 
+// records groups keys by their truncated expiration time, keys with a zero
+// expiration time never expire and are not recorded.
 type records[K comparable] struct {
 	data      *orderedmap.OrderedMap[int64, *Set[K]]
 	precision time.Duration
@@ -56,6 +91,9 @@ func (r *records[K]) Get(t time.Time) []K {
 }
 
 func (r *records[K]) Add(key K, t time.Time) {
+	if t.IsZero() {
+		return
+	}
 	recordKey := r.truncateTime(t)
 	s, ok := r.data.Get(recordKey)
 	if ok {
@@ -68,6 +106,9 @@ func (r *records[K]) Add(key K, t time.Time) {
 }
 
 func (r *records[K]) AddMany(keys []K, t time.Time) {
+	if t.IsZero() {
+		return
+	}
 	recordKey := r.truncateTime(t)
 	s, ok := r.data.Get(recordKey)
 	if ok {
@@ -80,6 +121,9 @@ func (r *records[K]) AddMany(keys []K, t time.Time) {
 }
 
 func (r *records[K]) Delete(key K, t time.Time) {
+	if t.IsZero() {
+		return
+	}
 	recordKey := r.truncateTime(t)
 	if recordKey == -1 {
 		r.logger.Warning(fmt.Sprintf("[RECORDS_EVENT] Attempted to delete key %v with invalid record", key))
@@ -96,31 +140,49 @@ func (r *records[K]) Delete(key K, t time.Time) {
 	}
 }
 
+// Move changes the record of a key whose expiration time changed from `from`
+// to `to`.
+func (r *records[K]) Move(key K, from time.Time, to time.Time) {
+	r.Delete(key, from)
+	r.Add(key, to)
+}
+
+// DeleteBefore clears the records whose whole time range is over at t, the
+// records are not sorted by time so every one of them is looked at.
 func (r *records[K]) DeleteBefore(t time.Time, deleteCallback func([]K)) int {
 	nbrKeys := 0
 	recordKey := r.truncateTime(t)
 	r.logger.Info(fmt.Sprintf("[RECORDS_EVENT] Deleting records before %v", t))
-	for pair := r.data.GetPair(recordKey); pair != nil; pair = pair.Prev() {
-		keys := pair.Value.Items()
-		deleteCallback(keys)
-		r.data.Delete(pair.Key)
-		nbrKeys++
+	for pair := r.data.Oldest(); pair != nil; {
+		next := pair.Next()
+		if pair.Key < recordKey {
+			keys := pair.Value.Items()
+			r.data.Delete(pair.Key)
+			deleteCallback(keys)
+			nbrKeys += len(keys)
+		}
+		pair = next
 	}
-	r.logger.Info(fmt.Sprintf("[RECORDS_EVENT] Deleted %d records before %v", nbrKeys, t))
+	r.logger.Info(fmt.Sprintf("[RECORDS_EVENT] Deleted %d keys before %v", nbrKeys, t))
 	return nbrKeys
 }
 
+// DeleteAfter clears the records starting at or after t.
 func (r *records[K]) DeleteAfter(t time.Time, deleteCallback func([]K)) int {
 	nbrKeys := 0
 	recordKey := r.truncateTime(t)
 	r.logger.Info(fmt.Sprintf("[RECORDS_EVENT] Deleting records after %v", t))
-	for pair := r.data.GetPair(recordKey); pair != nil; pair = pair.Next() {
-		keys := pair.Value.Items()
-		deleteCallback(keys)
-		r.data.Delete(pair.Key)
-		nbrKeys++
+	for pair := r.data.Oldest(); pair != nil; {
+		next := pair.Next()
+		if pair.Key >= recordKey {
+			keys := pair.Value.Items()
+			r.data.Delete(pair.Key)
+			deleteCallback(keys)
+			nbrKeys += len(keys)
+		}
+		pair = next
 	}
-	r.logger.Info(fmt.Sprintf("[RECORDS_EVENT] Deleted %d records after %v", nbrKeys, t))
+	r.logger.Info(fmt.Sprintf("[RECORDS_EVENT] Deleted %d keys after %v", nbrKeys, t))
 	return nbrKeys
 }
 
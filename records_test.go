@@ -0,0 +1,102 @@
+package main
+
+import (
+	"io"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// orderedPrecision is the precision of the ordered records, which only support
+// precisions above one second. The timing wheel is also measured at this
+// precision for comparison, along with the sub-second ones it exists for.
+const orderedPrecision = 2 * time.Second
+
+var wheelPrecisions = []time.Duration{time.Millisecond, 200 * time.Millisecond, orderedPrecision}
+
+func newBenchmarkRecords(b *testing.B, kind RecordsKind, precision time.Duration) Records[string] {
+	records, err := NewRecordsOfKind[string](kind, precision, NewLogger(io.Discard, "", 0))
+	if err != nil {
+		b.Fatalf("unexpected error %v", err)
+	}
+	return records
+}
+
+// benchmarkKeys returns n keys expiring over the next hour, spread down to the
+// millisecond.
+func benchmarkKeys(n int, now time.Time) ([]string, []time.Time) {
+	keys := make([]string, n)
+	expirations := make([]time.Time, n)
+	for i := range n {
+		keys[i] = "key:" + strconv.Itoa(i)
+		expirations[i] = now.Add(time.Duration(i*7919%3600000+1) * time.Millisecond)
+	}
+	return keys, expirations
+}
+
+func benchmarkRecordsAdd(b *testing.B, kind RecordsKind, precision time.Duration) {
+	records := newBenchmarkRecords(b, kind, precision)
+	keys, expirations := benchmarkKeys(b.N, time.Now())
+	b.ResetTimer()
+	for i := range b.N {
+		records.Add(keys[i], expirations[i])
+	}
+}
+
+func benchmarkRecordsDelete(b *testing.B, kind RecordsKind, precision time.Duration) {
+	records := newBenchmarkRecords(b, kind, precision)
+	keys, expirations := benchmarkKeys(b.N, time.Now())
+	for i := range b.N {
+		records.Add(keys[i], expirations[i])
+	}
+	b.ResetTimer()
+	for i := range b.N {
+		records.Delete(keys[i], expirations[i])
+	}
+}
+
+// benchmarkRecordsDeleteBefore expires b.N keys at once, the time reported
+// per operation being the one taken per key.
+func benchmarkRecordsDeleteBefore(b *testing.B, kind RecordsKind, precision time.Duration) {
+	records := newBenchmarkRecords(b, kind, precision)
+	now := time.Now()
+	keys, expirations := benchmarkKeys(b.N, now)
+	for i := range b.N {
+		records.Add(keys[i], expirations[i])
+	}
+	b.ResetTimer()
+	deleted := records.DeleteBefore(now.Add(2*time.Hour), func([]string) {})
+	b.StopTimer()
+	if deleted != b.N {
+		b.Fatalf("deleted %d keys, want %d", deleted, b.N)
+	}
+}
+
+// benchmarkTimingWheel runs a records benchmark on timing wheels of every
+// precision in wheelPrecisions.
+func benchmarkTimingWheel(b *testing.B, benchmark func(*testing.B, RecordsKind, time.Duration)) {
+	for _, precision := range wheelPrecisions {
+		b.Run("precision="+precision.String(), func(b *testing.B) {
+			benchmark(b, TimingWheelRecords, precision)
+		})
+	}
+}
+
+func BenchmarkRecordsOrderedAdd(b *testing.B) {
+	benchmarkRecordsAdd(b, OrderedRecords, orderedPrecision)
+}
+func BenchmarkRecordsTimingWheelAdd(b *testing.B) { benchmarkTimingWheel(b, benchmarkRecordsAdd) }
+
+func BenchmarkRecordsOrderedDelete(b *testing.B) {
+	benchmarkRecordsDelete(b, OrderedRecords, orderedPrecision)
+}
+func BenchmarkRecordsTimingWheelDelete(b *testing.B) {
+	benchmarkTimingWheel(b, benchmarkRecordsDelete)
+}
+
+func BenchmarkRecordsOrderedDeleteBefore(b *testing.B) {
+	benchmarkRecordsDeleteBefore(b, OrderedRecords, orderedPrecision)
+}
+func BenchmarkRecordsTimingWheelDeleteBefore(b *testing.B) {
+	benchmarkTimingWheel(b, benchmarkRecordsDeleteBefore)
+}
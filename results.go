@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type okResult[V any] struct{}
+
+func (r *okResult[V]) String() string {
+	return "OK"
+}
+
+type nilResult[V any] struct{}
+
+func (r *nilResult[V]) String() string {
+	return "(nil)"
+}
+
+type textResult[V any] struct {
+	text string
+}
+
+func (r *textResult[V]) String() string {
+	return r.text
+}
+
+type integerResult[V any] struct {
+	value int64
+}
+
+func (r *integerResult[V]) String() string {
+	return fmt.Sprintf("(integer) %d", r.value)
+}
+
+// booleanResult replies 1 when ok is true and 0 otherwise.
+func booleanResult[V any](ok bool) Result[V] {
+	if ok {
+		return &integerResult[V]{value: 1}
+	}
+	return &integerResult[V]{value: 0}
+}
+
+type valueResult[V any] struct {
+	value V
+}
+
+func (r *valueResult[V]) String() string {
+	return fmt.Sprintf("\"%v\"", r.value)
+}
+
+type arrayResult[V any] struct {
+	items []Result[V]
+}
+
+func (r *arrayResult[V]) String() string {
+	if len(r.items) == 0 {
+		return "(empty array)"
+	}
+	lines := make([]string, 0, len(r.items))
+	width := len(fmt.Sprint(len(r.items)))
+	for i, item := range r.items {
+		prefix := fmt.Sprintf("%*d) ", width, i+1)
+		indent := strings.Repeat(" ", len(prefix))
+		itemLines := strings.Split(item.String(), "\n")
+		for j, line := range itemLines {
+			if j == 0 {
+				lines = append(lines, prefix+line)
+			} else {
+				lines = append(lines, indent+line)
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
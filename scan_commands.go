@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+)
+
+const DefaultScanCount = 10
+
+// keysCommand replies all the keys matching a pattern at once, it goes over
+// the whole cache and is meant for small datasets.
+type keysCommand[V any] struct {
+	Command
+}
+
+func NewKeysCommand[V any]() ExecutableCommand[string, V] {
+	return &keysCommand[V]{
+		Command: NewCommand("KEYS").
+			WithArgument(PatternArgument).
+			WithOption(FrequentAccessOption),
+	}
+}
+
+func (c *keysCommand[V]) Run(input CommandInput, cache Cache[string, V]) (Result[V], Error) {
+	pattern := input.GetArgument(*PatternArgument).(string)
+	var keys []string
+	for key := range cache.Keys() {
+		if globMatch(pattern, key) {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	return keysResult[V](keys), nil
+}
+
+func keysResult[V any](keys []string) Result[V] {
+	items := make([]Result[V], len(keys))
+	for i, key := range keys {
+		items[i] = &textResult[V]{text: fmt.Sprintf("%q", key)}
+	}
+	return &arrayResult[V]{items: items}
+}
+
+// scanCommand iterates over the keys a few at a time. It replies the cursor to
+// pass to the next call, 0 once the iteration is over, along with the keys
+// matching the optional pattern among the ones looked at. The cursor is the
+// position of the keys the next call starts from, so that the server keeps
+// nothing between calls.
+type scanCommand[V any] struct {
+	Command
+}
+
+func NewScanCommand[V any]() ExecutableCommand[string, V] {
+	return &scanCommand[V]{
+		Command: NewCommand("SCAN").
+			WithArgument(CursorArgument).
+			WithOption(MatchOption).
+			WithOption(CountOption).
+			WithOption(FrequentAccessOption),
+	}
+}
+
+func (c *scanCommand[V]) Run(input CommandInput, cache Cache[string, V]) (Result[V], Error) {
+	cursor := input.GetArgument(*CursorArgument).(int)
+	count := DefaultScanCount
+	if value := input.GetOption(*CountOption); value != nil {
+		count = value.(int)
+	}
+	if count <= 0 {
+		return nil, &InvalidCommandUsageError{command: c.GetName()}
+	}
+	if cursor < 0 {
+		return nil, &CommandError{message: "Invalid cursor in " + c.GetName()}
+	}
+	pattern, hasPattern := input.GetOption(*MatchOption).(string)
+
+	scanned, next := cache.Scan(cursor, count)
+	var keys []string
+	for _, key := range scanned {
+		if !hasPattern || globMatch(pattern, key) {
+			keys = append(keys, key)
+		}
+	}
+	return &arrayResult[V]{items: []Result[V]{
+		&textResult[V]{text: strconv.Quote(strconv.Itoa(next))},
+		keysResult[V](keys),
+	}}, nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func scanAll(t *testing.T, cache Cache[string, string], count int, during func(calls int)) []string {
+	var keys []string
+	cursor, calls := 0, 0
+	for {
+		scanned, next := cache.Scan(cursor, count)
+		keys = append(keys, scanned...)
+		calls++
+		if next == 0 {
+			return keys
+		}
+		if next <= cursor {
+			t.Fatalf("cursor went from %d back to %d", cursor, next)
+		}
+		cursor = next
+		during(calls)
+	}
+}
+
+func TestScanReturnsEveryKeyOnce(t *testing.T) {
+	for name, cache := range newTestCaches(t) {
+		var want []string
+		for i := range 1000 {
+			key := strconv.Itoa(i)
+			cache.Set(key, "v", time.Time{})
+			want = append(want, key)
+		}
+		// keys written during the iteration may or may not be returned,
+		// while the others are returned exactly once
+		keys := scanAll(t, cache, 10, func(calls int) {
+			cache.Set("new"+strconv.Itoa(calls), "v", time.Time{})
+			cache.Delete("new" + strconv.Itoa(calls-1))
+		})
+		keys = slices.DeleteFunc(keys, func(key string) bool {
+			_, err := strconv.Atoi(key)
+			return err != nil
+		})
+		slices.Sort(keys)
+		slices.Sort(want)
+		if !slices.Equal(keys, want) {
+			t.Errorf("%s: got %d keys, want %d each once", name, len(keys), len(want))
+		}
+	}
+}
+
+func TestScanSkipsExpiredKeys(t *testing.T) {
+	for name, cache := range newTestCaches(t) {
+		cache.Set("expired", "v", time.Now().Add(time.Millisecond))
+		cache.Set("kept", "v", time.Time{})
+		time.Sleep(2 * time.Millisecond)
+		if keys := scanAll(t, cache, 10, func(int) {}); !slices.Equal(keys, []string{"kept"}) {
+			t.Errorf("%s: got keys %v, want [kept]", name, keys)
+		}
+	}
+}
+
+// TestScanVisitsCountKeys checks that a call only looks at the keys it
+// returns rather than at the whole cache.
+func TestScanVisitsCountKeys(t *testing.T) {
+	index := newScanIndex[int]()
+	for i := range 100000 {
+		index.Add(i)
+	}
+	cursor := 0
+	for range 3 {
+		visited := 0
+		keys, next := index.Scan(cursor, 10, func(int) bool {
+			visited++
+			return false
+		})
+		if visited != 10 || len(keys) != 10 || next <= cursor {
+			t.Fatalf("cursor %d: visited %d keys, returned %d and cursor %d", cursor, visited, len(keys), next)
+		}
+		cursor = next
+	}
+	for i := range 99990 {
+		index.Delete(i)
+	}
+	visited := 0
+	keys, next := index.Scan(cursor, 10, func(int) bool {
+		visited++
+		return false
+	})
+	if visited != 10 || len(keys) != 10 || next != 0 {
+		t.Errorf("visited %d keys, returned %d and cursor %d after deleting the keys in between", visited, len(keys), next)
+	}
+}
+
+func TestScanCommandRejectsNegativeCursors(t *testing.T) {
+	command := NewScanCommand[Value]()
+	input, err := command.Parse([]string{"-1"})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, err := command.Run(input, newTestCache(t)); err == nil {
+		t.Errorf("cursor -1 accepted")
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import "math"
+
+// scanIndex gives the keys of a cache their position in SCAN iterations: the
+// order in which they were added, starting from 1. A key keeps its position
+// as long as it exists, so that a key existing during a whole iteration is
+// returned exactly once. Keys are kept ordered by position in a sorted set,
+// so that a call seeks its cursor in O(log n) and then only reads the keys it
+// returns. It is not safe for concurrent use: caches update it with their
+// write lock held.
+type scanIndex[K comparable] struct {
+	positions map[K]int
+	keys      map[int]K
+	// ordered scores the positions by themselves, which float64 holds
+	// exactly up to 2^53 positions
+	ordered *SortedSet[int]
+	last    int
+}
+
+func newScanIndex[K comparable]() *scanIndex[K] {
+	return &scanIndex[K]{
+		positions: make(map[K]int),
+		keys:      make(map[int]K),
+		ordered:   NewSortedSet[int](),
+	}
+}
+
+// Add gives the key the next position, unless it already has one.
+func (s *scanIndex[K]) Add(key K) {
+	if _, exists := s.positions[key]; exists {
+		return
+	}
+	s.last++
+	s.positions[key] = s.last
+	s.keys[s.last] = key
+	s.ordered.Add(s.last, float64(s.last))
+}
+
+func (s *scanIndex[K]) Delete(key K) {
+	position, exists := s.positions[key]
+	if !exists {
+		return
+	}
+	delete(s.positions, key)
+	delete(s.keys, position)
+	s.ordered.Remove(position)
+}
+
+// Clear deletes every key, positions keep growing so that the cursors given
+// before do not skip the keys added after.
+func (s *scanIndex[K]) Clear() {
+	clear(s.positions)
+	clear(s.keys)
+	s.ordered = NewSortedSet[int]()
+}
+
+// Scan looks at the count keys coming next from cursor on and returns the
+// ones skip does not reject, along with the cursor of the next call: the
+// position of the key following them, or 0 once no key is left.
+func (s *scanIndex[K]) Scan(cursor int, count int, skip func(K) bool) ([]K, int) {
+	// One more key tells whether the iteration is over
+	members := s.ordered.FirstByScore(ScoreBound{Score: float64(cursor)}, min(count, math.MaxInt-1)+1)
+	next := 0
+	if len(members) > count {
+		next = members[count].Member
+		members = members[:count]
+	}
+	var keys []K
+	for _, member := range members {
+		if key := s.keys[member.Member]; !skip(key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, next
+}
@@ -12,13 +12,27 @@ import (
 )
 
 type ServerConfig struct {
-	nbrWorkers int
-	port       int
+	// nbrWorkers bounds the number of commands executed concurrently, the
+	// number of open connections is bounded by maxConnections
+	nbrWorkers          int
+	port                int
+	slowLogThreshold    time.Duration
+	slowLogMaxLen       int
+	timeouts            ConnectionTimeouts
+	maxConnections      int
+	maxConnectionsPerIP int
+	// pubSubBufferSize is the number of messages buffered for each
+	// subscriber, the policy applies once it is full
+	pubSubBufferSize         int
+	pubSubSlowConsumerPolicy SlowConsumerPolicy
+	// keyspaceNotifications selects the keyspace events published to pub/sub
+	keyspaceNotifications KeyspaceNotifications
 }
 
 type Server interface {
 	Start(time.Duration)
 	acceptConnection() (Connection, Error)
+	serveConnection(Connection)
 	handleConnection(Connection)
 	CloseConnections()
 	Log(LogType, string)
@@ -32,29 +46,77 @@ type server[K comparable, V any] struct {
 	listener       net.Listener
 	config         *ServerConfig
 	logger         Logger
-	connections    chan Connection
+	executionSlots chan struct{}
 	commandManager CommandManager
 	cacheManager   CacheManager[K, V]
 	executor       Executor[K, V]
-	shutdown       chan os.Signal
-	wg             sync.WaitGroup
+	monitor        Monitor
+	pubSub         PubSub
+	// keyspaceUnsubscribers stop the publishing of keyspace events
+	keyspaceUnsubscribers []func()
+	clients               ClientRegistry
+	shutdown              chan os.Signal
+	wg                    sync.WaitGroup
 }
 
-func NewServer[K comparable, V any](port int, nbrWorkers int, logger Logger, commandManager CommandManager, cacheManager CacheManager[K, V]) (Server, error) {
-	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+func NewServer[K comparable, V any](config *ServerConfig, logger Logger, commandManager CommandManager, cacheManager CacheManager[K, V]) (Server, error) {
+	slowLog, setupErr := NewSlowLog(config.slowLogThreshold, config.slowLogMaxLen, logger)
+	if setupErr != nil {
+		return nil, setupErr
+	}
+
+	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", config.port))
 	if err != nil {
 		return nil, err
 	}
 
+	monitor := NewMonitor(logger)
+	clients := NewClientRegistry(logger)
+	pubSub, setupErr := NewPubSub(config.pubSubBufferSize, config.pubSubSlowConsumerPolicy, logger)
+	if setupErr != nil {
+		listener.Close()
+		return nil, setupErr
+	}
+
+	commandManager.
+		AddCommand("SLOWLOG", NewSlowLogCommand[K, V](slowLog)).
+		AddCommand("MONITOR", NewMonitorCommand[K, V](monitor)).
+		AddCommand("CLIENT", NewClientCommand[K, V](clients, logger)).
+		AddCommand("SUBSCRIBE", NewSubscribeCommand[K, V](pubSub)).
+		AddCommand("PSUBSCRIBE", NewPSubscribeCommand[K, V](pubSub)).
+		AddCommand("UNSUBSCRIBE", NewUnsubscribeCommand[K, V]()).
+		AddCommand("PUNSUBSCRIBE", NewPUnsubscribeCommand[K, V]()).
+		AddCommand("PUBLISH", NewPublishCommand[K, V](pubSub))
+
+	// The main cache publishes its events as cache 0 and the sync cache as
+	// cache 1
+	var keyspaceUnsubscribers []func()
+	if config.keyspaceNotifications.Enabled() {
+		for cacheNumber, frequentAccess := range []bool{false, true} {
+			cache := cacheManager.Get(frequentAccess)
+			if cache == nil {
+				continue
+			}
+			events := cache.Events()
+			id, subscription := events.Subscribe(config.keyspaceNotifications.Classes)
+			go publishKeyspaceEvents(subscription, pubSub, config.keyspaceNotifications, cacheNumber)
+			keyspaceUnsubscribers = append(keyspaceUnsubscribers, func() { events.Unsubscribe(id) })
+		}
+	}
+
 	return &server[K, V]{
-		listener:       listener,
-		config:         &ServerConfig{port: port, nbrWorkers: nbrWorkers},
-		logger:         logger,
-		shutdown:       nil,
-		connections:    make(chan Connection),
-		commandManager: commandManager,
-		cacheManager:   cacheManager,
-		executor:       NewExecutor(cacheManager),
+		listener:              listener,
+		config:                config,
+		logger:                logger,
+		shutdown:              nil,
+		executionSlots:        make(chan struct{}, max(config.nbrWorkers, 1)),
+		commandManager:        commandManager,
+		cacheManager:          cacheManager,
+		executor:              NewExecutor(cacheManager, slowLog),
+		monitor:               monitor,
+		pubSub:                pubSub,
+		keyspaceUnsubscribers: keyspaceUnsubscribers,
+		clients:               clients,
 	}, nil
 }
 
@@ -73,46 +135,191 @@ func (server *server[K, V]) acceptConnection() (Connection, Error) {
 			return nil, nil
 		}
 	}
-	return NewTCPConnection(conn, server.logger), nil
+	connection := NewTCPConnection(conn, server.config.timeouts, server.logger)
+	if err := server.checkConnectionLimits(connection); err != nil {
+		reply(connection, err.Display())
+		connection.Close()
+		return nil, err
+	}
+	// Connections are registered as soon as they are accepted so that the ones
+	// waiting for a worker count towards the limits
+	server.clients.Register(connection)
+	return connection, nil
+}
+
+func (server *server[K, V]) checkConnectionLimits(connection Connection) Error {
+	maxConnections := server.config.maxConnections
+	if maxConnections > 0 && server.clients.Len() >= maxConnections {
+		return &ConnectionLimitError{message: fmt.Sprintf("Max number of clients reached (%d)", maxConnections)}
+	}
+	maxConnectionsPerIP := server.config.maxConnectionsPerIP
+	if maxConnectionsPerIP > 0 && server.clients.CountByIP(connectionIP(connection)) >= maxConnectionsPerIP {
+		return &ConnectionLimitError{message: fmt.Sprintf("Max number of clients per IP reached (%d)", maxConnectionsPerIP)}
+	}
+	return nil
+}
+
+// serveConnection handles the connection on its own goroutine: idle
+// connections only cost a blocked read while commands are executed within the
+// execution slots.
+func (server *server[K, V]) serveConnection(connection Connection) {
+	server.wg.Add(1)
+	go func() {
+		defer server.wg.Done()
+		server.handleConnection(connection)
+	}()
 }
 
 func (server *server[K, V]) handleConnection(connection Connection) {
 	defer connection.Close()
+	defer server.clients.Unregister(connection)
 
-	commandString, err := connection.Read()
-	if err != nil {
-		connection.Send(err.Display())
-		return
+	transaction := &transaction[K, V]{}
+	for {
+		commandString, err := connection.Read()
+		if err != nil {
+			return
+		}
+		if !server.handleCommand(connection, transaction, commandString) {
+			return
+		}
+	}
+}
+
+// handleCommand runs a single command received on the connection and sends
+// back its reply, or queues it when a transaction is open. It returns false
+// once the connection should be closed.
+func (server *server[K, V]) handleCommand(connection Connection, transaction *transaction[K, V], commandString string) bool {
+	in := strings.Fields(commandString)
+	if len(in) == 0 {
+		return true
 	}
-	in := strings.Split(strings.TrimSpace(commandString), " ")
 	commandName := strings.ToUpper(in[0])
+	connection.SetLastCommand(commandName)
+	switch commandName {
+	case "QUIT":
+		reply(connection, "OK")
+		return false
+	case "MULTI", "EXEC", "DISCARD", "UNWATCH":
+		return server.handleTransactionCommand(connection, transaction, commandName, in[1:])
+	}
 	command, err := server.commandManager.Get(commandName)
-	if err != nil {
-		connection.Send(err.Display())
-		return
+	if err == nil {
+		var commandInput CommandInput
+		commandInput, err = command.Parse(in[1:])
+		if err == nil {
+			return server.runCommand(connection, transaction, command, commandInput)
+		}
 	}
-	commandInput, err := command.Parse(in[1:])
-	if err != nil {
-		connection.Send(err.Display())
-		return
+	// Commands rejected while queueing make the whole transaction fail
+	if transaction.active {
+		transaction.failed = true
+	}
+	return reply(connection, err.Display()) == nil
+}
+
+// runCommand executes a parsed command, or queues it when a transaction is
+// open.
+func (server *server[K, V]) runCommand(connection Connection, transaction *transaction[K, V], command Command, commandInput CommandInput) bool {
+	commandName := command.GetName()
+	if server.monitor.Len() > 0 {
+		server.monitor.Publish(MonitorEvent{
+			Timestamp: time.Now(),
+			Client:    connection.RemoteAddr().String(),
+			Command:   commandName,
+			Args:      commandInput.Args(),
+		})
 	}
 	executableCommand, ok := command.(ExecutableCommand[K, V])
-	if ok {
-		result, err := server.executor.Execute(executableCommand, commandInput)
-		if err != nil {
-			connection.Send(err.Display())
-		} else {
-			connection.Send(result.String())
-		}
-	} else {
+	if !ok {
 		err := &CommandNotExecutableError{command: commandName}
-		connection.Send(err.Display())
+		return reply(connection, err.Display()) == nil
+	}
+	if transaction.active {
+		if unqueuedCommands[commandName] {
+			transaction.failed = true
+			err := &CommandError{message: commandName + " inside MULTI is not allowed"}
+			return reply(connection, err.Display()) == nil
+		}
+		transaction.queue = append(transaction.queue, QueuedCommand[K, V]{Command: executableCommand, Input: commandInput})
+		return reply(connection, "QUEUED") == nil
+	}
+	server.executionSlots <- struct{}{}
+	result, err := server.executor.Execute(connection, executableCommand, commandInput)
+	<-server.executionSlots
+	if err != nil {
+		return reply(connection, err.Display()) == nil
+	}
+	if watchResult, ok := result.(WatchResult); ok {
+		transaction.watches = append(transaction.watches, watchResult.Checks()...)
+	}
+	if blockingResult, ok := result.(BlockingResult); ok {
+		return blockingResult.Block(connection, server.executor.ExecuteFunc)
+	}
+	if streamingResult, ok := result.(StreamingResult); ok {
+		return streamingResult.Stream(connection)
+	}
+	return reply(connection, result.String()) == nil
+}
+
+// handleTransactionCommand handles the commands opening, running and
+// discarding transactions, which act on the state of the connection.
+func (server *server[K, V]) handleTransactionCommand(connection Connection, transaction *transaction[K, V], commandName string, args []string) bool {
+	if len(args) > 0 {
+		err := &InvalidCommandUsageError{command: commandName}
+		return reply(connection, err.Display()) == nil
+	}
+	switch commandName {
+	case "MULTI":
+		if transaction.active {
+			return reply(connection, "MULTI calls can not be nested") == nil
+		}
+		transaction.active = true
+		return reply(connection, "OK") == nil
+	case "DISCARD":
+		if !transaction.active {
+			return reply(connection, "DISCARD without MULTI") == nil
+		}
+		transaction.reset()
+		return reply(connection, "OK") == nil
+	case "UNWATCH":
+		// The watches are checked by EXEC, so that they cannot be dropped
+		// once the transaction is open
+		if transaction.active {
+			transaction.failed = true
+			err := &CommandError{message: commandName + " inside MULTI is not allowed"}
+			return reply(connection, err.Display()) == nil
+		}
+		transaction.watches = nil
+		return reply(connection, "OK") == nil
+	}
+
+	if !transaction.active {
+		return reply(connection, "EXEC without MULTI") == nil
+	}
+	defer transaction.reset()
+	if transaction.failed {
+		return reply(connection, "Transaction discarded because of previous errors") == nil
+	}
+	if server.monitor.Len() > 0 {
+		server.monitor.Publish(MonitorEvent{
+			Timestamp: time.Now(),
+			Client:    connection.RemoteAddr().String(),
+			Command:   commandName,
+		})
 	}
+	server.executionSlots <- struct{}{}
+	results, ok := server.executor.ExecuteAll(connection, transaction.queue, transaction.watches)
+	<-server.executionSlots
+	if !ok {
+		return reply(connection, (&nilResult[V]{}).String()) == nil
+	}
+	return reply(connection, (&arrayResult[V]{items: results}).String()) == nil
 }
 
 func (server *server[K, V]) CloseConnections() {
-	server.listener.Close()   // Stop accepting new connections
-	close(server.connections) // Close the connections channel
+	server.listener.Close() // Stop accepting new connections
+	server.clients.CloseAll()
 }
 
 func (server *server[K, V]) wait() {
@@ -137,14 +344,9 @@ func (server *server[K, V]) Start(timeout time.Duration) {
 	// Handle graceful shutdown
 	go handleShutdown(server, timeout)
 
-	// Create a worker pool
-	for i := 0; i < server.config.nbrWorkers; i++ {
-		server.wg.Add(1)
-		go worker(server, server.connections)
-	}
-
-	// Accept connections and pass them to the worker pool
-	go acceptConnections(server, server.connections)
+	// Accept connections and serve each of them on its own goroutine
+	server.wg.Add(1)
+	go acceptConnections(server)
 
 	server.wg.Wait()
 }
@@ -155,6 +357,11 @@ func (server *server[K, V]) ShutDownChan() chan os.Signal {
 
 func (server *server[K, V]) ShutDown(timeout time.Duration) {
 	server.Log(InfoLog, "Shutting down gracefully...")
+	server.monitor.Close()
+	for _, unsubscribe := range server.keyspaceUnsubscribers {
+		unsubscribe()
+	}
+	server.pubSub.Close()
 	server.CloseConnections()
 	// Wait for workers to finish with a timeout
 	done := make(chan struct{})
@@ -171,30 +378,36 @@ func (server *server[K, V]) ShutDown(timeout time.Duration) {
 	}
 }
 
-func acceptConnections(server Server, connections chan<- Connection) {
+func acceptConnections(server Server) {
+	defer server.done()
+
 	for {
 		connection, err := server.acceptConnection()
 		if err != nil {
-			server.Log(ErrorLog, err.Error())
+			if _, ok := err.(*ConnectionLimitError); ok {
+				server.Log(WarningLog, fmt.Sprintf("Connection rejected: %s", err.Error()))
+			} else {
+				server.Log(ErrorLog, err.Error())
+			}
+		} else if connection == nil {
+			// The listener has been closed
+			return
 		} else {
-			connections <- connection
+			server.serveConnection(connection)
 		}
 	}
 }
 
-func worker(server Server, connections <-chan Connection) {
-	defer server.done()
-
-	for conn := range connections {
-		server.handleConnection(conn)
-	}
-}
-
 func handleShutdown(server Server, timeout time.Duration) {
 	<-server.ShutDownChan() // Wait for a shutdown signal
 	server.ShutDown(timeout)
 }
 
+// reply sends a newline terminated reply to the client.
+func reply(connection Connection, output string) Error {
+	return connection.Send(output + "\n")
+}
+
 func isClosedConnectionError(err error) bool {
 	return strings.Contains(err.Error(), "use of closed network connection")
 }
@@ -0,0 +1,248 @@
+//go:build unix
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// benchmarkActiveClients is the number of clients sending commands during the
+// server benchmarks, the other connections staying idle. It is also the number
+// of workers of the pool and of execution slots of the server, so that both
+// can serve every active client at once.
+const benchmarkActiveClients = 32
+
+// benchmarkCommand is sent by the clients, it is executed within an execution
+// slot like any other command and replies a single line.
+const benchmarkCommand = "CLIENT ID"
+
+// lateClientTimeout is the time after which a client connecting behind the
+// idle connections is counted as never served.
+const lateClientTimeout = time.Second
+
+// startBenchmarkServer starts a server on a free port and returns its address
+// along with a function stopping it. With pool set, the connections are served
+// by a fixed pool of workers instead of their own goroutine.
+func startBenchmarkServer(b *testing.B, pool bool) (string, func()) {
+	logger := NewLogger(io.Discard, "", 0)
+	cacheManager := NewCacheManager[string, string](logger)
+	if err := cacheManager.SetupMainCache(CacheConfig{Precision: time.Minute}); err != nil {
+		b.Fatalf("unexpected error %v", err)
+	}
+	config := &ServerConfig{
+		nbrWorkers:       benchmarkActiveClients,
+		slowLogThreshold: time.Second,
+		slowLogMaxLen:    128,
+		maxConnections:   100000,
+		pubSubBufferSize: 1024,
+	}
+	s, err := NewServer(config, logger, NewCommandManager(), cacheManager)
+	if err != nil {
+		b.Fatalf("unexpected error %v", err)
+	}
+	server := s.(*server[string, string])
+	server.wg.Add(1)
+	if pool {
+		go acceptIntoPool(server, config.nbrWorkers)
+	} else {
+		go acceptConnections(server)
+	}
+	return server.listener.Addr().String(), func() {
+		server.ShutDown(5 * time.Second)
+	}
+}
+
+// acceptIntoPool serves the connections the way the server did before each of
+// them got its own goroutine: a fixed number of workers each handle a single
+// connection at a time, in the order they were accepted.
+func acceptIntoPool(server *server[string, string], nbrWorkers int) {
+	defer server.done()
+
+	connections := make(chan Connection, server.config.maxConnections)
+	var workers sync.WaitGroup
+	for range nbrWorkers {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for connection := range connections {
+				server.handleConnection(connection)
+			}
+		}()
+	}
+	for {
+		connection, err := server.acceptConnection()
+		if err == nil && connection == nil {
+			// The listener has been closed
+			break
+		}
+		if err == nil {
+			connections <- connection
+		}
+	}
+	close(connections)
+	workers.Wait()
+}
+
+// skipBelowFileLimit skips the benchmark when the open file limit cannot hold
+// both ends of the given number of connections, which all live in this
+// process.
+func skipBelowFileLimit(b *testing.B, connections int) {
+	needed := 2*connections + 64
+	var limit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &limit); err == nil && limit.Cur < uint64(needed) {
+		b.Skipf("needs %d open files, the limit is %d", needed, limit.Cur)
+	}
+}
+
+// openIdleConnections opens connections that never send anything, they are
+// closed at the end of the benchmark.
+func openIdleConnections(b *testing.B, addr string, n int) {
+	for range n {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			b.Fatalf("unexpected error %v", err)
+		}
+		b.Cleanup(func() { conn.Close() })
+	}
+}
+
+// benchmarkServer measures commands sent by a few clients while the given
+// number of other connections stay open without sending anything. Besides
+// the time per command, it reports the throughput and the 99th percentile of
+// the latency seen by the clients.
+//
+// The active clients connect before the idle ones, which is the only order in
+// which the pool serves them: benchmarkLateClient measures the other one.
+func benchmarkServer(b *testing.B, pool bool, idleConnections int) {
+	skipBelowFileLimit(b, idleConnections+benchmarkActiveClients)
+
+	addr, stop := startBenchmarkServer(b, pool)
+	defer stop()
+	clients := make([]*bufio.ReadWriter, benchmarkActiveClients)
+	for i := range clients {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			b.Fatalf("unexpected error %v", err)
+		}
+		defer conn.Close()
+		clients[i] = bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+		// Wait for the reply so that the client is being served
+		if err := roundTrip(clients[i], benchmarkCommand); err != nil {
+			b.Fatalf("unexpected error %v", err)
+		}
+	}
+	openIdleConnections(b, addr, idleConnections)
+
+	latencies := make([]time.Duration, b.N)
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	b.ResetTimer()
+	startedAt := time.Now()
+	for _, client := range clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := int(next.Add(1) - 1); i < b.N; i = int(next.Add(1) - 1) {
+				sentAt := time.Now()
+				if err := roundTrip(client, benchmarkCommand); err != nil {
+					b.Error(err)
+					return
+				}
+				latencies[i] = time.Since(sentAt)
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(startedAt)
+	b.StopTimer()
+
+	slices.Sort(latencies)
+	b.ReportMetric(float64(b.N)/elapsed.Seconds(), "ops/s")
+	b.ReportMetric(float64(latencies[len(latencies)*99/100].Nanoseconds())/1e3, "p99-µs")
+}
+
+// benchmarkLateClient measures how long a client connecting after the given
+// number of idle connections waits for the reply to its first command, the
+// connection included. The pool hands its workers to the connections in the
+// order they were accepted, so that it never serves a client coming after as
+// many idle connections as it has workers: the clients not served within
+// lateClientTimeout are reported as starved.
+func benchmarkLateClient(b *testing.B, pool bool, idleConnections int) {
+	skipBelowFileLimit(b, idleConnections+1)
+
+	addr, stop := startBenchmarkServer(b, pool)
+	defer stop()
+	openIdleConnections(b, addr, idleConnections)
+
+	latencies := make([]time.Duration, b.N)
+	starved := 0
+	b.ResetTimer()
+	for i := range b.N {
+		connectedAt := time.Now()
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			b.Fatalf("unexpected error %v", err)
+		}
+		conn.SetDeadline(connectedAt.Add(lateClientTimeout))
+		err = roundTrip(bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn)), benchmarkCommand)
+		latencies[i] = time.Since(connectedAt)
+		conn.Close()
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			starved++
+		} else if err != nil {
+			b.Fatalf("unexpected error %v", err)
+		}
+	}
+	b.StopTimer()
+
+	slices.Sort(latencies)
+	b.ReportMetric(float64(latencies[len(latencies)*99/100].Nanoseconds())/1e3, "p99-µs")
+	b.ReportMetric(float64(starved)/float64(b.N), "starved/op")
+}
+
+// roundTrip sends a command and reads its reply, which fits on one line.
+func roundTrip(client *bufio.ReadWriter, command string) error {
+	if _, err := client.WriteString(command + "\n"); err != nil {
+		return err
+	}
+	if err := client.Flush(); err != nil {
+		return err
+	}
+	_, err := client.ReadString('\n')
+	return err
+}
+
+// BenchmarkServerLateClient compares how the server and the pool serve a client
+// connecting once the other connections are open and idle.
+func BenchmarkServerLateClient(b *testing.B) {
+	for _, mode := range []string{"core", "pool"} {
+		for _, idleConnections := range []int{0, 1000, 5000, 20000} {
+			b.Run(fmt.Sprintf("%s/idle=%d", mode, idleConnections), func(b *testing.B) {
+				benchmarkLateClient(b, mode == "pool", idleConnections)
+			})
+		}
+	}
+}
+
+// BenchmarkServer compares the server, serving each connection on its own
+// goroutine, with the fixed pool of workers it replaced, under the same load.
+func BenchmarkServer(b *testing.B) {
+	for _, mode := range []string{"core", "pool"} {
+		for _, idleConnections := range []int{0, 1000, 5000, 20000} {
+			b.Run(fmt.Sprintf("%s/idle=%d", mode, idleConnections), func(b *testing.B) {
+				benchmarkServer(b, mode == "pool", idleConnections)
+			})
+		}
+	}
+}
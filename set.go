@@ -11,16 +11,22 @@ func NewSet[K comparable]() *Set[K] {
 	return &Set[K]{items: make(map[K]struct{})}
 }
 
-func (s *Set[K]) Add(key K) {
+// Add adds the key and returns whether it was not in the set yet.
+func (s *Set[K]) Add(key K) bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	_, exists := s.items[key]
 	s.items[key] = struct{}{}
+	return !exists
 }
 
-func (s *Set[K]) Remove(key K) {
+// Remove removes the key and returns whether it was in the set.
+func (s *Set[K]) Remove(key K) bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	_, exists := s.items[key]
 	delete(s.items, key)
+	return exists
 }
 
 func (s *Set[K]) Contains(key K) bool {
@@ -53,3 +59,53 @@ func (s *Set[K]) AddMany(keys []K) {
 		s.items[k] = struct{}{}
 	}
 }
+
+// Union returns a new set holding the keys of all the sets.
+func Union[K comparable](sets ...*Set[K]) *Set[K] {
+	union := NewSet[K]()
+	for _, set := range sets {
+		union.AddMany(set.Items())
+	}
+	return union
+}
+
+// Intersection returns a new set holding the keys found in every set, it is
+// empty when no set is given.
+func Intersection[K comparable](sets ...*Set[K]) *Set[K] {
+	intersection := NewSet[K]()
+	if len(sets) == 0 {
+		return intersection
+	}
+	for _, key := range sets[0].Items() {
+		inAll := true
+		for _, set := range sets[1:] {
+			if !set.Contains(key) {
+				inAll = false
+				break
+			}
+		}
+		if inAll {
+			intersection.Add(key)
+		}
+	}
+	return intersection
+}
+
+// Difference returns a new set holding the keys of first found in none of the
+// others.
+func Difference[K comparable](first *Set[K], others ...*Set[K]) *Set[K] {
+	difference := NewSet[K]()
+	for _, key := range first.Items() {
+		inOther := false
+		for _, set := range others {
+			if set.Contains(key) {
+				inOther = true
+				break
+			}
+		}
+		if !inOther {
+			difference.Add(key)
+		}
+	}
+	return difference
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"slices"
+	"time"
+)
+
+// Sets holds the sets of the cache. Sets lock themselves so they are read
+// without going through Compute, only the writes creating or deleting them do.
+type Sets interface {
+	Add(key string, members []string) (int, Error)
+	Remove(key string, members []string) (int, Error)
+	Get(key string) (*Set[string], Error)
+}
+
+type sets struct {
+	cache Cache[string, Value]
+}
+
+func NewSets(cache Cache[string, Value]) Sets {
+	return &sets{cache: cache}
+}
+
+// Add adds the members to the set, which is created when needed, and returns
+// the number of members added.
+func (s *sets) Add(key string, members []string) (int, Error) {
+	added := 0
+	_, err := computeAs(s.cache, key, SetData, func(current **Set[string]) (*Set[string], ComputeAction, Error) {
+		set := NewSet[string]()
+		if current != nil {
+			set = *current
+		}
+		for _, member := range members {
+			if set.Add(member) {
+				added++
+			}
+		}
+		if added == 0 {
+			return set, KeepValue, nil
+		}
+		return set, StoreValue, nil
+	}, time.Time{})
+	return added, err
+}
+
+// Remove removes the members from the set, which is deleted once empty, and
+// returns the number of members removed.
+func (s *sets) Remove(key string, members []string) (int, Error) {
+	removed := 0
+	_, err := computeAs(s.cache, key, SetData, func(current **Set[string]) (*Set[string], ComputeAction, Error) {
+		if current == nil {
+			return nil, KeepValue, nil
+		}
+		set := *current
+		for _, member := range members {
+			if set.Remove(member) {
+				removed++
+			}
+		}
+		if set.Len() == 0 {
+			return set, DeleteValue, nil
+		}
+		if removed == 0 {
+			return set, KeepValue, nil
+		}
+		return set, StoreValue, nil
+	}, time.Time{})
+	return removed, err
+}
+
+// Get returns the set of the key, an empty one when it does not exist.
+func (s *sets) Get(key string) (*Set[string], Error) {
+	value, ok := s.cache.Get(key)
+	if !ok {
+		return NewSet[string](), nil
+	}
+	return valueAs[*Set[string]](*value, SetData)
+}
+
+// membersResult replies the members sorted, so that replies do not depend on
+// the order of the map holding them.
+func membersResult(set *Set[string]) Result[Value] {
+	members := set.Items()
+	slices.Sort(members)
+	items := make([]Result[Value], len(members))
+	for i, member := range members {
+		items[i] = &valueResult[Value]{value: StringValue(member)}
+	}
+	return &arrayResult[Value]{items: items}
+}
+
+type saddCommand struct {
+	Command
+	sets Sets
+}
+
+func NewSAddCommand(sets Sets) ExecutableCommand[string, Value] {
+	return &saddCommand{
+		Command: NewCommand("SADD").
+			WithArgument(KeyCommandArgument).
+			WithArgument(MembersArgument),
+		sets: sets,
+	}
+}
+
+func (c *saddCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	members := toStrings(input.GetArgument(*MembersArgument).([]any))
+	added, err := c.sets.Add(key, members)
+	if err != nil {
+		return nil, err
+	}
+	return &integerResult[Value]{value: int64(added)}, nil
+}
+
+type sremCommand struct {
+	Command
+	sets Sets
+}
+
+func NewSRemCommand(sets Sets) ExecutableCommand[string, Value] {
+	return &sremCommand{
+		Command: NewCommand("SREM").
+			WithArgument(KeyCommandArgument).
+			WithArgument(MembersArgument),
+		sets: sets,
+	}
+}
+
+func (c *sremCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	members := toStrings(input.GetArgument(*MembersArgument).([]any))
+	removed, err := c.sets.Remove(key, members)
+	if err != nil {
+		return nil, err
+	}
+	return &integerResult[Value]{value: int64(removed)}, nil
+}
+
+type smembersCommand struct {
+	Command
+	sets Sets
+}
+
+func NewSMembersCommand(sets Sets) ExecutableCommand[string, Value] {
+	return &smembersCommand{
+		Command: NewCommand("SMEMBERS").
+			WithArgument(KeyCommandArgument),
+		sets: sets,
+	}
+}
+
+func (c *smembersCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	set, err := c.sets.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	return membersResult(set), nil
+}
+
+type sismemberCommand struct {
+	Command
+	sets Sets
+}
+
+func NewSIsMemberCommand(sets Sets) ExecutableCommand[string, Value] {
+	return &sismemberCommand{
+		Command: NewCommand("SISMEMBER").
+			WithArgument(KeyCommandArgument).
+			WithArgument(MemberArgument),
+		sets: sets,
+	}
+}
+
+func (c *sismemberCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	member := input.GetArgument(*MemberArgument).(string)
+	set, err := c.sets.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	return booleanResult[Value](set.Contains(member)), nil
+}
+
+// setOperationCommand replies the result of an operation over the sets of the
+// keys, missing keys counting as empty sets.
+type setOperationCommand struct {
+	Command
+	sets      Sets
+	operation func(...*Set[string]) *Set[string]
+}
+
+func NewSInterCommand(sets Sets) ExecutableCommand[string, Value] {
+	return &setOperationCommand{
+		Command: NewCommand("SINTER").
+			WithArgument(KeysArgument),
+		sets:      sets,
+		operation: Intersection[string],
+	}
+}
+
+func NewSUnionCommand(sets Sets) ExecutableCommand[string, Value] {
+	return &setOperationCommand{
+		Command: NewCommand("SUNION").
+			WithArgument(KeysArgument),
+		sets:      sets,
+		operation: Union[string],
+	}
+}
+
+// NewSDiffCommand replies the members of the first set found in none of the
+// others.
+func NewSDiffCommand(sets Sets) ExecutableCommand[string, Value] {
+	return &setOperationCommand{
+		Command: NewCommand("SDIFF").
+			WithArgument(KeysArgument),
+		sets: sets,
+		operation: func(sets ...*Set[string]) *Set[string] {
+			return Difference(sets[0], sets[1:]...)
+		},
+	}
+}
+
+func (c *setOperationCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	keys := toStrings(input.GetArgument(*KeysArgument).([]any))
+	sets := make([]*Set[string], len(keys))
+	for i, key := range keys {
+		set, err := c.sets.Get(key)
+		if err != nil {
+			return nil, err
+		}
+		sets[i] = set
+	}
+	return membersResult(c.operation(sets...)), nil
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	SlowLogMaxArgs      = 32
+	SlowLogMaxArgLength = 128
+)
+
+type SlowLogEntry struct {
+	ID        int64
+	Timestamp time.Time
+	Duration  time.Duration
+	Client    string
+	Command   string
+	Args      []string
+}
+
+type SlowLog interface {
+	Add(client string, command string, args []string, startedAt time.Time, duration time.Duration)
+	Get(int) []SlowLogEntry
+	Len() int
+	Reset()
+	Threshold() time.Duration
+}
+
+type slowLog struct {
+	mu        sync.Mutex
+	entries   []SlowLogEntry
+	maxLen    int
+	threshold time.Duration
+	nextID    int64
+	logger    Logger
+}
+
+// NewSlowLog creates a slow log keeping at most maxLen commands that took
+// longer than threshold. A negative threshold disables the slow log.
+func NewSlowLog(threshold time.Duration, maxLen int, logger Logger) (SlowLog, Error) {
+	if maxLen < 0 {
+		err := &SetupError{message: "Invalid slow log length: must be positive"}
+		logger.Error(fmt.Sprintf("[SLOWLOG_EVENT] %s", err.Error()))
+		return nil, err
+	}
+
+	logger.Info(fmt.Sprintf("[SLOWLOG_EVENT] Initializing slow log with threshold %v and max length %d", threshold, maxLen))
+	return &slowLog{
+		entries:   make([]SlowLogEntry, 0, maxLen),
+		maxLen:    maxLen,
+		threshold: threshold,
+		logger:    logger,
+	}, nil
+}
+
+func (s *slowLog) Add(client string, command string, args []string, startedAt time.Time, duration time.Duration) {
+	if s.threshold < 0 || duration < s.threshold || s.maxLen == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry := SlowLogEntry{
+		ID:        s.nextID,
+		Timestamp: startedAt,
+		Duration:  duration,
+		Client:    client,
+		Command:   command,
+		Args:      truncateArgs(args),
+	}
+	s.nextID++
+	// Newest entries come first, the oldest one is dropped once full
+	if len(s.entries) == s.maxLen {
+		s.entries = s.entries[:s.maxLen-1]
+	}
+	s.entries = append([]SlowLogEntry{entry}, s.entries...)
+	s.logger.Warning(fmt.Sprintf("[SLOWLOG_EVENT] [%s] %s took %v", client, command, duration))
+}
+
+// Get returns up to count of the most recent entries, or all of them when
+// count is negative.
+func (s *slowLog) Get(count int) []SlowLogEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if count < 0 || count > len(s.entries) {
+		count = len(s.entries)
+	}
+	entries := make([]SlowLogEntry, count)
+	copy(entries, s.entries[:count])
+	return entries
+}
+
+func (s *slowLog) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+func (s *slowLog) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = s.entries[:0]
+	s.logger.Info("[SLOWLOG_EVENT] Slow log reset")
+}
+
+func (s *slowLog) Threshold() time.Duration {
+	return s.threshold
+}
+
+func truncateArgs(args []string) []string {
+	truncated := make([]string, 0, min(len(args), SlowLogMaxArgs))
+	for i, arg := range args {
+		if i == SlowLogMaxArgs-1 && len(args) > SlowLogMaxArgs {
+			truncated = append(truncated, fmt.Sprintf("... (%d more arguments)", len(args)-i))
+			break
+		}
+		if len(arg) > SlowLogMaxArgLength {
+			arg = fmt.Sprintf("%s... (%d more bytes)", arg[:SlowLogMaxArgLength], len(arg)-SlowLogMaxArgLength)
+		}
+		truncated = append(truncated, arg)
+	}
+	return truncated
+}
+
+type slowLogCommand[K comparable, V any] struct {
+	Command
+	slowLog SlowLog
+}
+
+func NewSlowLogCommand[K comparable, V any](slowLog SlowLog) ExecutableCommand[K, V] {
+	return &slowLogCommand[K, V]{
+		Command: NewCommand("SLOWLOG").
+			WithArgument(SubcommandArgument).
+			WithArgument(CountArgument),
+		slowLog: slowLog,
+	}
+}
+
+func (c *slowLogCommand[K, V]) Run(input CommandInput, cache Cache[K, V]) (Result[V], Error) {
+	subcommand := input.GetArgument(*SubcommandArgument).(string)
+	switch strings.ToUpper(subcommand) {
+	case "GET":
+		count := 10
+		if value := input.GetArgument(*CountArgument); value != nil {
+			count = value.(int)
+		}
+		entries := c.slowLog.Get(count)
+		items := make([]Result[V], 0, len(entries))
+		for _, entry := range entries {
+			args := make([]Result[V], 0, len(entry.Args)+1)
+			args = append(args, &textResult[V]{text: entry.Command})
+			for _, arg := range entry.Args {
+				args = append(args, &textResult[V]{text: arg})
+			}
+			items = append(items, &arrayResult[V]{items: []Result[V]{
+				&integerResult[V]{value: entry.ID},
+				&integerResult[V]{value: entry.Timestamp.Unix()},
+				&integerResult[V]{value: entry.Duration.Microseconds()},
+				&arrayResult[V]{items: args},
+				&textResult[V]{text: entry.Client},
+			}})
+		}
+		return &arrayResult[V]{items: items}, nil
+	case "LEN":
+		return &integerResult[V]{value: int64(c.slowLog.Len())}, nil
+	case "RESET":
+		c.slowLog.Reset()
+		return &okResult[V]{}, nil
+	default:
+		return nil, &InvalidCommandUsageError{command: c.GetName()}
+	}
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"cmp"
+	"math/rand/v2"
+)
+
+// A node gets one more level with a probability of skipListP, up to
+// maxSkipListLevel levels which is enough for 4^32 members.
+const (
+	maxSkipListLevel = 32
+	skipListP        = 0.25
+)
+
+type skipListLevel[M cmp.Ordered] struct {
+	forward *skipListNode[M]
+	// span is the number of nodes the forward link goes past, used to
+	// compute ranks
+	span int
+}
+
+type skipListNode[M cmp.Ordered] struct {
+	member M
+	score  float64
+	levels []skipListLevel[M]
+}
+
+// ScoredMember is a member of a sorted set along with its score.
+type ScoredMember[M cmp.Ordered] struct {
+	Member M
+	Score  float64
+}
+
+// ScoreBound is the bound of a score range, excluded when Exclusive is set.
+type ScoreBound struct {
+	Score     float64
+	Exclusive bool
+}
+
+// SortedSet keeps members ordered by score, then by member for equal scores,
+// in a skip list indexed by a map of the scores. Adding, removing and ranking
+// a member take O(log n).
+type SortedSet[M cmp.Ordered] struct {
+	scores map[M]float64
+	head   *skipListNode[M]
+	level  int
+	// length is the number of nodes in the skip list, which lags behind the
+	// scores while a member is moved
+	length int
+}
+
+func NewSortedSet[M cmp.Ordered]() *SortedSet[M] {
+	return &SortedSet[M]{
+		scores: make(map[M]float64),
+		head:   &skipListNode[M]{levels: make([]skipListLevel[M], maxSkipListLevel)},
+		level:  1,
+	}
+}
+
+func (s *SortedSet[M]) Len() int {
+	return len(s.scores)
+}
+
+func (s *SortedSet[M]) Score(member M) (float64, bool) {
+	score, ok := s.scores[member]
+	return score, ok
+}
+
+// Add sets the score of the member and returns whether it is new.
+func (s *SortedSet[M]) Add(member M, score float64) bool {
+	current, exists := s.scores[member]
+	if exists {
+		if current == score {
+			return false
+		}
+		s.delete(member, current)
+	}
+	s.insert(member, score)
+	s.scores[member] = score
+	return !exists
+}
+
+// IncrBy adds delta to the score of the member, which starts at 0 when it is
+// new, and returns the new score.
+func (s *SortedSet[M]) IncrBy(member M, delta float64) float64 {
+	score := s.scores[member] + delta
+	s.Add(member, score)
+	return score
+}
+
+func (s *SortedSet[M]) Remove(member M) bool {
+	score, exists := s.scores[member]
+	if !exists {
+		return false
+	}
+	s.delete(member, score)
+	delete(s.scores, member)
+	return true
+}
+
+// Rank returns the position of the member starting from 0 for the lowest
+// score.
+func (s *SortedSet[M]) Rank(member M) (int, bool) {
+	score, exists := s.scores[member]
+	if !exists {
+		return 0, false
+	}
+	rank := 0
+	x := s.head
+	for i := s.level - 1; i >= 0; i-- {
+		for next := x.levels[i].forward; next != nil && !before(member, score, next); next = x.levels[i].forward {
+			rank += x.levels[i].span
+			x = next
+		}
+	}
+	return rank - 1, true
+}
+
+// Range returns the members from rank start to stop included, negative ranks
+// counting from the highest score.
+func (s *SortedSet[M]) Range(start, stop int) []ScoredMember[M] {
+	length := s.Len()
+	if start < 0 {
+		start = max(length+start, 0)
+	}
+	if stop < 0 {
+		stop = length + stop
+	}
+	stop = min(stop, length-1)
+	if start > stop {
+		return nil
+	}
+	members := make([]ScoredMember[M], 0, stop-start+1)
+	for x := s.byRank(start + 1); x != nil && len(members) < stop-start+1; x = x.levels[0].forward {
+		members = append(members, ScoredMember[M]{Member: x.member, Score: x.score})
+	}
+	return members
+}
+
+// RangeByScore returns the members whose score is between min and max, in
+// order.
+func (s *SortedSet[M]) RangeByScore(min, max ScoreBound) []ScoredMember[M] {
+	var members []ScoredMember[M]
+	for x := s.firstAbove(min); x != nil && belowMax(x.score, max); x = x.levels[0].forward {
+		members = append(members, ScoredMember[M]{Member: x.member, Score: x.score})
+	}
+	return members
+}
+
+// FirstByScore returns up to count members whose score is above min, in order.
+func (s *SortedSet[M]) FirstByScore(min ScoreBound, count int) []ScoredMember[M] {
+	var members []ScoredMember[M]
+	for x := s.firstAbove(min); x != nil && len(members) < count; x = x.levels[0].forward {
+		members = append(members, ScoredMember[M]{Member: x.member, Score: x.score})
+	}
+	return members
+}
+
+// firstAbove returns the first node whose score is above min, nil when there
+// is none.
+func (s *SortedSet[M]) firstAbove(min ScoreBound) *skipListNode[M] {
+	x := s.head
+	for i := s.level - 1; i >= 0; i-- {
+		for next := x.levels[i].forward; next != nil && !aboveMin(next.score, min); next = x.levels[i].forward {
+			x = next
+		}
+	}
+	return x.levels[0].forward
+}
+
+func aboveMin(score float64, min ScoreBound) bool {
+	if min.Exclusive {
+		return score > min.Score
+	}
+	return score >= min.Score
+}
+
+func belowMax(score float64, max ScoreBound) bool {
+	if max.Exclusive {
+		return score < max.Score
+	}
+	return score <= max.Score
+}
+
+// before tells whether the member with the score comes before the node, which
+// is where the search for the member stops.
+func before[M cmp.Ordered](member M, score float64, node *skipListNode[M]) bool {
+	return node.score > score || (node.score == score && node.member > member)
+}
+
+// byRank returns the node at the rank starting from 1, nil when out of range.
+func (s *SortedSet[M]) byRank(rank int) *skipListNode[M] {
+	traversed := 0
+	x := s.head
+	for i := s.level - 1; i >= 0; i-- {
+		for x.levels[i].forward != nil && traversed+x.levels[i].span <= rank {
+			traversed += x.levels[i].span
+			x = x.levels[i].forward
+		}
+		if traversed == rank {
+			return x
+		}
+	}
+	return nil
+}
+
+func randomLevel() int {
+	level := 1
+	for level < maxSkipListLevel && rand.Float64() < skipListP {
+		level++
+	}
+	return level
+}
+
+func (s *SortedSet[M]) insert(member M, score float64) {
+	var update [maxSkipListLevel]*skipListNode[M]
+	var rank [maxSkipListLevel]int
+	x := s.head
+	for i := s.level - 1; i >= 0; i-- {
+		if i < s.level-1 {
+			rank[i] = rank[i+1]
+		}
+		for next := x.levels[i].forward; next != nil && !before(member, score, next); next = x.levels[i].forward {
+			rank[i] += x.levels[i].span
+			x = next
+		}
+		update[i] = x
+	}
+	level := randomLevel()
+	if level > s.level {
+		for i := s.level; i < level; i++ {
+			update[i] = s.head
+			update[i].levels[i].span = s.length
+		}
+		s.level = level
+	}
+	x = &skipListNode[M]{member: member, score: score, levels: make([]skipListLevel[M], level)}
+	for i := 0; i < level; i++ {
+		x.levels[i].forward = update[i].levels[i].forward
+		update[i].levels[i].forward = x
+		x.levels[i].span = update[i].levels[i].span - (rank[0] - rank[i])
+		update[i].levels[i].span = rank[0] - rank[i] + 1
+	}
+	for i := level; i < s.level; i++ {
+		update[i].levels[i].span++
+	}
+	s.length++
+}
+
+func (s *SortedSet[M]) delete(member M, score float64) {
+	var update [maxSkipListLevel]*skipListNode[M]
+	x := s.head
+	for i := s.level - 1; i >= 0; i-- {
+		for next := x.levels[i].forward; next != nil && (next.score < score || (next.score == score && next.member < member)); next = x.levels[i].forward {
+			x = next
+		}
+		update[i] = x
+	}
+	x = x.levels[0].forward
+	if x == nil || x.score != score || x.member != member {
+		return
+	}
+	for i := 0; i < s.level; i++ {
+		if update[i].levels[i].forward == x {
+			update[i].levels[i].span += x.levels[i].span - 1
+			update[i].levels[i].forward = x.levels[i].forward
+		} else {
+			update[i].levels[i].span--
+		}
+	}
+	for s.level > 1 && s.head.levels[s.level-1].forward == nil {
+		s.level--
+	}
+	s.length--
+}
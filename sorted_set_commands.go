@@ -0,0 +1,346 @@
+package main
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SortedSets holds the sorted sets of the cache.
+type SortedSets interface {
+	Add(key string, members []ScoredMember[string]) (int, Error)
+	IncrBy(key string, member string, delta float64) (float64, Error)
+	Remove(key string, members []string) (int, Error)
+	Range(key string, start, stop int) ([]ScoredMember[string], Error)
+	RangeByScore(key string, min, max ScoreBound) ([]ScoredMember[string], Error)
+	Rank(key string, member string) (int, bool, Error)
+}
+
+type sortedSets struct {
+	cache Cache[string, Value]
+}
+
+func NewSortedSets(cache Cache[string, Value]) SortedSets {
+	return &sortedSets{cache: cache}
+}
+
+// read runs read on the sorted set of the key when it exists.
+func (s *sortedSets) read(key string, read func(set *SortedSet[string])) Error {
+	_, err := computeAs(s.cache, key, SortedSetData, func(current **SortedSet[string]) (*SortedSet[string], ComputeAction, Error) {
+		if current != nil {
+			read(*current)
+		}
+		return nil, KeepValue, nil
+	}, time.Time{})
+	return err
+}
+
+// Add sets the scores of the members, the sorted set is created when needed.
+// It returns the number of members added.
+func (s *sortedSets) Add(key string, members []ScoredMember[string]) (int, Error) {
+	added := 0
+	_, err := computeAs(s.cache, key, SortedSetData, func(current **SortedSet[string]) (*SortedSet[string], ComputeAction, Error) {
+		set := NewSortedSet[string]()
+		if current != nil {
+			set = *current
+		}
+		for _, member := range members {
+			if set.Add(member.Member, member.Score) {
+				added++
+			}
+		}
+		return set, StoreValue, nil
+	}, time.Time{})
+	return added, err
+}
+
+// IncrBy adds delta to the score of the member, a missing member counts as 0.
+func (s *sortedSets) IncrBy(key string, member string, delta float64) (float64, Error) {
+	var score float64
+	_, err := computeAs(s.cache, key, SortedSetData, func(current **SortedSet[string]) (*SortedSet[string], ComputeAction, Error) {
+		set := NewSortedSet[string]()
+		if current != nil {
+			set = *current
+		}
+		currentScore, _ := set.Score(member)
+		if sum := currentScore + delta; math.IsNaN(sum) || math.IsInf(sum, 0) {
+			return nil, KeepValue, &OverflowError{command: "ZINCRBY"}
+		}
+		score = set.IncrBy(member, delta)
+		return set, StoreValue, nil
+	}, time.Time{})
+	return score, err
+}
+
+// Remove removes the members, the sorted set is deleted once empty. It returns
+// the number of members removed.
+func (s *sortedSets) Remove(key string, members []string) (int, Error) {
+	removed := 0
+	_, err := computeAs(s.cache, key, SortedSetData, func(current **SortedSet[string]) (*SortedSet[string], ComputeAction, Error) {
+		if current == nil {
+			return nil, KeepValue, nil
+		}
+		set := *current
+		for _, member := range members {
+			if set.Remove(member) {
+				removed++
+			}
+		}
+		if set.Len() == 0 {
+			return set, DeleteValue, nil
+		}
+		if removed == 0 {
+			return set, KeepValue, nil
+		}
+		return set, StoreValue, nil
+	}, time.Time{})
+	return removed, err
+}
+
+func (s *sortedSets) Range(key string, start, stop int) ([]ScoredMember[string], Error) {
+	var members []ScoredMember[string]
+	err := s.read(key, func(set *SortedSet[string]) {
+		members = set.Range(start, stop)
+	})
+	return members, err
+}
+
+func (s *sortedSets) RangeByScore(key string, min, max ScoreBound) ([]ScoredMember[string], Error) {
+	var members []ScoredMember[string]
+	err := s.read(key, func(set *SortedSet[string]) {
+		members = set.RangeByScore(min, max)
+	})
+	return members, err
+}
+
+func (s *sortedSets) Rank(key string, member string) (int, bool, Error) {
+	rank, ok := 0, false
+	err := s.read(key, func(set *SortedSet[string]) {
+		rank, ok = set.Rank(member)
+	})
+	return rank, ok, err
+}
+
+func formatScore(score float64) string {
+	return strconv.FormatFloat(score, 'f', -1, 64)
+}
+
+// parseScore reads a finite score.
+func parseScore(token string) (float64, bool) {
+	score, err := strconv.ParseFloat(token, 64)
+	if err != nil || math.IsNaN(score) || math.IsInf(score, 0) {
+		return 0, false
+	}
+	return score, true
+}
+
+// parseScoreBound reads a bound of ZRANGEBYSCORE: a score, excluded when it
+// starts with '(', or -inf and +inf.
+func parseScoreBound(token string) (ScoreBound, bool) {
+	var bound ScoreBound
+	if strings.HasPrefix(token, "(") {
+		bound.Exclusive = true
+		token = token[1:]
+	}
+	switch strings.ToLower(token) {
+	case "-inf":
+		bound.Score = math.Inf(-1)
+		return bound, true
+	case "+inf", "inf":
+		bound.Score = math.Inf(1)
+		return bound, true
+	}
+	score, ok := parseScore(token)
+	bound.Score = score
+	return bound, ok
+}
+
+// scoredMembersResult replies the members, each followed by its score when
+// withScores is set.
+func scoredMembersResult(members []ScoredMember[string], withScores bool) Result[Value] {
+	items := make([]Result[Value], 0, len(members))
+	for _, member := range members {
+		items = append(items, &valueResult[Value]{value: StringValue(member.Member)})
+		if withScores {
+			items = append(items, &valueResult[Value]{value: StringValue(formatScore(member.Score))})
+		}
+	}
+	return &arrayResult[Value]{items: items}
+}
+
+type zaddCommand struct {
+	Command
+	sortedSets SortedSets
+}
+
+func NewZAddCommand(sortedSets SortedSets) ExecutableCommand[string, Value] {
+	return &zaddCommand{
+		Command: NewCommand("ZADD").
+			WithArgument(KeyCommandArgument).
+			WithArgument(ScoreMembersArgument),
+		sortedSets: sortedSets,
+	}
+}
+
+func (c *zaddCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	tokens := toStrings(input.GetArgument(*ScoreMembersArgument).([]any))
+	if len(tokens)%2 != 0 {
+		return nil, &InvalidCommandUsageError{command: c.GetName()}
+	}
+	members := make([]ScoredMember[string], 0, len(tokens)/2)
+	for i := 0; i < len(tokens); i += 2 {
+		score, ok := parseScore(tokens[i])
+		if !ok {
+			return nil, &ValueTypeError{valueType: TypeFloat}
+		}
+		members = append(members, ScoredMember[string]{Member: tokens[i+1], Score: score})
+	}
+	added, err := c.sortedSets.Add(key, members)
+	if err != nil {
+		return nil, err
+	}
+	return &integerResult[Value]{value: int64(added)}, nil
+}
+
+type zincrbyCommand struct {
+	Command
+	sortedSets SortedSets
+}
+
+func NewZIncrByCommand(sortedSets SortedSets) ExecutableCommand[string, Value] {
+	return &zincrbyCommand{
+		Command: NewCommand("ZINCRBY").
+			WithArgument(KeyCommandArgument).
+			WithArgument(FloatIncrementArgument).
+			WithArgument(IncrementedMemberArgument),
+		sortedSets: sortedSets,
+	}
+}
+
+func (c *zincrbyCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	increment := input.GetArgument(*FloatIncrementArgument).(float64)
+	member := input.GetArgument(*IncrementedMemberArgument).(string)
+	if math.IsNaN(increment) || math.IsInf(increment, 0) {
+		return nil, &ValueTypeError{valueType: TypeFloat}
+	}
+	score, err := c.sortedSets.IncrBy(key, member, increment)
+	if err != nil {
+		return nil, err
+	}
+	return &valueResult[Value]{value: StringValue(formatScore(score))}, nil
+}
+
+type zremCommand struct {
+	Command
+	sortedSets SortedSets
+}
+
+func NewZRemCommand(sortedSets SortedSets) ExecutableCommand[string, Value] {
+	return &zremCommand{
+		Command: NewCommand("ZREM").
+			WithArgument(KeyCommandArgument).
+			WithArgument(MembersArgument),
+		sortedSets: sortedSets,
+	}
+}
+
+func (c *zremCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	members := toStrings(input.GetArgument(*MembersArgument).([]any))
+	removed, err := c.sortedSets.Remove(key, members)
+	if err != nil {
+		return nil, err
+	}
+	return &integerResult[Value]{value: int64(removed)}, nil
+}
+
+type zrangeCommand struct {
+	Command
+	sortedSets SortedSets
+}
+
+func NewZRangeCommand(sortedSets SortedSets) ExecutableCommand[string, Value] {
+	return &zrangeCommand{
+		Command: NewCommand("ZRANGE").
+			WithArgument(KeyCommandArgument).
+			WithArgument(StartArgument).
+			WithArgument(StopArgument).
+			WithOption(WithScoresOption),
+		sortedSets: sortedSets,
+	}
+}
+
+func (c *zrangeCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	start := input.GetArgument(*StartArgument).(int)
+	stop := input.GetArgument(*StopArgument).(int)
+	withScores := input.GetOption(*WithScoresOption) != nil
+	members, err := c.sortedSets.Range(key, start, stop)
+	if err != nil {
+		return nil, err
+	}
+	return scoredMembersResult(members, withScores), nil
+}
+
+type zrangeByScoreCommand struct {
+	Command
+	sortedSets SortedSets
+}
+
+func NewZRangeByScoreCommand(sortedSets SortedSets) ExecutableCommand[string, Value] {
+	return &zrangeByScoreCommand{
+		Command: NewCommand("ZRANGEBYSCORE").
+			WithArgument(KeyCommandArgument).
+			WithArgument(MinScoreArgument).
+			WithArgument(MaxScoreArgument).
+			WithOption(WithScoresOption),
+		sortedSets: sortedSets,
+	}
+}
+
+func (c *zrangeByScoreCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	min, minOk := parseScoreBound(input.GetArgument(*MinScoreArgument).(string))
+	max, maxOk := parseScoreBound(input.GetArgument(*MaxScoreArgument).(string))
+	if !minOk || !maxOk {
+		return nil, &ValueTypeError{valueType: TypeFloat}
+	}
+	withScores := input.GetOption(*WithScoresOption) != nil
+	members, err := c.sortedSets.RangeByScore(key, min, max)
+	if err != nil {
+		return nil, err
+	}
+	return scoredMembersResult(members, withScores), nil
+}
+
+type zrankCommand struct {
+	Command
+	sortedSets SortedSets
+}
+
+func NewZRankCommand(sortedSets SortedSets) ExecutableCommand[string, Value] {
+	return &zrankCommand{
+		Command: NewCommand("ZRANK").
+			WithArgument(KeyCommandArgument).
+			WithArgument(MemberArgument),
+		sortedSets: sortedSets,
+	}
+}
+
+// Run replies the rank of the member starting from 0 for the lowest score, nil
+// when it is not in the sorted set.
+func (c *zrankCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	member := input.GetArgument(*MemberArgument).(string)
+	rank, ok, err := c.sortedSets.Rank(key, member)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return &nilResult[Value]{}, nil
+	}
+	return &integerResult[Value]{value: int64(rank)}, nil
+}
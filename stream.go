@@ -0,0 +1,306 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StreamID identifies an entry of a stream: the unix time in milliseconds at
+// which it was added, and a sequence number telling apart the entries added
+// within the same millisecond.
+type StreamID struct {
+	Ms  uint64
+	Seq uint64
+}
+
+var maxStreamID = StreamID{Ms: math.MaxUint64, Seq: math.MaxUint64}
+
+func (id StreamID) String() string {
+	return fmt.Sprintf("%d-%d", id.Ms, id.Seq)
+}
+
+func (id StreamID) Compare(other StreamID) int {
+	if c := cmp.Compare(id.Ms, other.Ms); c != 0 {
+		return c
+	}
+	return cmp.Compare(id.Seq, other.Seq)
+}
+
+// ParseStreamID reads an ID written ms-seq, or ms alone in which case the
+// sequence number is missingSeq.
+func ParseStreamID(token string, missingSeq uint64) (StreamID, bool) {
+	msToken, seqToken, hasSeq := strings.Cut(token, "-")
+	ms, err := strconv.ParseUint(msToken, 10, 64)
+	if err != nil {
+		return StreamID{}, false
+	}
+	if !hasSeq {
+		return StreamID{Ms: ms, Seq: missingSeq}, true
+	}
+	seq, err := strconv.ParseUint(seqToken, 10, 64)
+	if err != nil {
+		return StreamID{}, false
+	}
+	return StreamID{Ms: ms, Seq: seq}, true
+}
+
+// StreamEntry is an entry of a stream, its fields each followed by its value.
+type StreamEntry struct {
+	ID     StreamID
+	Fields []string
+}
+
+// PendingEntry is an entry delivered to a consumer of a group and not
+// acknowledged yet.
+type PendingEntry struct {
+	ID          StreamID
+	Consumer    string
+	DeliveredAt time.Time
+	Deliveries  int
+}
+
+// StreamGroup is a consumer group: its consumers share the entries of the
+// stream, each new entry being delivered to a single one of them, and keep
+// them pending until they acknowledge them.
+type StreamGroup struct {
+	lastDelivered StreamID
+	pending       map[StreamID]*PendingEntry
+}
+
+// Stream is an append-only log of entries ordered by ID, read by ranges of
+// IDs or through consumer groups.
+type Stream struct {
+	entries []StreamEntry
+	lastID  StreamID
+	groups  map[string]*StreamGroup
+}
+
+func NewStream() *Stream {
+	return &Stream{groups: make(map[string]*StreamGroup)}
+}
+
+func (s *Stream) Len() int {
+	return len(s.entries)
+}
+
+// LastID returns the ID of the last entry added, trimmed entries included.
+func (s *Stream) LastID() StreamID {
+	return s.lastID
+}
+
+// NextID returns the ID of an entry added at now: its time in milliseconds,
+// or the time of the last entry when the clock went back, with the next
+// sequence number.
+func (s *Stream) NextID(now time.Time) StreamID {
+	ms := uint64(max(now.UnixMilli(), 0))
+	if ms > s.lastID.Ms {
+		return StreamID{Ms: ms}
+	}
+	return StreamID{Ms: s.lastID.Ms, Seq: s.lastID.Seq + 1}
+}
+
+// NextSeq returns the ID of an entry added at the given time in milliseconds,
+// with the next sequence number.
+func (s *Stream) NextSeq(ms uint64) StreamID {
+	if ms == s.lastID.Ms {
+		return StreamID{Ms: ms, Seq: s.lastID.Seq + 1}
+	}
+	return StreamID{Ms: ms}
+}
+
+// Add appends an entry and returns false when its ID is not greater than the
+// last one, IDs only ever growing.
+func (s *Stream) Add(id StreamID, fields []string) bool {
+	if id.Compare(s.lastID) <= 0 {
+		return false
+	}
+	s.entries = append(s.entries, StreamEntry{ID: id, Fields: fields})
+	s.lastID = id
+	return true
+}
+
+// search returns the index of the first entry whose ID is not lower than id.
+func (s *Stream) search(id StreamID) int {
+	return sort.Search(len(s.entries), func(i int) bool {
+		return s.entries[i].ID.Compare(id) >= 0
+	})
+}
+
+func (s *Stream) entry(id StreamID) (StreamEntry, bool) {
+	i := s.search(id)
+	if i < len(s.entries) && s.entries[i].ID == id {
+		return s.entries[i], true
+	}
+	return StreamEntry{}, false
+}
+
+// Range returns up to count entries, all of them when count is not positive,
+// whose IDs are between start and end included.
+func (s *Stream) Range(start, end StreamID, count int) []StreamEntry {
+	from := s.search(start)
+	to := from
+	for to < len(s.entries) && s.entries[to].ID.Compare(end) <= 0 && (count <= 0 || to-from < count) {
+		to++
+	}
+	return slices.Clone(s.entries[from:to])
+}
+
+// After returns up to count entries, all of them when count is not positive,
+// whose IDs are greater than id.
+func (s *Stream) After(id StreamID, count int) []StreamEntry {
+	if id == maxStreamID {
+		return nil
+	}
+	next := StreamID{Ms: id.Ms, Seq: id.Seq + 1}
+	if id.Seq == math.MaxUint64 {
+		next = StreamID{Ms: id.Ms + 1}
+	}
+	return s.Range(next, maxStreamID, count)
+}
+
+// TrimLen removes the oldest entries until at most maxLen are left and returns
+// the number of entries removed.
+func (s *Stream) TrimLen(maxLen int) int {
+	removed := max(len(s.entries)-maxLen, 0)
+	s.entries = s.entries[removed:]
+	return removed
+}
+
+// TrimBefore removes the entries whose IDs are lower than minID and returns
+// their number.
+func (s *Stream) TrimBefore(minID StreamID) int {
+	removed := s.search(minID)
+	s.entries = s.entries[removed:]
+	return removed
+}
+
+// CreateGroup adds a group whose consumers get the entries added after
+// lastDelivered, it returns false when the group already exists.
+func (s *Stream) CreateGroup(name string, lastDelivered StreamID) bool {
+	if _, exists := s.groups[name]; exists {
+		return false
+	}
+	s.groups[name] = &StreamGroup{lastDelivered: lastDelivered, pending: make(map[StreamID]*PendingEntry)}
+	return true
+}
+
+func (s *Stream) DestroyGroup(name string) bool {
+	_, exists := s.groups[name]
+	delete(s.groups, name)
+	return exists
+}
+
+func (s *Stream) Group(name string) (*StreamGroup, bool) {
+	group, ok := s.groups[name]
+	return group, ok
+}
+
+// ReadGroup delivers to the consumer up to count entries never delivered to
+// the group, all of them when count is not positive. They stay pending until
+// acknowledged.
+func (s *Stream) ReadGroup(group *StreamGroup, consumer string, count int, now time.Time) []StreamEntry {
+	entries := s.After(group.lastDelivered, count)
+	for _, entry := range entries {
+		group.pending[entry.ID] = &PendingEntry{ID: entry.ID, Consumer: consumer, DeliveredAt: now, Deliveries: 1}
+		group.lastDelivered = entry.ID
+	}
+	return entries
+}
+
+// ReadPending returns up to count entries pending for the consumer whose IDs
+// are greater than after, for a consumer to get back the entries it did not
+// acknowledge. Pending entries trimmed from the stream are left out.
+func (s *Stream) ReadPending(group *StreamGroup, consumer string, after StreamID, count int) []StreamEntry {
+	var entries []StreamEntry
+	for _, pending := range group.Pending() {
+		if count > 0 && len(entries) >= count {
+			break
+		}
+		if pending.Consumer != consumer || pending.ID.Compare(after) <= 0 {
+			continue
+		}
+		if entry, ok := s.entry(pending.ID); ok {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// Claim gives the consumer the entries pending for at least minIdle, so that
+// the entries of a consumer that stopped are processed by another one. The
+// entries trimmed from the stream are no longer pending and not returned.
+func (s *Stream) Claim(group *StreamGroup, consumer string, minIdle time.Duration, ids []StreamID, now time.Time) []StreamEntry {
+	var entries []StreamEntry
+	for _, id := range ids {
+		if entry, ok := s.claim(group, consumer, minIdle, id, now); ok {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// AutoClaim claims up to count entries pending for at least minIdle, starting
+// from the ID start. It returns the ID to start the next call from, 0-0 once
+// every pending entry was looked at.
+func (s *Stream) AutoClaim(group *StreamGroup, consumer string, minIdle time.Duration, start StreamID, count int, now time.Time) (StreamID, []StreamEntry) {
+	var entries []StreamEntry
+	for _, pending := range group.Pending() {
+		if pending.ID.Compare(start) < 0 {
+			continue
+		}
+		if len(entries) >= count {
+			return pending.ID, entries
+		}
+		if entry, ok := s.claim(group, consumer, minIdle, pending.ID, now); ok {
+			entries = append(entries, entry)
+		}
+	}
+	return StreamID{}, entries
+}
+
+func (s *Stream) claim(group *StreamGroup, consumer string, minIdle time.Duration, id StreamID, now time.Time) (StreamEntry, bool) {
+	pending, ok := group.pending[id]
+	if !ok || now.Sub(pending.DeliveredAt) < minIdle {
+		return StreamEntry{}, false
+	}
+	entry, ok := s.entry(id)
+	if !ok {
+		delete(group.pending, id)
+		return StreamEntry{}, false
+	}
+	pending.Consumer = consumer
+	pending.DeliveredAt = now
+	pending.Deliveries++
+	return entry, true
+}
+
+// Ack acknowledges the entries, which are no longer pending, and returns the
+// number of entries that were.
+func (g *StreamGroup) Ack(ids []StreamID) int {
+	acknowledged := 0
+	for _, id := range ids {
+		if _, ok := g.pending[id]; ok {
+			delete(g.pending, id)
+			acknowledged++
+		}
+	}
+	return acknowledged
+}
+
+// Pending returns copies of the pending entries ordered by ID.
+func (g *StreamGroup) Pending() []PendingEntry {
+	pending := make([]PendingEntry, 0, len(g.pending))
+	for _, entry := range g.pending {
+		pending = append(pending, *entry)
+	}
+	slices.SortFunc(pending, func(a, b PendingEntry) int {
+		return a.ID.Compare(b.ID)
+	})
+	return pending
+}
@@ -0,0 +1,1010 @@
+package main
+
+import (
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// streamTrim tells which entries are trimmed from a stream: the oldest ones
+// beyond maxLen when it is not negative, and the ones older than maxAge when
+// it is not zero.
+type streamTrim struct {
+	maxLen int
+	maxAge time.Duration
+}
+
+// streamEntries are entries read from the stream of a key.
+type streamEntries struct {
+	key     string
+	entries []StreamEntry
+}
+
+// Streams holds the streams of the cache, along with the clients blocked until
+// an entry is added to one of them.
+type Streams interface {
+	Add(key string, id string, fields []string, trim streamTrim, expiresAt time.Time) (StreamID, Error)
+	Len(key string) (int, Error)
+	Range(key string, start, end StreamID, count int) ([]StreamEntry, Error)
+	LastIDs(keys []string) ([]StreamID, Error)
+	Read(keys []string, after []StreamID, count int) ([]streamEntries, Error)
+	ReadGroup(keys []string, group, consumer string, after []*StreamID, count int) ([]streamEntries, Error)
+	Ack(key, group string, ids []StreamID) (int, Error)
+	Pending(key, group string) ([]PendingEntry, Error)
+	Claim(key, group, consumer string, minIdle time.Duration, ids []StreamID) ([]StreamEntry, Error)
+	AutoClaim(key, group, consumer string, minIdle time.Duration, start StreamID, count int) (StreamID, []StreamEntry, Error)
+	Trim(key string, trim streamTrim) (int, Error)
+	CreateGroup(key, group, id string, mkStream bool) Error
+	DestroyGroup(key, group string) (bool, Error)
+	Wait(keys []string) *keyWaiter
+	StopWaiting(*keyWaiter)
+}
+
+type streams struct {
+	*keyWaiters
+	cache Cache[string, Value]
+}
+
+func NewStreams(cache Cache[string, Value]) Streams {
+	return &streams{
+		keyWaiters: newKeyWaiters(),
+		cache:      cache,
+	}
+}
+
+// update runs change on the stream of the key when it exists, change reports
+// whether it modified the stream.
+func (s *streams) update(key string, change func(stream *Stream) (bool, Error)) Error {
+	_, err := computeAs(s.cache, key, StreamData, func(current **Stream) (*Stream, ComputeAction, Error) {
+		if current == nil {
+			return nil, KeepValue, nil
+		}
+		changed, err := change(*current)
+		if err != nil || !changed {
+			return *current, KeepValue, err
+		}
+		return *current, StoreValue, nil
+	}, time.Time{})
+	return err
+}
+
+// updateGroup runs change on the group of the stream of the key, a missing key
+// or group being an error of the command.
+func (s *streams) updateGroup(commandName string, key string, name string, change func(stream *Stream, group *StreamGroup) bool) Error {
+	found := false
+	err := s.update(key, func(stream *Stream) (bool, Error) {
+		group, ok := stream.Group(name)
+		if !ok {
+			return false, nil
+		}
+		found = true
+		return change(stream, group), nil
+	})
+	if err == nil && !found {
+		return &CommandError{message: "No such key or consumer group in " + commandName}
+	}
+	return err
+}
+
+// apply removes the entries of the stream the trim applies to and returns their
+// number.
+func (t streamTrim) apply(stream *Stream, now time.Time) int {
+	removed := 0
+	if t.maxAge > 0 {
+		minMs := max(now.Add(-t.maxAge).UnixMilli(), 0)
+		removed += stream.TrimBefore(StreamID{Ms: uint64(minMs)})
+	}
+	if t.maxLen >= 0 {
+		removed += stream.TrimLen(t.maxLen)
+	}
+	return removed
+}
+
+// Add appends an entry to the stream, which is created when needed and then
+// expires at expiresAt, and returns its ID. The id is either an ID greater
+// than the last one, * to generate it from the current time or ms-* to only
+// generate the sequence number. The stream is then trimmed.
+func (s *streams) Add(key string, id string, fields []string, trim streamTrim, expiresAt time.Time) (StreamID, Error) {
+	var added StreamID
+	now := time.Now()
+	_, err := computeAs(s.cache, key, StreamData, func(current **Stream) (*Stream, ComputeAction, Error) {
+		stream := NewStream()
+		if current != nil {
+			stream = *current
+		}
+		switch msToken, isPartial := strings.CutSuffix(id, "-*"); {
+		case id == "*":
+			added = stream.NextID(now)
+		case isPartial:
+			ms, err := strconv.ParseUint(msToken, 10, 64)
+			if err != nil {
+				return nil, KeepValue, &CommandError{message: "Invalid stream ID in XADD"}
+			}
+			added = stream.NextSeq(ms)
+		default:
+			var ok bool
+			if added, ok = ParseStreamID(id, 0); !ok {
+				return nil, KeepValue, &CommandError{message: "Invalid stream ID in XADD"}
+			}
+		}
+		if !stream.Add(added, fields) {
+			return nil, KeepValue, &CommandError{message: "The ID must be greater than the one of the last entry in XADD"}
+		}
+		trim.apply(stream, now)
+		return stream, StoreValue, nil
+	}, expiresAt)
+	if err != nil {
+		return StreamID{}, err
+	}
+	s.signal(key)
+	return added, nil
+}
+
+func (s *streams) Len(key string) (int, Error) {
+	length := 0
+	err := s.update(key, func(stream *Stream) (bool, Error) {
+		length = stream.Len()
+		return false, nil
+	})
+	return length, err
+}
+
+func (s *streams) Range(key string, start, end StreamID, count int) ([]StreamEntry, Error) {
+	var entries []StreamEntry
+	err := s.update(key, func(stream *Stream) (bool, Error) {
+		entries = stream.Range(start, end, count)
+		return false, nil
+	})
+	return entries, err
+}
+
+// LastIDs returns the ID of the last entry of each stream, 0-0 for the keys
+// that do not exist.
+func (s *streams) LastIDs(keys []string) ([]StreamID, Error) {
+	ids := make([]StreamID, len(keys))
+	for i, key := range keys {
+		err := s.update(key, func(stream *Stream) (bool, Error) {
+			ids[i] = stream.LastID()
+			return false, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ids, nil
+}
+
+// Read returns up to count entries of each stream added after the matching
+// ID, the streams without any being left out.
+func (s *streams) Read(keys []string, after []StreamID, count int) ([]streamEntries, Error) {
+	var reads []streamEntries
+	for i, key := range keys {
+		var entries []StreamEntry
+		err := s.update(key, func(stream *Stream) (bool, Error) {
+			entries = stream.After(after[i], count)
+			return false, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(entries) > 0 {
+			reads = append(reads, streamEntries{key: key, entries: entries})
+		}
+	}
+	return reads, nil
+}
+
+// ReadGroup reads up to count entries of each stream for the consumer of the
+// group: the entries never delivered to the group when the matching ID is
+// nil, and otherwise the entries pending for the consumer after the ID.
+func (s *streams) ReadGroup(keys []string, group, consumer string, after []*StreamID, count int) ([]streamEntries, Error) {
+	var reads []streamEntries
+	now := time.Now()
+	for i, key := range keys {
+		var entries []StreamEntry
+		err := s.updateGroup("XREADGROUP", key, group, func(stream *Stream, group *StreamGroup) bool {
+			if after[i] != nil {
+				entries = stream.ReadPending(group, consumer, *after[i], count)
+				return false
+			}
+			entries = stream.ReadGroup(group, consumer, count, now)
+			return len(entries) > 0
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(entries) > 0 {
+			reads = append(reads, streamEntries{key: key, entries: entries})
+		}
+	}
+	return reads, nil
+}
+
+// Ack acknowledges the entries for the group and returns the number of
+// entries that were pending.
+func (s *streams) Ack(key, group string, ids []StreamID) (int, Error) {
+	acknowledged := 0
+	err := s.updateGroup("XACK", key, group, func(stream *Stream, group *StreamGroup) bool {
+		acknowledged = group.Ack(ids)
+		return acknowledged > 0
+	})
+	return acknowledged, err
+}
+
+// Pending returns the entries pending for the group, ordered by ID.
+func (s *streams) Pending(key, group string) ([]PendingEntry, Error) {
+	var pending []PendingEntry
+	err := s.updateGroup("XPENDING", key, group, func(stream *Stream, group *StreamGroup) bool {
+		pending = group.Pending()
+		return false
+	})
+	return pending, err
+}
+
+// Claim gives the consumer the entries of the group pending for at least
+// minIdle, and returns them.
+func (s *streams) Claim(key, group, consumer string, minIdle time.Duration, ids []StreamID) ([]StreamEntry, Error) {
+	var entries []StreamEntry
+	now := time.Now()
+	err := s.updateGroup("XCLAIM", key, group, func(stream *Stream, group *StreamGroup) bool {
+		entries = stream.Claim(group, consumer, minIdle, ids, now)
+		return true
+	})
+	return entries, err
+}
+
+// AutoClaim gives the consumer up to count entries of the group pending for
+// at least minIdle, looked at from the ID start. It returns the ID to start
+// the next call from, 0-0 once every pending entry was looked at.
+func (s *streams) AutoClaim(key, group, consumer string, minIdle time.Duration, start StreamID, count int) (StreamID, []StreamEntry, Error) {
+	var next StreamID
+	var entries []StreamEntry
+	now := time.Now()
+	err := s.updateGroup("XAUTOCLAIM", key, group, func(stream *Stream, group *StreamGroup) bool {
+		next, entries = stream.AutoClaim(group, consumer, minIdle, start, count, now)
+		return true
+	})
+	return next, entries, err
+}
+
+// Trim removes the entries of the stream the trim applies to and returns their
+// number.
+func (s *streams) Trim(key string, trim streamTrim) (int, Error) {
+	removed := 0
+	err := s.update(key, func(stream *Stream) (bool, Error) {
+		removed = trim.apply(stream, time.Now())
+		return removed > 0, nil
+	})
+	return removed, err
+}
+
+// CreateGroup adds a group to the stream reading the entries added after the
+// id, $ standing for the last entry. The stream is created when mkStream is
+// set and fails otherwise when it does not exist.
+func (s *streams) CreateGroup(key, group, id string, mkStream bool) Error {
+	_, err := computeAs(s.cache, key, StreamData, func(current **Stream) (*Stream, ComputeAction, Error) {
+		var stream *Stream
+		switch {
+		case current != nil:
+			stream = *current
+		case mkStream:
+			stream = NewStream()
+		default:
+			return nil, KeepValue, &CommandError{message: "No such key in XGROUP, pass mkstream to create it"}
+		}
+		lastDelivered := stream.LastID()
+		if id != "$" {
+			var ok bool
+			if lastDelivered, ok = ParseStreamID(id, 0); !ok {
+				return nil, KeepValue, &CommandError{message: "Invalid stream ID in XGROUP"}
+			}
+		}
+		if !stream.CreateGroup(group, lastDelivered) {
+			return nil, KeepValue, &CommandError{message: "Consumer group already exists in XGROUP"}
+		}
+		return stream, StoreValue, nil
+	}, time.Time{})
+	return err
+}
+
+func (s *streams) DestroyGroup(key, group string) (bool, Error) {
+	destroyed := false
+	err := s.update(key, func(stream *Stream) (bool, Error) {
+		destroyed = stream.DestroyGroup(group)
+		return destroyed, nil
+	})
+	return destroyed, err
+}
+
+// parseRangeID reads a bound of XRANGE: - and + stand for the first and the
+// last entry, and an ID without sequence number covers the whole millisecond.
+func parseRangeID(token string, isEnd bool) (StreamID, bool) {
+	switch token {
+	case "-":
+		return StreamID{}, true
+	case "+":
+		return maxStreamID, true
+	}
+	if isEnd {
+		return ParseStreamID(token, math.MaxUint64)
+	}
+	return ParseStreamID(token, 0)
+}
+
+// parseStreamIDs reads IDs of entries.
+func parseStreamIDs(commandName string, tokens []string) ([]StreamID, Error) {
+	ids := make([]StreamID, len(tokens))
+	for i, token := range tokens {
+		id, ok := ParseStreamID(token, 0)
+		if !ok {
+			return nil, &CommandError{message: "Invalid stream ID in " + commandName}
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
+// splitKeysIDs splits keys followed by as many IDs.
+func splitKeysIDs(commandName string, tokens []string) ([]string, []string, Error) {
+	if len(tokens)%2 != 0 {
+		return nil, nil, &InvalidCommandUsageError{command: commandName}
+	}
+	return tokens[:len(tokens)/2], tokens[len(tokens)/2:], nil
+}
+
+// entriesCountFromInput reads the count option, 0 standing for no limit.
+func entriesCountFromInput(commandName string, input CommandInput) (int, Error) {
+	count, _ := input.GetOption(*EntriesCountOption).(int)
+	if count < 0 {
+		return 0, &InvalidCommandUsageError{command: commandName}
+	}
+	return count, nil
+}
+
+// trimFromInput reads the maxlen and maxage options.
+func trimFromInput(commandName string, input CommandInput) (streamTrim, Error) {
+	trim := streamTrim{maxLen: -1}
+	if maxLen, ok := input.GetOption(*MaxLenOption).(int); ok {
+		if maxLen < 0 {
+			return trim, &InvalidCommandUsageError{command: commandName}
+		}
+		trim.maxLen = maxLen
+	}
+	if maxAge, ok := input.GetOption(*MaxAgeOption).(int); ok {
+		if maxAge <= 0 {
+			return trim, &InvalidCommandUsageError{command: commandName}
+		}
+		trim.maxAge = time.Duration(maxAge) * time.Second
+	}
+	return trim, nil
+}
+
+// entryResult replies the ID of the entry followed by its fields and values.
+func entryResult(entry StreamEntry) Result[Value] {
+	return &arrayResult[Value]{items: []Result[Value]{
+		&valueResult[Value]{value: StringValue(entry.ID.String())},
+		elementsResult(entry.Fields),
+	}}
+}
+
+func entriesResult(entries []StreamEntry) Result[Value] {
+	items := make([]Result[Value], len(entries))
+	for i, entry := range entries {
+		items[i] = entryResult(entry)
+	}
+	return &arrayResult[Value]{items: items}
+}
+
+// streamsResult replies each key followed by the entries read from it, nil
+// when no entry was read.
+func streamsResult(reads []streamEntries) Result[Value] {
+	if len(reads) == 0 {
+		return &nilResult[Value]{}
+	}
+	items := make([]Result[Value], len(reads))
+	for i, read := range reads {
+		items[i] = &arrayResult[Value]{items: []Result[Value]{
+			&textResult[Value]{text: strconv.Quote(read.key)},
+			entriesResult(read.entries),
+		}}
+	}
+	return &arrayResult[Value]{items: items}
+}
+
+type xaddCommand struct {
+	Command
+	streams Streams
+}
+
+func NewXAddCommand(streams Streams) ExecutableCommand[string, Value] {
+	return &xaddCommand{
+		Command: NewCommand("XADD").
+			WithArgument(KeyCommandArgument).
+			WithArgument(StreamIDArgument).
+			WithArgument(StreamFieldValuesArgument).
+			WithOption(MaxLenOption).
+			WithOption(MaxAgeOption).
+			WithOption(ExpirationOption).
+			WithOption(ExpirationMsOption),
+		streams: streams,
+	}
+}
+
+// Run replies the ID of the entry added. The maxlen and maxage options trim the
+// stream once the entry is added, and the TTL given as option applies to the
+// stream when it is created.
+func (c *xaddCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	id := input.GetArgument(*StreamIDArgument).(string)
+	fields := toStrings(input.GetArgument(*StreamFieldValuesArgument).([]any))
+	if len(fields)%2 != 0 {
+		return nil, &InvalidCommandUsageError{command: c.GetName()}
+	}
+	trim, err := trimFromInput(c.GetName(), input)
+	if err != nil {
+		return nil, err
+	}
+	expiresAt, err := expirationFromInput(c.GetName(), input)
+	if err != nil {
+		return nil, err
+	}
+	added, err := c.streams.Add(key, id, fields, trim, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return &valueResult[Value]{value: StringValue(added.String())}, nil
+}
+
+type xlenCommand struct {
+	Command
+	streams Streams
+}
+
+func NewXLenCommand(streams Streams) ExecutableCommand[string, Value] {
+	return &xlenCommand{
+		Command: NewCommand("XLEN").
+			WithArgument(KeyCommandArgument),
+		streams: streams,
+	}
+}
+
+func (c *xlenCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	length, err := c.streams.Len(key)
+	if err != nil {
+		return nil, err
+	}
+	return &integerResult[Value]{value: int64(length)}, nil
+}
+
+type xrangeCommand struct {
+	Command
+	streams Streams
+}
+
+func NewXRangeCommand(streams Streams) ExecutableCommand[string, Value] {
+	return &xrangeCommand{
+		Command: NewCommand("XRANGE").
+			WithArgument(KeyCommandArgument).
+			WithArgument(StartIDArgument).
+			WithArgument(EndIDArgument).
+			WithOption(EntriesCountOption),
+		streams: streams,
+	}
+}
+
+// Run replies the entries whose IDs are between start and end included, in
+// order, each as its ID followed by its fields and values.
+func (c *xrangeCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	start, startOk := parseRangeID(input.GetArgument(*StartIDArgument).(string), false)
+	end, endOk := parseRangeID(input.GetArgument(*EndIDArgument).(string), true)
+	if !startOk || !endOk {
+		return nil, &CommandError{message: "Invalid stream ID in " + c.GetName()}
+	}
+	count, err := entriesCountFromInput(c.GetName(), input)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := c.streams.Range(key, start, end, count)
+	if err != nil {
+		return nil, err
+	}
+	return entriesResult(entries), nil
+}
+
+type xtrimCommand struct {
+	Command
+	streams Streams
+}
+
+func NewXTrimCommand(streams Streams) ExecutableCommand[string, Value] {
+	return &xtrimCommand{
+		Command: NewCommand("XTRIM").
+			WithArgument(KeyCommandArgument).
+			WithOption(MaxLenOption).
+			WithOption(MaxAgeOption),
+		streams: streams,
+	}
+}
+
+// Run replies the number of entries trimmed by length or by age.
+func (c *xtrimCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	trim, err := trimFromInput(c.GetName(), input)
+	if err != nil {
+		return nil, err
+	}
+	if trim.maxLen < 0 && trim.maxAge == 0 {
+		return nil, &InvalidCommandUsageError{command: c.GetName()}
+	}
+	removed, err := c.streams.Trim(key, trim)
+	if err != nil {
+		return nil, err
+	}
+	return &integerResult[Value]{value: int64(removed)}, nil
+}
+
+// blockingReadResult replies the entries read from streams. With the block
+// option, when no entry was read at execution it sends the reply once
+// entries are added, or nil after the timeout. Inside a transaction it does
+// not block and replies nil.
+type blockingReadResult struct {
+	streams Streams
+	keys    []string
+	block   bool
+	timeout time.Duration
+	read    func() ([]streamEntries, Error)
+	reads   []streamEntries
+}
+
+// blockFromInput reads the block option in milliseconds.
+func blockFromInput(commandName string, input CommandInput) (bool, time.Duration, Error) {
+	block, ok := input.GetOption(*BlockOption).(int)
+	if !ok {
+		return false, 0, nil
+	}
+	if block < 0 || block > math.MaxInt64/int(time.Millisecond) {
+		return false, 0, &InvalidCommandUsageError{command: commandName}
+	}
+	return true, time.Duration(block) * time.Millisecond, nil
+}
+
+// minIdleFromInput reads the min idle time argument in milliseconds.
+func minIdleFromInput(commandName string, input CommandInput) (time.Duration, Error) {
+	minIdle := input.GetArgument(*MinIdleTimeArgument).(int)
+	if minIdle < 0 || minIdle > math.MaxInt64/int(time.Millisecond) {
+		return 0, &InvalidCommandUsageError{command: commandName}
+	}
+	return time.Duration(minIdle) * time.Millisecond, nil
+}
+
+func (r *blockingReadResult) String() string {
+	return streamsResult(r.reads).String()
+}
+
+func (r *blockingReadResult) Block(connection Connection, execute func(func())) bool {
+	if len(r.reads) > 0 || !r.block {
+		return reply(connection, r.String()) == nil
+	}
+	waiter := r.streams.Wait(r.keys)
+	defer r.streams.StopWaiting(waiter)
+	closed, stopWatching := connection.WatchClosed()
+	defer stopWatching()
+	var timeout <-chan time.Time
+	if r.timeout > 0 {
+		timer := time.NewTimer(r.timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	for {
+		var reads []streamEntries
+		var err Error
+		execute(func() {
+			reads, err = r.read()
+		})
+		if err != nil {
+			return reply(connection, err.Display()) == nil
+		}
+		if len(reads) > 0 {
+			r.reads = reads
+			return reply(connection, r.String()) == nil
+		}
+		select {
+		case <-waiter.wake:
+		case <-timeout:
+			return reply(connection, r.String()) == nil
+		case <-closed:
+			return false
+		}
+	}
+}
+
+type xreadCommand struct {
+	Command
+	streams Streams
+}
+
+func NewXReadCommand(streams Streams) ExecutableCommand[string, Value] {
+	return &xreadCommand{
+		Command: NewCommand("XREAD").
+			WithArgument(KeysIDsArgument).
+			WithOption(EntriesCountOption).
+			WithOption(BlockOption),
+		streams: streams,
+	}
+}
+
+// Run replies, for each stream having some, the entries added after the
+// matching ID. With the block option it waits for entries when there are none,
+// without holding an execution slot.
+func (c *xreadCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	keys, idTokens, err := splitKeysIDs(c.GetName(), toStrings(input.GetArgument(*KeysIDsArgument).([]any)))
+	if err != nil {
+		return nil, err
+	}
+	count, err := entriesCountFromInput(c.GetName(), input)
+	if err != nil {
+		return nil, err
+	}
+	block, timeout, err := blockFromInput(c.GetName(), input)
+	if err != nil {
+		return nil, err
+	}
+	lastIDs, err := c.streams.LastIDs(keys)
+	if err != nil {
+		return nil, err
+	}
+	after := make([]StreamID, len(keys))
+	for i, token := range idTokens {
+		if token == "$" {
+			after[i] = lastIDs[i]
+			continue
+		}
+		id, ok := ParseStreamID(token, 0)
+		if !ok {
+			return nil, &CommandError{message: "Invalid stream ID in " + c.GetName()}
+		}
+		after[i] = id
+	}
+	result := &blockingReadResult{
+		streams: c.streams,
+		keys:    keys,
+		block:   block,
+		timeout: timeout,
+		read: func() ([]streamEntries, Error) {
+			return c.streams.Read(keys, after, count)
+		},
+	}
+	if result.reads, err = result.read(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+type xreadGroupCommand struct {
+	Command
+	streams Streams
+}
+
+func NewXReadGroupCommand(streams Streams) ExecutableCommand[string, Value] {
+	return &xreadGroupCommand{
+		Command: NewCommand("XREADGROUP").
+			WithArgument(ReadGroupArgument).
+			WithArgument(ReadConsumerArgument).
+			WithArgument(GroupKeysIDsArgument).
+			WithOption(EntriesCountOption).
+			WithOption(BlockOption),
+		streams: streams,
+	}
+}
+
+// Run replies, for each stream having some, the entries read by the consumer:
+// with the ID > the entries never delivered to the group, which become pending
+// for the consumer, and with another ID its own pending entries after it. With
+// the block option it waits for new entries when reading with > only.
+func (c *xreadGroupCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	group := input.GetArgument(*ReadGroupArgument).(string)
+	consumer := input.GetArgument(*ReadConsumerArgument).(string)
+	keys, idTokens, err := splitKeysIDs(c.GetName(), toStrings(input.GetArgument(*GroupKeysIDsArgument).([]any)))
+	if err != nil {
+		return nil, err
+	}
+	count, err := entriesCountFromInput(c.GetName(), input)
+	if err != nil {
+		return nil, err
+	}
+	block, timeout, err := blockFromInput(c.GetName(), input)
+	if err != nil {
+		return nil, err
+	}
+	after := make([]*StreamID, len(keys))
+	for i, token := range idTokens {
+		if token == ">" {
+			continue
+		}
+		id, ok := ParseStreamID(token, 0)
+		if !ok {
+			return nil, &CommandError{message: "Invalid stream ID in " + c.GetName()}
+		}
+		after[i] = &id
+		block = false
+	}
+	result := &blockingReadResult{
+		streams: c.streams,
+		keys:    keys,
+		block:   block,
+		timeout: timeout,
+		read: func() ([]streamEntries, Error) {
+			return c.streams.ReadGroup(keys, group, consumer, after, count)
+		},
+	}
+	if result.reads, err = result.read(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+type xackCommand struct {
+	Command
+	streams Streams
+}
+
+func NewXAckCommand(streams Streams) ExecutableCommand[string, Value] {
+	return &xackCommand{
+		Command: NewCommand("XACK").
+			WithArgument(KeyCommandArgument).
+			WithArgument(GroupArgument).
+			WithArgument(EntryIDsArgument),
+		streams: streams,
+	}
+}
+
+// Run replies the number of entries acknowledged that were pending.
+func (c *xackCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	group := input.GetArgument(*GroupArgument).(string)
+	ids, err := parseStreamIDs(c.GetName(), toStrings(input.GetArgument(*EntryIDsArgument).([]any)))
+	if err != nil {
+		return nil, err
+	}
+	acknowledged, err := c.streams.Ack(key, group, ids)
+	if err != nil {
+		return nil, err
+	}
+	return &integerResult[Value]{value: int64(acknowledged)}, nil
+}
+
+type xpendingCommand struct {
+	Command
+	streams Streams
+}
+
+func NewXPendingCommand(streams Streams) ExecutableCommand[string, Value] {
+	return &xpendingCommand{
+		Command: NewCommand("XPENDING").
+			WithArgument(KeyCommandArgument).
+			WithArgument(GroupArgument).
+			WithArgument(PendingStartArgument).
+			WithArgument(PendingEndArgument).
+			WithArgument(PendingCountArgument),
+		streams: streams,
+	}
+}
+
+// Run replies a summary of the entries pending for the group: their number,
+// the lowest and highest IDs and the number of entries of each consumer. Given
+// a range and a count it lists the pending entries instead, each with its
+// consumer, the milliseconds since it was delivered and its deliveries.
+func (c *xpendingCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	group := input.GetArgument(*GroupArgument).(string)
+	startToken, hasStart := input.GetArgument(*PendingStartArgument).(string)
+	endToken, hasEnd := input.GetArgument(*PendingEndArgument).(string)
+	count, hasCount := input.GetArgument(*PendingCountArgument).(int)
+	if hasStart != hasCount || hasEnd != hasCount || count < 0 {
+		return nil, &InvalidCommandUsageError{command: c.GetName()}
+	}
+	pending, err := c.streams.Pending(key, group)
+	if err != nil {
+		return nil, err
+	}
+	if !hasStart {
+		return pendingSummaryResult(pending), nil
+	}
+	start, startOk := parseRangeID(startToken, false)
+	end, endOk := parseRangeID(endToken, true)
+	if !startOk || !endOk {
+		return nil, &CommandError{message: "Invalid stream ID in " + c.GetName()}
+	}
+	now := time.Now()
+	items := []Result[Value]{}
+	for _, entry := range pending {
+		if len(items) >= count {
+			break
+		}
+		if entry.ID.Compare(start) < 0 || entry.ID.Compare(end) > 0 {
+			continue
+		}
+		items = append(items, &arrayResult[Value]{items: []Result[Value]{
+			&valueResult[Value]{value: StringValue(entry.ID.String())},
+			&valueResult[Value]{value: StringValue(entry.Consumer)},
+			&integerResult[Value]{value: now.Sub(entry.DeliveredAt).Milliseconds()},
+			&integerResult[Value]{value: int64(entry.Deliveries)},
+		}})
+	}
+	return &arrayResult[Value]{items: items}, nil
+}
+
+func pendingSummaryResult(pending []PendingEntry) Result[Value] {
+	if len(pending) == 0 {
+		return &arrayResult[Value]{items: []Result[Value]{
+			&integerResult[Value]{value: 0},
+			&nilResult[Value]{},
+			&nilResult[Value]{},
+			&nilResult[Value]{},
+		}}
+	}
+	counts := make(map[string]int64)
+	var consumers []string
+	for _, entry := range pending {
+		if counts[entry.Consumer] == 0 {
+			consumers = append(consumers, entry.Consumer)
+		}
+		counts[entry.Consumer]++
+	}
+	slices.Sort(consumers)
+	consumerItems := make([]Result[Value], len(consumers))
+	for i, consumer := range consumers {
+		consumerItems[i] = &arrayResult[Value]{items: []Result[Value]{
+			&valueResult[Value]{value: StringValue(consumer)},
+			&integerResult[Value]{value: counts[consumer]},
+		}}
+	}
+	return &arrayResult[Value]{items: []Result[Value]{
+		&integerResult[Value]{value: int64(len(pending))},
+		&valueResult[Value]{value: StringValue(pending[0].ID.String())},
+		&valueResult[Value]{value: StringValue(pending[len(pending)-1].ID.String())},
+		&arrayResult[Value]{items: consumerItems},
+	}}
+}
+
+type xclaimCommand struct {
+	Command
+	streams Streams
+}
+
+func NewXClaimCommand(streams Streams) ExecutableCommand[string, Value] {
+	return &xclaimCommand{
+		Command: NewCommand("XCLAIM").
+			WithArgument(KeyCommandArgument).
+			WithArgument(GroupArgument).
+			WithArgument(ConsumerArgument).
+			WithArgument(MinIdleTimeArgument).
+			WithArgument(ClaimIDsArgument),
+		streams: streams,
+	}
+}
+
+// Run replies the entries given to the consumer among the ones pending for at
+// least the min idle time, so that the entries of a consumer that stopped are
+// processed by another one.
+func (c *xclaimCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	group := input.GetArgument(*GroupArgument).(string)
+	consumer := input.GetArgument(*ConsumerArgument).(string)
+	minIdle, err := minIdleFromInput(c.GetName(), input)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := parseStreamIDs(c.GetName(), toStrings(input.GetArgument(*ClaimIDsArgument).([]any)))
+	if err != nil {
+		return nil, err
+	}
+	entries, err := c.streams.Claim(key, group, consumer, minIdle, ids)
+	if err != nil {
+		return nil, err
+	}
+	return entriesResult(entries), nil
+}
+
+type xautoclaimCommand struct {
+	Command
+	streams Streams
+}
+
+func NewXAutoClaimCommand(streams Streams) ExecutableCommand[string, Value] {
+	return &xautoclaimCommand{
+		Command: NewCommand("XAUTOCLAIM").
+			WithArgument(KeyCommandArgument).
+			WithArgument(GroupArgument).
+			WithArgument(ConsumerArgument).
+			WithArgument(MinIdleTimeArgument).
+			WithArgument(ClaimStartArgument).
+			WithOption(EntriesCountOption),
+		streams: streams,
+	}
+}
+
+// Run gives the consumer up to count entries (default: 100) pending for at
+// least the min idle time, and replies the ID to pass as start to the next
+// call, 0-0 once all the pending entries were looked at, followed by the
+// entries claimed.
+func (c *xautoclaimCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	group := input.GetArgument(*GroupArgument).(string)
+	consumer := input.GetArgument(*ConsumerArgument).(string)
+	minIdle, err := minIdleFromInput(c.GetName(), input)
+	if err != nil {
+		return nil, err
+	}
+	start, ok := parseRangeID(input.GetArgument(*ClaimStartArgument).(string), false)
+	if !ok {
+		return nil, &CommandError{message: "Invalid stream ID in " + c.GetName()}
+	}
+	count, err := entriesCountFromInput(c.GetName(), input)
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		count = 100
+	}
+	next, entries, err := c.streams.AutoClaim(key, group, consumer, minIdle, start, count)
+	if err != nil {
+		return nil, err
+	}
+	return &arrayResult[Value]{items: []Result[Value]{
+		&valueResult[Value]{value: StringValue(next.String())},
+		entriesResult(entries),
+	}}, nil
+}
+
+type xgroupCommand struct {
+	Command
+	streams Streams
+}
+
+func NewXGroupCommand(streams Streams) ExecutableCommand[string, Value] {
+	return &xgroupCommand{
+		Command: NewCommand("XGROUP").
+			WithArgument(SubcommandArgument).
+			WithArgument(StreamKeyArgument).
+			WithArgument(GroupNameArgument).
+			WithArgument(GroupStartIDArgument).
+			WithOption(MkStreamOption),
+		streams: streams,
+	}
+}
+
+// Run creates a consumer group with CREATE key group id, or deletes one along
+// with its pending entries with DESTROY key group.
+func (c *xgroupCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	subcommand := input.GetArgument(*SubcommandArgument).(string)
+	key := input.GetArgument(*StreamKeyArgument).(string)
+	group := input.GetArgument(*GroupNameArgument).(string)
+	id, hasID := input.GetArgument(*GroupStartIDArgument).(string)
+
+	switch strings.ToUpper(subcommand) {
+	case "CREATE":
+		if !hasID {
+			return nil, &InvalidCommandUsageError{command: c.GetName()}
+		}
+		if err := c.streams.CreateGroup(key, group, id, input.GetOption(*MkStreamOption) != nil); err != nil {
+			return nil, err
+		}
+		return &okResult[Value]{}, nil
+	case "DESTROY":
+		if hasID {
+			return nil, &InvalidCommandUsageError{command: c.GetName()}
+		}
+		destroyed, err := c.streams.DestroyGroup(key, group)
+		if err != nil {
+			return nil, err
+		}
+		return booleanResult[Value](destroyed), nil
+	default:
+		return nil, &InvalidCommandUsageError{command: c.GetName()}
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestBlockingReadWaitsForTransactions checks that a blocked read woken up by
+// an entry added does not read in the middle of the transaction that added it.
+func TestBlockingReadWaitsForTransactions(t *testing.T) {
+	streams := NewStreams(newTestCache(t))
+	executor := &executor[string, Value]{}
+	var inTransaction, readInTransaction atomic.Bool
+	keys := []string{"racest"}
+	result := &blockingReadResult{
+		streams: streams,
+		keys:    keys,
+		block:   true,
+		read: func() ([]streamEntries, Error) {
+			if inTransaction.Load() {
+				readInTransaction.Store(true)
+			}
+			return streams.Read(keys, []StreamID{{}}, 0)
+		},
+	}
+	connection := newTestConnection()
+	done := make(chan bool)
+	go func() {
+		done <- result.Block(connection, executor.ExecuteFunc)
+	}()
+
+	executor.exclusive.Lock()
+	inTransaction.Store(true)
+	if _, err := streams.Add("racest", "*", []string{"field", "value"}, streamTrim{maxLen: -1}, time.Time{}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	inTransaction.Store(false)
+	executor.exclusive.Unlock()
+
+	if !<-done {
+		t.Fatalf("connection not usable after the read")
+	}
+	if readInTransaction.Load() {
+		t.Errorf("stream read during the transaction")
+	}
+	if len(result.reads) != 1 || len(result.reads[0].entries) != 1 {
+		t.Errorf("got reads %v, want the entry added", result.reads)
+	}
+	<-connection.replies
+}
+
+// TestClaimRejectsOverflowingMinIdle checks that a min idle time too large to
+// be a duration does not wrap around and claim every pending entry.
+func TestClaimRejectsOverflowingMinIdle(t *testing.T) {
+	cache := newTestCache(t)
+	streams := NewStreams(cache)
+	if err := streams.CreateGroup("claimst", "g", "$", true); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	id, err := streams.Add("claimst", "*", []string{"field", "value"}, streamTrim{maxLen: -1}, time.Time{})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, err := streams.ReadGroup([]string{"claimst"}, "g", "c1", []*StreamID{nil}, 0); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	minIdle := "10000000000000"
+	for command, args := range map[ExecutableCommand[string, Value]][]string{
+		NewXClaimCommand(streams):     {"claimst", "g", "c2", minIdle, id.String()},
+		NewXAutoClaimCommand(streams): {"claimst", "g", "c2", minIdle, "0"},
+	} {
+		input, err := command.Parse(args)
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", command.GetName(), err)
+		}
+		if _, err := command.Run(input, cache); err == nil {
+			t.Errorf("%s: min idle time of %s ms accepted", command.GetName(), minIdle)
+		}
+	}
+	pending, err := streams.Pending("claimst", "g")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(pending) != 1 || pending[0].Consumer != "c1" {
+		t.Errorf("got pending entries %+v, want the entry kept by c1", pending)
+	}
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const MinWheelPrecision = time.Millisecond
+
+const (
+	wheelLevels        = 5
+	wheelLevelZeroBits = 8
+	wheelLevelBits     = 6
+)
+
+// wheelShift returns the number of tick bits below the slots of a level.
+func wheelShift(level int) int {
+	if level == 0 {
+		return 0
+	}
+	return wheelLevelZeroBits + (level-1)*wheelLevelBits
+}
+
+func wheelSlots(level int) int64 {
+	if level == 0 {
+		return 1 << wheelLevelZeroBits
+	}
+	return 1 << wheelLevelBits
+}
+
+// wheelSpan returns the number of ticks covered by a level and all the
+// levels below it.
+func wheelSpan(level int) int64 {
+	return int64(1) << (wheelShift(level + 1))
+}
+
+type wheelEntry struct {
+	tick  int64
+	level int
+	slot  int64
+}
+
+// timingWheel is a hierarchical timing wheel implementing Records: each level
+// splits the span of one slot of the level above, keys are cascaded down as
+// time advances and expire from the lowest level. Adding and deleting a key
+// are O(1) thanks to the index of the slot holding every key.
+type timingWheel[K comparable] struct {
+	mu          sync.Mutex
+	levels      [wheelLevels][]map[K]int64
+	sizes       [wheelLevels]int
+	overflow    map[K]int64
+	entries     map[K]wheelEntry
+	currentTick int64
+	precision   time.Duration
+	logger      Logger
+}
+
+func NewTimingWheel[K comparable](precision time.Duration, logger Logger) (Records[K], Error) {
+	if precision < MinWheelPrecision {
+		err := &SetupError{message: fmt.Sprintf("Invalid precision value: must be at least %s", MinWheelPrecision.String())}
+		logger.Error(fmt.Sprintf("[RECORDS_EVENT] %s", err.Error()))
+		return nil, err
+	}
+
+	logger.Info("[RECORDS_EVENT] Initializing timing wheel records with precision: " + precision.String())
+	w := &timingWheel[K]{
+		precision: precision,
+		logger:    logger,
+	}
+	w.reset()
+	return w, nil
+}
+
+func (w *timingWheel[K]) reset() {
+	for level := range w.levels {
+		w.levels[level] = make([]map[K]int64, wheelSlots(level))
+		w.sizes[level] = 0
+	}
+	w.overflow = make(map[K]int64)
+	w.entries = make(map[K]wheelEntry)
+	w.currentTick = w.truncateTime(time.Now())
+}
+
+func (w *timingWheel[K]) truncateTime(t time.Time) int64 {
+	return t.UnixNano() / int64(w.precision)
+}
+
+// place stores the key in the slot matching its distance to the current tick,
+// it must be called with the lock held.
+func (w *timingWheel[K]) place(key K, tick int64) {
+	slotTick := max(tick, w.currentTick)
+	delta := slotTick - w.currentTick
+	for level := 0; level < wheelLevels; level++ {
+		if delta < wheelSpan(level) {
+			slot := (slotTick >> wheelShift(level)) & (wheelSlots(level) - 1)
+			if w.levels[level][slot] == nil {
+				w.levels[level][slot] = make(map[K]int64)
+			}
+			w.levels[level][slot][key] = tick
+			w.sizes[level]++
+			w.entries[key] = wheelEntry{tick: tick, level: level, slot: slot}
+			return
+		}
+	}
+	w.overflow[key] = tick
+	w.entries[key] = wheelEntry{tick: tick, level: wheelLevels}
+}
+
+// remove drops the key from its slot, it must be called with the lock held.
+func (w *timingWheel[K]) remove(key K) bool {
+	entry, ok := w.entries[key]
+	if !ok {
+		return false
+	}
+	delete(w.entries, key)
+	if entry.level == wheelLevels {
+		delete(w.overflow, key)
+		return true
+	}
+	slot := w.levels[entry.level][entry.slot]
+	delete(slot, key)
+	w.sizes[entry.level]--
+	if len(slot) == 0 {
+		w.levels[entry.level][entry.slot] = nil
+	}
+	return true
+}
+
+// cascade moves the keys of the current slot of a level down to the lower
+// levels, going up a level every time the current slot wraps around.
+func (w *timingWheel[K]) cascade(level int) {
+	if level == wheelLevels {
+		overflow := w.overflow
+		w.overflow = make(map[K]int64)
+		for key, tick := range overflow {
+			w.place(key, tick)
+		}
+		return
+	}
+	slot := (w.currentTick >> wheelShift(level)) & (wheelSlots(level) - 1)
+	keys := w.levels[level][slot]
+	w.levels[level][slot] = nil
+	w.sizes[level] -= len(keys)
+	if slot == 0 {
+		w.cascade(level + 1)
+	}
+	for key, tick := range keys {
+		w.place(key, tick)
+	}
+}
+
+// advance moves the wheel up to the given tick and returns the keys whose tick
+// has passed, it must be called with the lock held. Ticks are walked one by
+// one only while the lowest level holds keys, otherwise the wheel jumps to
+// the next cascade of the lowest non-empty level.
+func (w *timingWheel[K]) advance(target int64) []K {
+	var expired []K
+	for w.currentTick < target {
+		if len(w.entries) == 0 {
+			w.currentTick = target
+			break
+		}
+		if w.sizes[0] == 0 {
+			level := 1
+			for level < wheelLevels && w.sizes[level] == 0 {
+				level++
+			}
+			boundary := (w.currentTick | (int64(1)<<wheelShift(level) - 1)) + 1
+			if boundary > target {
+				w.currentTick = target
+				break
+			}
+			w.currentTick = boundary
+			w.cascade(1)
+			continue
+		}
+		slot := w.currentTick & (wheelSlots(0) - 1)
+		for key := range w.levels[0][slot] {
+			expired = append(expired, key)
+			delete(w.entries, key)
+		}
+		w.sizes[0] -= len(w.levels[0][slot])
+		w.levels[0][slot] = nil
+		w.currentTick++
+		if w.currentTick&(wheelSlots(0)-1) == 0 {
+			w.cascade(1)
+		}
+	}
+	return expired
+}
+
+func (w *timingWheel[K]) Get(t time.Time) []K {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	tick := w.truncateTime(t)
+	var keys []K
+	for key, entry := range w.entries {
+		if entry.tick == tick {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func (w *timingWheel[K]) Add(key K, t time.Time) {
+	if t.IsZero() {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.remove(key)
+	w.place(key, w.truncateTime(t))
+}
+
+func (w *timingWheel[K]) AddMany(keys []K, t time.Time) {
+	if t.IsZero() {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	tick := w.truncateTime(t)
+	for _, key := range keys {
+		w.remove(key)
+		w.place(key, tick)
+	}
+}
+
+func (w *timingWheel[K]) Delete(key K, t time.Time) {
+	if t.IsZero() {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.remove(key) {
+		w.logger.Warning(fmt.Sprintf("[RECORDS_EVENT] Attempted to delete key %v missing from the timing wheel", key))
+	}
+}
+
+func (w *timingWheel[K]) Move(key K, from time.Time, to time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.remove(key)
+	if !to.IsZero() {
+		w.place(key, w.truncateTime(to))
+	}
+}
+
+// DeleteBefore expires every key whose tick is over at t. The callback is
+// called without holding the lock so that it can update the records.
+func (w *timingWheel[K]) DeleteBefore(t time.Time, deleteCallback func([]K)) int {
+	w.logger.Info(fmt.Sprintf("[RECORDS_EVENT] Deleting records before %v", t))
+	w.mu.Lock()
+	expired := w.advance(w.truncateTime(t))
+	w.mu.Unlock()
+	if len(expired) > 0 {
+		deleteCallback(expired)
+	}
+	w.logger.Info(fmt.Sprintf("[RECORDS_EVENT] Deleted %d keys before %v", len(expired), t))
+	return len(expired)
+}
+
+func (w *timingWheel[K]) DeleteAfter(t time.Time, deleteCallback func([]K)) int {
+	w.logger.Info(fmt.Sprintf("[RECORDS_EVENT] Deleting records after %v", t))
+	w.mu.Lock()
+	tick := w.truncateTime(t)
+	var deleted []K
+	for key, entry := range w.entries {
+		if entry.tick >= tick {
+			deleted = append(deleted, key)
+		}
+	}
+	for _, key := range deleted {
+		w.remove(key)
+	}
+	w.mu.Unlock()
+	if len(deleted) > 0 {
+		deleteCallback(deleted)
+	}
+	w.logger.Info(fmt.Sprintf("[RECORDS_EVENT] Deleted %d keys after %v", len(deleted), t))
+	return len(deleted)
+}
+
+func (w *timingWheel[K]) Clear() {
+	w.logger.Info("[RECORDS_EVENT] Clearing all records...")
+	w.mu.Lock()
+	w.reset()
+	w.mu.Unlock()
+	w.logger.Info("[RECORDS_EVENT] All records cleared")
+}
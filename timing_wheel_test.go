@@ -0,0 +1,93 @@
+package main
+
+import (
+	"io"
+	"slices"
+	"testing"
+	"time"
+)
+
+// newTestTimingWheel returns a wheel with a millisecond precision along with
+// the time of its current tick.
+func newTestTimingWheel(t *testing.T) (*timingWheel[string], time.Time) {
+	records, err := NewTimingWheel[string](time.Millisecond, NewLogger(io.Discard, "", 0))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	wheel := records.(*timingWheel[string])
+	return wheel, time.Unix(0, wheel.currentTick*int64(time.Millisecond))
+}
+
+func deleteBefore(wheel *timingWheel[string], t time.Time) []string {
+	var deleted []string
+	wheel.DeleteBefore(t, func(keys []string) {
+		deleted = append(deleted, keys...)
+	})
+	slices.Sort(deleted)
+	return deleted
+}
+
+func TestTimingWheelCascade(t *testing.T) {
+	wheel, now := newTestTimingWheel(t)
+	ticks := func(n int64) time.Time {
+		return now.Add(time.Duration(n) * time.Millisecond)
+	}
+	// one key per level, each expiring past the span of the levels below
+	expirations := map[string]int64{"a": 10, "b": 300, "c": 20000, "d": 1 << 21, "e": 1 << 27}
+	for key, tick := range expirations {
+		wheel.Add(key, ticks(tick))
+	}
+	for key, level := range map[string]int{"a": 0, "b": 1, "c": 2, "d": 3, "e": 4} {
+		if got := wheel.entries[key].level; got != level {
+			t.Errorf("key %s at level %d, want %d", key, got, level)
+		}
+	}
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		tick := expirations[key]
+		if deleted := deleteBefore(wheel, ticks(tick)); len(deleted) != 0 {
+			t.Fatalf("keys %v deleted before their tick %d is over", deleted, tick)
+		}
+		if got := wheel.entries[key].level; got != 0 {
+			t.Errorf("key %s not cascaded down to level 0 by its tick", key)
+		}
+		if deleted := deleteBefore(wheel, ticks(tick+1)); !slices.Equal(deleted, []string{key}) {
+			t.Fatalf("got keys %v deleted once tick %d is over, want %s", deleted, tick, key)
+		}
+	}
+	if len(wheel.entries) != 0 || wheel.sizes != [wheelLevels]int{} {
+		t.Errorf("wheel not empty: %d entries, sizes %v", len(wheel.entries), wheel.sizes)
+	}
+}
+
+func TestTimingWheelOverflow(t *testing.T) {
+	wheel, now := newTestTimingWheel(t)
+	tick := wheelSpan(wheelLevels-1) + 5
+	expiresAt := now.Add(time.Duration(tick) * time.Millisecond)
+	wheel.Add("far", expiresAt)
+	if wheel.entries["far"].level != wheelLevels || len(wheel.overflow) != 1 {
+		t.Fatalf("key beyond the last level not in the overflow")
+	}
+	if deleted := deleteBefore(wheel, expiresAt); len(deleted) != 0 {
+		t.Fatalf("key deleted before its tick is over")
+	}
+	if len(wheel.overflow) != 0 || wheel.entries["far"].level != 0 {
+		t.Errorf("key not moved from the overflow to level 0")
+	}
+	if deleted := deleteBefore(wheel, expiresAt.Add(time.Millisecond)); !slices.Equal(deleted, []string{"far"}) {
+		t.Errorf("got keys %v deleted, want far", deleted)
+	}
+}
+
+func TestTimingWheelDeleteAndMove(t *testing.T) {
+	wheel, now := newTestTimingWheel(t)
+	wheel.Add("a", now.Add(500*time.Millisecond))
+	wheel.Add("b", now.Add(500*time.Millisecond))
+	wheel.Delete("a", now.Add(500*time.Millisecond))
+	wheel.Move("b", now.Add(500*time.Millisecond), now.Add(time.Hour))
+	if deleted := deleteBefore(wheel, now.Add(time.Second)); len(deleted) != 0 {
+		t.Fatalf("got keys %v deleted, want none", deleted)
+	}
+	if deleted := deleteBefore(wheel, now.Add(2*time.Hour)); !slices.Equal(deleted, []string{"b"}) {
+		t.Errorf("got keys %v deleted, want b", deleted)
+	}
+}
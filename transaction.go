@@ -0,0 +1,97 @@
+package main
+
+import (
+	"time"
+)
+
+// QueuedCommand is a command queued by a transaction along with its parsed
+// input, it is only run on EXEC.
+type QueuedCommand[K comparable, V any] struct {
+	Command ExecutableCommand[K, V]
+	Input   CommandInput
+}
+
+// unqueuedCommands cannot be used inside a transaction.
+var unqueuedCommands = map[string]bool{
+	"WATCH":      true,
+	"MONITOR":    true,
+	"SUBSCRIBE":  true,
+	"PSUBSCRIBE": true,
+}
+
+// transaction is the MULTI/EXEC state of a connection, it is only used by the
+// goroutine serving the connection.
+type transaction[K comparable, V any] struct {
+	// active is true between MULTI and EXEC or DISCARD
+	active bool
+	// failed is true when a command could not be queued, EXEC then discards
+	// the whole transaction
+	failed  bool
+	queue   []QueuedCommand[K, V]
+	watches []WatchCheck
+}
+
+func (t *transaction[K, V]) reset() {
+	t.active = false
+	t.failed = false
+	t.queue = nil
+	t.watches = nil
+}
+
+// WatchCheck reports whether a watched key is unchanged since WATCH.
+type WatchCheck func() bool
+
+// WatchResult is implemented by the results of the commands watching keys,
+// the server keeps their checks until the next EXEC, which is aborted if one
+// of them fails.
+type WatchResult interface {
+	Checks() []WatchCheck
+}
+
+type watchResult[V any] struct {
+	checks []WatchCheck
+}
+
+func (r *watchResult[V]) String() string {
+	return "OK"
+}
+
+func (r *watchResult[V]) Checks() []WatchCheck {
+	return r.checks
+}
+
+type watchCommand[V any] struct {
+	Command
+}
+
+func NewWatchCommand[V any]() ExecutableCommand[string, V] {
+	return &watchCommand[V]{
+		Command: NewCommand("WATCH").
+			WithArgument(KeysArgument).
+			WithOption(FrequentAccessOption),
+	}
+}
+
+// Run records the version of the keys, a key is changed once it has been
+// written, deleted or has expired.
+func (c *watchCommand[V]) Run(input CommandInput, cache Cache[string, V]) (Result[V], Error) {
+	keys := toStrings(input.GetArgument(*KeysArgument).([]any))
+	checks := make([]WatchCheck, len(keys))
+	for i, key := range keys {
+		version := currentVersion(cache, key)
+		checks[i] = func() bool {
+			return currentVersion(cache, key) == version
+		}
+	}
+	return &watchResult[V]{checks: checks}, nil
+}
+
+// currentVersion returns the version of the value of a key, 0 when it does not
+// exist.
+func currentVersion[V any](cache Cache[string, V], key string) uint64 {
+	cacheValue, ok := cache.get(key)
+	if !ok || isExpired(cacheValue, time.Now()) {
+		return 0
+	}
+	return cacheValue.Version()
+}
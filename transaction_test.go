@@ -0,0 +1,142 @@
+package main
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestServer(t *testing.T) *server[string, Value] {
+	logger := NewLogger(io.Discard, "", 0)
+	cacheManager := NewCacheManager[string, Value](logger)
+	if err := cacheManager.SetupMainCache(CacheConfig{Precision: time.Minute}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	commandManager := NewCommandManager().
+		AddCommand("SET", NewSetCommand()).
+		AddCommand("GET", NewGetCommand()).
+		AddCommand("WATCH", NewWatchCommand[Value]())
+	config := &ServerConfig{nbrWorkers: 1, slowLogThreshold: -1, pubSubBufferSize: 16}
+	s, err := NewServer(config, logger, commandManager, cacheManager)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	t.Cleanup(func() { s.(*server[string, Value]).listener.Close() })
+	return s.(*server[string, Value])
+}
+
+// send runs the commands on the connection one after the other and returns
+// their replies.
+func send(t *testing.T, s *server[string, Value], connection *testConnection, transaction *transaction[string, Value], commands ...string) []string {
+	replies := make([]string, len(commands))
+	for i, command := range commands {
+		if !s.handleCommand(connection, transaction, command) {
+			t.Fatalf("connection closed by %q", command)
+		}
+		replies[i] = strings.TrimSuffix(<-connection.replies, "\n")
+	}
+	return replies
+}
+
+func TestWatchAbortsExecAfterAnotherConnectionWrites(t *testing.T) {
+	s := newTestServer(t)
+	connection, other := newTestConnection(), newTestConnection()
+	tx := &transaction[string, Value]{}
+	send(t, s, connection, tx, "SET k before", "WATCH k", "MULTI", "SET k mine")
+	send(t, s, other, &transaction[string, Value]{}, "SET k theirs")
+
+	replies := send(t, s, connection, tx, "EXEC", "GET k")
+	if replies[0] != "(nil)" || replies[1] != `"theirs"` {
+		t.Errorf("got replies %q, want the transaction aborted", replies)
+	}
+	// The watches are dropped by EXEC
+	replies = send(t, s, connection, tx, "MULTI", "SET k mine", "EXEC", "GET k")
+	if replies[3] != `"mine"` {
+		t.Errorf("got replies %q, want the next transaction run", replies)
+	}
+}
+
+func TestExecDiscardsTransactionAfterParseError(t *testing.T) {
+	s := newTestServer(t)
+	connection := newTestConnection()
+	tx := &transaction[string, Value]{}
+	replies := send(t, s, connection, tx, "MULTI", "SET k", "SET k v", "EXEC", "GET k")
+	if replies[2] != "QUEUED" {
+		t.Errorf("got reply %q, want commands still queued after the error", replies[2])
+	}
+	if replies[3] != "Transaction discarded because of previous errors" || replies[4] != "(nil)" {
+		t.Errorf("got replies %q, want the transaction discarded", replies)
+	}
+	if tx.active || len(tx.queue) > 0 {
+		t.Errorf("transaction left open after EXEC")
+	}
+}
+
+func TestUnwatchRejectedInsideMulti(t *testing.T) {
+	s := newTestServer(t)
+	connection, other := newTestConnection(), newTestConnection()
+	tx := &transaction[string, Value]{}
+	replies := send(t, s, connection, tx, "WATCH k", "MULTI", "UNWATCH", "SET k mine")
+	if !strings.Contains(replies[2], "not allowed") {
+		t.Errorf("got reply %q to UNWATCH inside MULTI", replies[2])
+	}
+	if len(tx.watches) != 1 {
+		t.Errorf("UNWATCH dropped the watches inside MULTI")
+	}
+	send(t, s, other, &transaction[string, Value]{}, "SET k theirs")
+	replies = send(t, s, connection, tx, "EXEC", "GET k")
+	if replies[0] != "Transaction discarded because of previous errors" || replies[1] != `"theirs"` {
+		t.Errorf("got replies %q, want the transaction discarded", replies)
+	}
+}
+
+// TestExecDoesNotInterleaveWithExecute checks that the commands run by other
+// connections wait for the whole transaction.
+func TestExecDoesNotInterleaveWithExecute(t *testing.T) {
+	s := newTestServer(t)
+	set := NewSetCommand()
+	queue := make([]QueuedCommand[string, Value], 100)
+	for i := range queue {
+		input, err := set.Parse([]string{"k", strconv.Itoa(i)})
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		queue[i] = QueuedCommand[string, Value]{Command: set, Input: input}
+	}
+	get := NewGetCommand()
+	getInput, err := get.Parse([]string{"k"})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		connection := newTestConnection()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			result, err := s.executor.Execute(connection, get, getInput)
+			if err == nil && result.String() != "(nil)" && result.String() != `"99"` {
+				t.Errorf("read %s in the middle of a transaction", result.String())
+				return
+			}
+		}
+	}()
+	connection := newTestConnection()
+	for range 100 {
+		if _, ok := s.executor.ExecuteAll(connection, queue, nil); !ok {
+			t.Fatalf("transaction aborted")
+		}
+	}
+	close(done)
+	wg.Wait()
+}
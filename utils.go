@@ -2,7 +2,9 @@ package main
 
 import (
 	"fmt"
+	"os"
 	"strconv"
+	"time"
 )
 
 type ValueType int
@@ -15,6 +17,22 @@ const (
 	TypeString
 )
 
+// Description returns a human readable name of the type.
+func (t ValueType) Description() string {
+	switch t {
+	case TypeInt:
+		return "an integer"
+	case TypeFloat:
+		return "a valid float"
+	case TypeBool:
+		return "a boolean"
+	case TypeString:
+		return "a string"
+	default:
+		return "a value"
+	}
+}
+
 // ParseValue attempts to parse a string into the specified type.
 func ParseValue(valueType ValueType, value string) (interface{}, error) {
 	switch valueType {
@@ -30,3 +48,64 @@ func ParseValue(valueType ValueType, value string) (interface{}, error) {
 		return nil, fmt.Errorf("unsupported type")
 	}
 }
+
+// toStrings converts the values of a variadic string argument.
+func toStrings(values []any) []string {
+	strs := make([]string, len(values))
+	for i, value := range values {
+		strs[i] = value.(string)
+	}
+	return strs
+}
+
+// getEnvString reads a string from the environment, falling back to
+// defaultValue when the variable is not set.
+func getEnvString(name string, defaultValue string) string {
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvInt reads an integer from the environment, falling back to
+// defaultValue when the variable is not set.
+func getEnvInt(name string, defaultValue int) (int, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return defaultValue, nil
+	}
+	return strconv.Atoi(value)
+}
+
+// getEnvDuration reads a duration such as "30s" or "500ms" from the
+// environment, falling back to defaultValue when the variable is not set.
+func getEnvDuration(name string, defaultValue time.Duration) (time.Duration, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return defaultValue, nil
+	}
+	return time.ParseDuration(value)
+}
+
+// hashString returns a 64-bit hash of the string that does not change between
+// runs: FNV-1a, whose bits are then mixed so that each bit of the hash depends
+// on every byte of the string, as probabilistic structures need.
+func hashString(s string) uint64 {
+	hash := uint64(14695981039346656037)
+	for i := 0; i < len(s); i++ {
+		hash ^= uint64(s[i])
+		hash *= 1099511628211
+	}
+	return mixHash(hash)
+}
+
+// mixHash is the finalizer of splitmix64.
+func mixHash(hash uint64) uint64 {
+	hash ^= hash >> 30
+	hash *= 0xbf58476d1ce4e5b9
+	hash ^= hash >> 27
+	hash *= 0x94d049bb133111eb
+	hash ^= hash >> 31
+	return hash
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// DataType is the type of a value stored in the cache, commands only apply to
+// the values of the types they are made for.
+type DataType int
+
+const (
+	// StringData values are strings, counters included.
+	StringData DataType = iota
+	ListData
+	HashData
+	SetData
+	SortedSetData
+	JSONData
+	BloomFilterData
+	HyperLogLogData
+	CountMinSketchData
+	StreamData
+	VectorIndexData
+)
+
+// String returns the name of the data type, as replied by TYPE.
+func (t DataType) String() string {
+	switch t {
+	case StringData:
+		return "string"
+	case ListData:
+		return "list"
+	case HashData:
+		return "hash"
+	case SetData:
+		return "set"
+	case SortedSetData:
+		return "zset"
+	case JSONData:
+		return "json"
+	case BloomFilterData:
+		return "bloom"
+	case HyperLogLogData:
+		return "hyperloglog"
+	case CountMinSketchData:
+		return "cms"
+	case StreamData:
+		return "stream"
+	case VectorIndexData:
+		return "vectorindex"
+	default:
+		return "unknown"
+	}
+}
+
+// Value is a value of the cache tagged with its data type. Strings are held as
+// is while the other types are held by pointer, they are changed in place
+// through Compute.
+type Value struct {
+	dataType DataType
+	data     any
+}
+
+func StringValue(value string) Value {
+	return Value{dataType: StringData, data: value}
+}
+
+func (v Value) Type() DataType {
+	return v.dataType
+}
+
+// String returns the string held by a string value, the values of the other
+// types are described by their data type.
+func (v Value) String() string {
+	if value, ok := v.data.(string); ok {
+		return value
+	}
+	return fmt.Sprintf("(%s)", v.dataType)
+}
+
+// valueAs returns the data held by the value, or a WrongTypeError when the
+// value is not of the data type.
+func valueAs[T any](value Value, dataType DataType) (T, Error) {
+	data, ok := value.data.(T)
+	if !ok || value.dataType != dataType {
+		var zero T
+		return zero, &WrongTypeError{}
+	}
+	return data, nil
+}
+
+// computeAs is Compute for the keys holding data of the data type: compute is
+// given the data of the key, nil when it does not exist, and the data returned
+// is stored tagged with the data type. Keys holding another type are left
+// unchanged and a WrongTypeError is returned.
+//
+// The data types held by pointer are not safe for concurrent use, apart from
+// Set which locks itself: their data is only read and changed through
+// computeAs, which runs compute with the lock of the cache held.
+func computeAs[T any](cache Cache[string, Value], key string, dataType DataType, compute func(*T) (T, ComputeAction, Error), expiresAt time.Time) (T, Error) {
+	var result T
+	_, err := cache.Compute(key, func(current *Value) (Value, ComputeAction, Error) {
+		var data *T
+		if current != nil {
+			typed, err := valueAs[T](*current, dataType)
+			if err != nil {
+				return Value{}, KeepValue, err
+			}
+			data = &typed
+		}
+		var action ComputeAction
+		var err Error
+		result, action, err = compute(data)
+		return Value{dataType: dataType, data: result}, action, err
+	}, expiresAt)
+	return result, err
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"strings"
+)
+
+// Bounds of the vector indexes, limiting the memory and the time taken by
+// each vector.
+const (
+	maxVectorDimension        = 32768
+	maxHNSWConnections        = 512
+	maxHNSWEfConstruction     = 65536
+	DefaultHNSWConnections    = 16
+	DefaultHNSWEfConstruction = 200
+	DefaultHNSWEfSearch       = 64
+	maxHNSWLevel              = 16
+	minHNSWDeletedToRebuild   = 64
+)
+
+// VectorMetric is how vectors are compared, lower distances meaning closer
+// vectors.
+type VectorMetric int
+
+const (
+	// CosineMetric is 1 minus the cosine similarity of the vectors
+	CosineMetric VectorMetric = iota
+	// L2Metric is the euclidean distance between the vectors
+	L2Metric
+	// DotMetric is 1 minus the dot product of the vectors, for vectors
+	// normalized beforehand
+	DotMetric
+)
+
+func parseVectorMetric(token string) (VectorMetric, bool) {
+	switch strings.ToUpper(token) {
+	case "COSINE":
+		return CosineMetric, true
+	case "L2":
+		return L2Metric, true
+	case "DOT":
+		return DotMetric, true
+	default:
+		return 0, false
+	}
+}
+
+func (m VectorMetric) distance(a, b []float32) float64 {
+	if m == L2Metric {
+		sum := 0.0
+		for i := range a {
+			d := float64(a[i]) - float64(b[i])
+			sum += d * d
+		}
+		return math.Sqrt(sum)
+	}
+	// cosine vectors are normalized when added
+	dot := 0.0
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+	}
+	return 1 - dot
+}
+
+// VectorMatch is a vector found by a search with its distance to the query.
+type VectorMatch struct {
+	Key      string
+	Distance float64
+}
+
+func compareVectorMatches(a, b VectorMatch) int {
+	if c := cmp.Compare(a.Distance, b.Distance); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.Key, b.Key)
+}
+
+// VectorIndex holds vectors of a fixed dimension by key and finds the nearest
+// ones to a query. Exact searches compare the query to every vector, while
+// indexes built with an HNSW graph also answer approximate searches in a time
+// growing with the logarithm of their size.
+type VectorIndex struct {
+	dimension int
+	metric    VectorMetric
+	vectors   map[string][]float32
+	graph     *hnswGraph
+}
+
+// NewFlatVectorIndex returns an index answering exact searches only.
+func NewFlatVectorIndex(dimension int, metric VectorMetric) *VectorIndex {
+	return &VectorIndex{
+		dimension: dimension,
+		metric:    metric,
+		vectors:   make(map[string][]float32),
+	}
+}
+
+// NewHNSWVectorIndex returns an index whose graph links each vector to up to
+// connections neighbours, found among efConstruction candidates.
+func NewHNSWVectorIndex(dimension int, metric VectorMetric, connections, efConstruction int) *VectorIndex {
+	index := NewFlatVectorIndex(dimension, metric)
+	index.graph = newHNSWGraph(metric, connections, efConstruction)
+	return index
+}
+
+func (v *VectorIndex) Len() int {
+	return len(v.vectors)
+}
+
+func (v *VectorIndex) Dimension() int {
+	return v.dimension
+}
+
+// Prepare converts a vector to the format of the index, normalizing it for the
+// cosine metric. It returns false when its dimension is not the one of the
+// index, when it is null with the cosine metric or when its values do not fit
+// in 32-bit floats.
+func (v *VectorIndex) Prepare(vector []float64) ([]float32, bool) {
+	if len(vector) != v.dimension {
+		return nil, false
+	}
+	norm := 1.0
+	if v.metric == CosineMetric {
+		sum := 0.0
+		for _, x := range vector {
+			sum += x * x
+		}
+		if norm = math.Sqrt(sum); norm == 0 || math.IsInf(norm, 0) {
+			return nil, false
+		}
+	}
+	prepared := make([]float32, len(vector))
+	for i, x := range vector {
+		prepared[i] = float32(x / norm)
+		if math.IsInf(float64(prepared[i]), 0) {
+			return nil, false
+		}
+	}
+	return prepared, true
+}
+
+// Add sets the vector of the key, prepared by Prepare, and returns whether the
+// key is new.
+func (v *VectorIndex) Add(key string, vector []float32) bool {
+	_, exists := v.vectors[key]
+	v.vectors[key] = vector
+	if v.graph != nil {
+		v.graph.insert(key, vector)
+		v.rebuildIfSparse()
+	}
+	return !exists
+}
+
+func (v *VectorIndex) Remove(key string) bool {
+	if _, exists := v.vectors[key]; !exists {
+		return false
+	}
+	delete(v.vectors, key)
+	if v.graph != nil {
+		v.graph.remove(key)
+		v.rebuildIfSparse()
+	}
+	return true
+}
+
+// rebuildIfSparse rebuilds the graph once it holds more nodes of removed or
+// replaced vectors than of current ones.
+func (v *VectorIndex) rebuildIfSparse() {
+	if v.graph.deleted >= minHNSWDeletedToRebuild && v.graph.deleted > len(v.vectors) {
+		v.rebuild()
+	}
+}
+
+// rebuild replaces the graph by one holding the remaining vectors only, so that
+// the nodes of removed and replaced vectors do not pile up.
+func (v *VectorIndex) rebuild() {
+	graph := newHNSWGraph(v.metric, v.graph.connections, v.graph.efConstruction)
+	for _, node := range v.graph.nodes {
+		if !node.deleted {
+			graph.insert(node.key, node.vector)
+		}
+	}
+	v.graph = graph
+}
+
+// Search returns the k vectors nearest to the query, prepared by Prepare,
+// among the ones whose keys start with prefix. It is exact when asked for or
+// when the index has no graph, and otherwise approximate with ef candidates
+// looked at, more candidates giving more accurate results.
+func (v *VectorIndex) Search(query []float32, k int, prefix string, exact bool, ef int) []VectorMatch {
+	if exact || v.graph == nil {
+		return v.exactSearch(query, k, prefix)
+	}
+	return v.graph.search(query, k, max(ef, k), func(key string) bool {
+		return strings.HasPrefix(key, prefix)
+	})
+}
+
+func (v *VectorIndex) exactSearch(query []float32, k int, prefix string) []VectorMatch {
+	nearest := &priorityQueue[VectorMatch]{before: func(a, b VectorMatch) bool {
+		return compareVectorMatches(a, b) > 0
+	}}
+	for key, vector := range v.vectors {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		nearest.push(VectorMatch{Key: key, Distance: v.metric.distance(query, vector)})
+		if nearest.Len() > k {
+			nearest.pop()
+		}
+	}
+	matches := nearest.items
+	slices.SortFunc(matches, compareVectorMatches)
+	return matches
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// vectorSearch tells which vectors a search looks at and how.
+type vectorSearch struct {
+	k      int
+	prefix string
+	exact  bool
+	ef     int
+}
+
+// VectorIndexes holds the vector indexes of the cache.
+type VectorIndexes interface {
+	Create(key string, create func() *VectorIndex, expiresAt time.Time) (bool, Error)
+	Add(key string, vectorKey string, vector []float64) (bool, Error)
+	Remove(key string, vectorKeys []string) (int, Error)
+	Search(key string, query []float64, search vectorSearch) ([]VectorMatch, Error)
+}
+
+type vectorIndexes struct {
+	cache Cache[string, Value]
+}
+
+func NewVectorIndexes(cache Cache[string, Value]) VectorIndexes {
+	return &vectorIndexes{cache: cache}
+}
+
+// Create stores the index returned by create, which expires at expiresAt, and
+// returns whether the key did not exist yet.
+func (v *vectorIndexes) Create(key string, create func() *VectorIndex, expiresAt time.Time) (bool, Error) {
+	created := false
+	_, err := computeAs(v.cache, key, VectorIndexData, func(current **VectorIndex) (*VectorIndex, ComputeAction, Error) {
+		if current != nil {
+			return *current, KeepValue, nil
+		}
+		created = true
+		return create(), StoreValue, nil
+	}, expiresAt)
+	return created, err
+}
+
+// Add sets the vector of the vector key and returns whether the vector key is
+// new, the index must have been created by Create.
+func (v *vectorIndexes) Add(key string, vectorKey string, vector []float64) (bool, Error) {
+	added := false
+	_, err := computeAs(v.cache, key, VectorIndexData, func(current **VectorIndex) (*VectorIndex, ComputeAction, Error) {
+		if current == nil {
+			return nil, KeepValue, &CommandError{message: "Key does not exist in VEC.ADD"}
+		}
+		index := *current
+		prepared, ok := index.Prepare(vector)
+		if !ok {
+			return nil, KeepValue, invalidVectorError("VEC.ADD", index)
+		}
+		added = index.Add(vectorKey, prepared)
+		return index, StoreValue, nil
+	}, time.Time{})
+	return added, err
+}
+
+// Remove removes the vectors of the vector keys and returns their number, the
+// index is kept once empty.
+func (v *vectorIndexes) Remove(key string, vectorKeys []string) (int, Error) {
+	removed := 0
+	_, err := computeAs(v.cache, key, VectorIndexData, func(current **VectorIndex) (*VectorIndex, ComputeAction, Error) {
+		if current == nil {
+			return nil, KeepValue, nil
+		}
+		index := *current
+		for _, vectorKey := range vectorKeys {
+			if index.Remove(vectorKey) {
+				removed++
+			}
+		}
+		if removed == 0 {
+			return index, KeepValue, nil
+		}
+		return index, StoreValue, nil
+	}, time.Time{})
+	return removed, err
+}
+
+// Search returns the vectors nearest to the query, ordered by distance.
+func (v *vectorIndexes) Search(key string, query []float64, search vectorSearch) ([]VectorMatch, Error) {
+	var matches []VectorMatch
+	_, err := computeAs(v.cache, key, VectorIndexData, func(current **VectorIndex) (*VectorIndex, ComputeAction, Error) {
+		if current == nil {
+			return nil, KeepValue, &CommandError{message: "Key does not exist in VEC.SEARCH"}
+		}
+		index := *current
+		prepared, ok := index.Prepare(query)
+		if !ok {
+			return nil, KeepValue, invalidVectorError("VEC.SEARCH", index)
+		}
+		matches = index.Search(prepared, search.k, search.prefix, search.exact, search.ef)
+		return nil, KeepValue, nil
+	}, time.Time{})
+	return matches, err
+}
+
+func invalidVectorError(commandName string, index *VectorIndex) Error {
+	return &CommandError{message: "Vectors must have " + strconv.Itoa(index.Dimension()) + " finite values, not all 0 for the cosine metric, in " + commandName}
+}
+
+// parseVector reads the finite values of a vector.
+func parseVector(tokens []string) ([]float64, Error) {
+	vector := make([]float64, len(tokens))
+	for i, token := range tokens {
+		value, ok := parseScore(token)
+		if !ok {
+			return nil, &ValueTypeError{valueType: TypeFloat}
+		}
+		vector[i] = value
+	}
+	return vector, nil
+}
+
+type vecCreateCommand struct {
+	Command
+	indexes VectorIndexes
+}
+
+func NewVecCreateCommand(indexes VectorIndexes) ExecutableCommand[string, Value] {
+	return &vecCreateCommand{
+		Command: NewCommand("VEC.CREATE").
+			WithArgument(KeyCommandArgument).
+			WithArgument(DimensionArgument).
+			WithArgument(MetricArgument).
+			WithArgument(AlgorithmArgument).
+			WithArgument(ConnectionsArgument).
+			WithArgument(EfConstructionArgument).
+			WithOption(ExpirationOption).
+			WithOption(ExpirationMsOption),
+		indexes: indexes,
+	}
+}
+
+// Run creates an empty index, which fails when the key already exists. HNSW
+// indexes link each vector to 16 neighbours picked among 200 candidates unless
+// given otherwise.
+func (c *vecCreateCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	dimension := input.GetArgument(*DimensionArgument).(int)
+	metric, ok := parseVectorMetric(input.GetArgument(*MetricArgument).(string))
+	if !ok {
+		return nil, &CommandError{message: "Unsupported metric in " + c.GetName()}
+	}
+	if dimension <= 0 || dimension > maxVectorDimension {
+		return nil, &CommandError{message: "Invalid dimension in " + c.GetName()}
+	}
+	algorithm, _ := input.GetArgument(*AlgorithmArgument).(string)
+	connections, hasConnections := input.GetArgument(*ConnectionsArgument).(int)
+	efConstruction, hasEfConstruction := input.GetArgument(*EfConstructionArgument).(int)
+	if !hasConnections {
+		connections = DefaultHNSWConnections
+	}
+	if !hasEfConstruction {
+		efConstruction = DefaultHNSWEfConstruction
+	}
+	var create func() *VectorIndex
+	switch strings.ToUpper(algorithm) {
+	case "", "FLAT":
+		if hasConnections || hasEfConstruction {
+			return nil, &InvalidCommandUsageError{command: c.GetName()}
+		}
+		create = func() *VectorIndex {
+			return NewFlatVectorIndex(dimension, metric)
+		}
+	case "HNSW":
+		if connections < 2 || connections > maxHNSWConnections || efConstruction <= 0 || efConstruction > maxHNSWEfConstruction {
+			return nil, &CommandError{message: "Invalid HNSW parameters in " + c.GetName()}
+		}
+		create = func() *VectorIndex {
+			return NewHNSWVectorIndex(dimension, metric, connections, efConstruction)
+		}
+	default:
+		return nil, &CommandError{message: "Unsupported algorithm in " + c.GetName()}
+	}
+	expiresAt, err := expirationFromInput(c.GetName(), input)
+	if err != nil {
+		return nil, err
+	}
+	created, err := c.indexes.Create(key, create, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return nil, &CommandError{message: "Key already exists in " + c.GetName()}
+	}
+	return &okResult[Value]{}, nil
+}
+
+type vecAddCommand struct {
+	Command
+	indexes VectorIndexes
+}
+
+func NewVecAddCommand(indexes VectorIndexes) ExecutableCommand[string, Value] {
+	return &vecAddCommand{
+		Command: NewCommand("VEC.ADD").
+			WithArgument(KeyCommandArgument).
+			WithArgument(VectorKeyArgument).
+			WithArgument(VectorArgument),
+		indexes: indexes,
+	}
+}
+
+// Run replies 1 when the vector key is new and 0 when its vector is replaced.
+func (c *vecAddCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	vectorKey := input.GetArgument(*VectorKeyArgument).(string)
+	vector, err := parseVector(toStrings(input.GetArgument(*VectorArgument).([]any)))
+	if err != nil {
+		return nil, err
+	}
+	added, err := c.indexes.Add(key, vectorKey, vector)
+	if err != nil {
+		return nil, err
+	}
+	return booleanResult[Value](added), nil
+}
+
+type vecRemCommand struct {
+	Command
+	indexes VectorIndexes
+}
+
+func NewVecRemCommand(indexes VectorIndexes) ExecutableCommand[string, Value] {
+	return &vecRemCommand{
+		Command: NewCommand("VEC.REM").
+			WithArgument(KeyCommandArgument).
+			WithArgument(VectorKeysArgument),
+		indexes: indexes,
+	}
+}
+
+// Run replies the number of vectors removed.
+func (c *vecRemCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	vectorKeys := toStrings(input.GetArgument(*VectorKeysArgument).([]any))
+	removed, err := c.indexes.Remove(key, vectorKeys)
+	if err != nil {
+		return nil, err
+	}
+	return &integerResult[Value]{value: int64(removed)}, nil
+}
+
+type vecSearchCommand struct {
+	Command
+	indexes VectorIndexes
+}
+
+func NewVecSearchCommand(indexes VectorIndexes) ExecutableCommand[string, Value] {
+	return &vecSearchCommand{
+		Command: NewCommand("VEC.SEARCH").
+			WithArgument(KeyCommandArgument).
+			WithArgument(NeighboursCountArgument).
+			WithArgument(QueryVectorArgument).
+			WithOption(PrefixOption).
+			WithOption(EfSearchOption).
+			WithOption(ExactOption),
+		indexes: indexes,
+	}
+}
+
+// Run replies the k vectors nearest to the query, each as its key followed by
+// its distance to the query. HNSW indexes are searched approximately looking
+// at 64 candidates unless given otherwise, at least k.
+func (c *vecSearchCommand) Run(input CommandInput, cache Cache[string, Value]) (Result[Value], Error) {
+	key := input.GetArgument(*KeyCommandArgument).(string)
+	search := vectorSearch{
+		k:     input.GetArgument(*NeighboursCountArgument).(int),
+		exact: input.GetOption(*ExactOption) != nil,
+		ef:    DefaultHNSWEfSearch,
+	}
+	search.prefix, _ = input.GetOption(*PrefixOption).(string)
+	if ef, ok := input.GetOption(*EfSearchOption).(int); ok {
+		search.ef = ef
+	}
+	if search.k <= 0 || search.ef <= 0 || search.ef > maxHNSWEfConstruction {
+		return nil, &InvalidCommandUsageError{command: c.GetName()}
+	}
+	query, err := parseVector(toStrings(input.GetArgument(*QueryVectorArgument).([]any)))
+	if err != nil {
+		return nil, err
+	}
+	matches, err := c.indexes.Search(key, query, search)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]Result[Value], len(matches))
+	for i, match := range matches {
+		items[i] = &arrayResult[Value]{items: []Result[Value]{
+			&valueResult[Value]{value: StringValue(match.Key)},
+			&valueResult[Value]{value: StringValue(formatScore(match.Distance))},
+		}}
+	}
+	return &arrayResult[Value]{items: items}, nil
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestVectorIndexReplacedVectorsDoNotPileUp(t *testing.T) {
+	index := NewHNSWVectorIndex(2, L2Metric, DefaultHNSWConnections, DefaultHNSWEfConstruction)
+	for i := range 10000 {
+		key := strconv.Itoa(i % 10)
+		index.Add(key, []float32{float32(i % 10), float32(i)})
+	}
+	if index.Len() != 10 {
+		t.Fatalf("got %d vectors, want 10", index.Len())
+	}
+	if nodes := len(index.graph.nodes); nodes > 2*minHNSWDeletedToRebuild+10 {
+		t.Errorf("graph holds %d nodes for 10 vectors", nodes)
+	}
+	matches := index.Search([]float32{3, 9993}, 1, "", false, DefaultHNSWEfSearch)
+	if len(matches) != 1 || matches[0].Key != "3" {
+		t.Errorf("got matches %v, want key 3", matches)
+	}
+}